@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stellar/go-stellar-sdk/hash"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/require"
+)
+
+// The integration test replays ledgers generated by writeReplayFixture, so that it needs no access to the data lake.
+// A contract keeps a persistent counter and a temporary session entry:
+//   - ledger 100: init creates both entries with their ttl
+//   - ledger 101: increment updates the counter and extends its ttl
+//   - ledger 102: no transactions
+//   - ledger 103: close removes the session entry and its ttl
+const (
+	fixtureStartLedger   = 100
+	fixtureEndLedger     = 103
+	fixtureSourceAccount = "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+	fixtureCloseTime     = 1760000000
+)
+
+var fixtureContractID = xdr.ContractId{1}
+
+// writeReplayFixture writes the fixture ledgers to dir, one zstd compressed LedgerCloseMetaBatch per ledger named
+// with the Galexie naming scheme, and returns the hash of the transaction of every invoked function.
+func writeReplayFixture(t *testing.T, dir string) map[string]string {
+	counter := fixtureContractData("Counter", xdr.ContractDataDurabilityPersistent, 1, 100)
+	counterTTL := fixtureTTL(t, counter, 1000, 100)
+	session := fixtureContractData("Session", xdr.ContractDataDurabilityTemporary, 7, 100)
+	sessionTTL := fixtureTTL(t, session, 200, 100)
+	incremented := fixtureContractData("Counter", xdr.ContractDataDurabilityPersistent, 2, 101)
+	extendedTTL := fixtureTTL(t, counter, 2000, 101)
+
+	transactionHashes := map[string]string{}
+	var ledgers []xdr.LedgerCloseMeta
+	var previousLedgerHash xdr.Hash
+	addLedger := func(functionName string, changes xdr.LedgerEntryChanges) {
+		sequence := uint32(fixtureStartLedger + len(ledgers))
+		var envelopes []xdr.TransactionEnvelope
+		var processing []xdr.TransactionResultMeta
+		if functionName != "" {
+			envelope, resultMeta := fixtureTransaction(t, sequence, functionName, changes)
+			envelopes = append(envelopes, envelope)
+			processing = append(processing, resultMeta)
+			transactionHashes[functionName] = resultMeta.Result.TransactionHash.HexString()
+		}
+		ledger := fixtureLedger(t, sequence, previousLedgerHash, envelopes, processing)
+		previousLedgerHash = ledger.LedgerHash()
+		ledgers = append(ledgers, ledger)
+	}
+
+	addLedger("init", append(fixtureCreated(counter, counterTTL), fixtureCreated(session, sessionTTL)...))
+	addLedger("increment", append(fixtureUpdated(counter, incremented), fixtureUpdated(counterTTL, extendedTTL)...))
+	addLedger("", nil)
+	addLedger("close", append(fixtureRemoved(t, session), fixtureRemoved(t, sessionTTL)...))
+
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer encoder.Close()
+	for _, ledger := range ledgers {
+		sequence := ledger.LedgerSequence()
+		batch := xdr.LedgerCloseMetaBatch{
+			StartSequence:    xdr.Uint32(sequence),
+			EndSequence:      xdr.Uint32(sequence),
+			LedgerCloseMetas: []xdr.LedgerCloseMeta{ledger},
+		}
+		raw, err := batch.MarshalBinary()
+		require.NoError(t, err)
+		name := fmt.Sprintf("%08X--%d.xdr.zst", math.MaxUint32-sequence, sequence)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), encoder.EncodeAll(raw, nil), 0o644))
+	}
+	return transactionHashes
+}
+
+func fixtureLedger(t *testing.T, sequence uint32, previousLedgerHash xdr.Hash, envelopes []xdr.TransactionEnvelope, processing []xdr.TransactionResultMeta) xdr.LedgerCloseMeta {
+	header := xdr.LedgerHeader{
+		LedgerVersion:      23,
+		PreviousLedgerHash: previousLedgerHash,
+		ScpValue: xdr.StellarValue{
+			CloseTime: xdr.TimePoint(fixtureCloseTime + 5*uint64(sequence-fixtureStartLedger)),
+		},
+		LedgerSeq:    xdr.Uint32(sequence),
+		BaseFee:      100,
+		BaseReserve:  5000000,
+		MaxTxSetSize: 1000,
+	}
+	headerBytes, err := header.MarshalBinary()
+	require.NoError(t, err)
+
+	phases := []xdr.TransactionPhase{{
+		V: 0,
+		V0Components: &[]xdr.TxSetComponent{{
+			Type:                  xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+			TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{Txs: envelopes},
+		}},
+	}}
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{Hash: xdr.Hash(hash.Hash(headerBytes)), Header: header},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V:       1,
+				V1TxSet: &xdr.TransactionSetV1{PreviousLedgerHash: previousLedgerHash, Phases: phases},
+			},
+			TxProcessing: processing,
+		},
+	}
+}
+
+// fixtureTransaction returns a successful transaction invoking functionName of the fixture contract,
+// whose only operation made changes.
+func fixtureTransaction(t *testing.T, sequence uint32, functionName string, changes xdr.LedgerEntryChanges) (xdr.TransactionEnvelope, xdr.TransactionResultMeta) {
+	contractID := fixtureContractID
+	envelope := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: xdr.MustMuxedAddress(fixtureSourceAccount),
+				Fee:           1000,
+				SeqNum:        xdr.SequenceNumber(sequence),
+				Operations: []xdr.Operation{{
+					Body: xdr.OperationBody{
+						Type: xdr.OperationTypeInvokeHostFunction,
+						InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+							HostFunction: xdr.HostFunction{
+								Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+								InvokeContract: &xdr.InvokeContractArgs{
+									ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID},
+									FunctionName:    xdr.ScSymbol(functionName),
+								},
+							},
+						},
+					},
+				}},
+				Ext: xdr.TransactionExt{
+					V: 1,
+					SorobanData: &xdr.SorobanTransactionData{
+						Resources: xdr.SorobanResources{
+							Instructions:  1000,
+							DiskReadBytes: 200,
+							WriteBytes:    300,
+						},
+						ResourceFee: 500,
+					},
+				},
+			},
+		},
+	}
+	transactionHash, err := network.HashTransactionInEnvelope(envelope, network.PublicNetworkPassphrase)
+	require.NoError(t, err)
+
+	return envelope, xdr.TransactionResultMeta{
+		Result: xdr.TransactionResultPair{
+			TransactionHash: xdr.Hash(transactionHash),
+			Result: xdr.TransactionResult{
+				FeeCharged: 600,
+				Result: xdr.TransactionResultResult{
+					Code: xdr.TransactionResultCodeTxSuccess,
+					Results: &[]xdr.OperationResult{{
+						Code: xdr.OperationResultCodeOpInner,
+						Tr: &xdr.OperationResultTr{
+							Type: xdr.OperationTypeInvokeHostFunction,
+							InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{
+								Code:    xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess,
+								Success: &xdr.Hash{},
+							},
+						},
+					}},
+				},
+			},
+		},
+		TxApplyProcessing: xdr.TransactionMeta{
+			V:  3,
+			V3: &xdr.TransactionMetaV3{Operations: []xdr.OperationMeta{{Changes: changes}}},
+		},
+	}
+}
+
+func fixtureContractData(symbol string, durability xdr.ContractDataDurability, value uint32, lastModifiedLedger uint32) xdr.LedgerEntry {
+	contractID := fixtureContractID
+	keySymbol := xdr.ScSymbol(symbol)
+	val := xdr.Uint32(value)
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: xdr.Uint32(lastModifiedLedger),
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &keySymbol},
+				Durability: durability,
+				Val:        xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &val},
+			},
+		},
+	}
+}
+
+func fixtureTTL(t *testing.T, entry xdr.LedgerEntry, liveUntilLedger uint32, lastModifiedLedger uint32) xdr.LedgerEntry {
+	ledgerKey, err := entry.LedgerKey()
+	require.NoError(t, err)
+	keyBytes, err := ledgerKey.MarshalBinary()
+	require.NoError(t, err)
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: xdr.Uint32(lastModifiedLedger),
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTtl,
+			Ttl:  &xdr.TtlEntry{KeyHash: xdr.Hash(hash.Hash(keyBytes)), LiveUntilLedgerSeq: xdr.Uint32(liveUntilLedger)},
+		},
+	}
+}
+
+func fixtureCreated(entries ...xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	var changes xdr.LedgerEntryChanges
+	for i := range entries {
+		changes = append(changes, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &entries[i]})
+	}
+	return changes
+}
+
+func fixtureUpdated(pre xdr.LedgerEntry, post xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &pre},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &post},
+	}
+}
+
+func fixtureRemoved(t *testing.T, pre xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	ledgerKey, err := pre.LedgerKey()
+	require.NoError(t, err)
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &pre},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &ledgerKey},
+	}
+}
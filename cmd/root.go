@@ -23,6 +23,7 @@ func DefineCommands() *cobra.Command {
 				cmd.PersistentFlags().Lookup("config-file"),
 				cmd.PersistentFlags().Lookup("backfill"),
 				cmd.PersistentFlags().Lookup("metrics-port"),
+				cmd.PersistentFlags().Lookup("replay-dir"),
 			)
 			config, err := internal.NewConfig(settings)
 			if err != nil {
@@ -39,12 +40,177 @@ func DefineCommands() *cobra.Command {
 	rootCmd.PersistentFlags().String("config-file", "config.toml", "Path to the TOML config file. Defaults to 'config.toml' on runtime working directory path.")
 	rootCmd.PersistentFlags().Bool("backfill", false, "Enable backfill mode. When enabled, the exact start and end ledgers are respected without checking the database for existing data. Use this for historical data imports or re-indexing specific ranges.")
 	rootCmd.PersistentFlags().Int("metrics-port", 8080, "Port for Prometheus metrics.")
+	rootCmd.PersistentFlags().String("replay-dir", "", "Optional path to a local directory of LedgerCloseMetaBatch XDR files (raw or zstd compressed) named with the Galexie naming scheme. "+
+		"When set, ledgers are replayed from this directory instead of the configured datastore and no cloud credentials are needed.")
 	viper.BindPFlags(rootCmd.PersistentFlags())
+	rootCmd.Args = cobra.NoArgs
+
+	rootCmd.AddCommand(defineEstimateRentCommand())
+	rootCmd.AddCommand(defineBackfillAddressReferencesCommand())
+	rootCmd.AddCommand(defineBackfillContractsCommand())
+	rootCmd.AddCommand(defineBackfillEntrySizesCommand())
+	rootCmd.AddCommand(defineRebuildContractStorageStatsCommand())
 
 	return rootCmd
 }
 
-func bindCliParameters(startFlag *pflag.Flag, endFlag *pflag.Flag, configFileFlag *pflag.Flag, backfillFlag *pflag.Flag, metricsPortFlag *pflag.Flag) internal.RuntimeSettings {
+func defineEstimateRentCommand() *cobra.Command {
+	var estimateRentCmd = &cobra.Command{
+		Use:   "estimate-rent",
+		Short: "Estimate the rent to extend the TTL of the indexed live contract storage",
+		Long: "Estimate the rent, in stroops, to extend the TTL of every live contract data entry up to --ledgers ledgers " +
+			"past the last indexed ledger, using the rent parameters in force as of that ledger. Entries are charged for the " +
+			"ledgers their TTL falls short of that target. With --contract-id the estimate of each " +
+			"entry of that contract is printed, otherwise the estimate of every contract is written to contract_rent_estimates.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			configFileFlag := cmd.Flag("config-file")
+			viper.BindPFlag(configFileFlag.Name, configFileFlag)
+			viper.BindEnv(configFileFlag.Name, strutils.KebabToConstantCase(configFileFlag.Name))
+			config, err := internal.NewConfig(internal.RuntimeSettings{ConfigFilePath: viper.GetString(configFileFlag.Name)})
+			if err != nil {
+				internal.Logger.Fatal("Failed to load configuration: ", err)
+			}
+
+			flags := cmd.Flags()
+			settings := internal.RentSettings{}
+			settings.ExtendLedgers, _ = flags.GetUint32("ledgers")
+			settings.ContractId, _ = flags.GetString("contract-id")
+			settings.FeeOverrides.FeeRent1Kb, _ = flags.GetInt64("fee-rent-1kb")
+			settings.FeeOverrides.FeeWrite1Kb, _ = flags.GetInt64("fee-write-1kb")
+			settings.FeeOverrides.FeeWriteLedgerEntry, _ = flags.GetInt64("fee-write-ledger-entry")
+			settings.FeeOverrides.PersistentRentRateDenominator, _ = flags.GetInt64("persistent-rent-rate-denominator")
+			settings.FeeOverrides.TemporaryRentRateDenominator, _ = flags.GetInt64("temporary-rent-rate-denominator")
+			if err := internal.EstimateRent(*config, settings, cmd.OutOrStdout()); err != nil {
+				internal.Logger.Fatal("Failed to estimate rent: ", err)
+			}
+		},
+	}
+
+	estimateRentCmd.Flags().Uint32("ledgers", internal.DefaultRentExtendLedgers, "Number of ledgers past the last indexed ledger to extend the TTL of the live entries to, defaults to about 31 days.")
+	estimateRentCmd.Flags().String("contract-id", "", "Contract to print the estimate of each live entry for. When absent, the estimate of every contract is written to contract_rent_estimates.")
+	estimateRentCmd.Flags().Int64("fee-rent-1kb", 0, "Rent fee per 1KB per ledger, overrides the value of the last indexed ledger.")
+	estimateRentCmd.Flags().Int64("fee-write-1kb", 0, "Write fee per 1KB, overrides the indexed contract_ledger_cost_ext_v0 setting.")
+	estimateRentCmd.Flags().Int64("fee-write-ledger-entry", 0, "Fee per ledger entry written, overrides the indexed contract_ledger_cost_v0 setting.")
+	estimateRentCmd.Flags().Int64("persistent-rent-rate-denominator", 0, "Rent rate denominator of persistent storage, overrides the indexed state_archival setting.")
+	estimateRentCmd.Flags().Int64("temporary-rent-rate-denominator", 0, "Rent rate denominator of temporary storage, overrides the indexed state_archival setting.")
+
+	return estimateRentCmd
+}
+
+func defineBackfillAddressReferencesCommand() *cobra.Command {
+	var backfillAddressReferencesCmd = &cobra.Command{
+		Use:   "backfill-address-references",
+		Short: "Reference the addresses of the contract data entries indexed before address_references",
+		Long: "Walk the stored key and value of every existing contract data entry and replace its rows of " +
+			"address_references, --batch-size entries per transaction. It can run alongside ingestion.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			configFileFlag := cmd.Flag("config-file")
+			viper.BindPFlag(configFileFlag.Name, configFileFlag)
+			viper.BindEnv(configFileFlag.Name, strutils.KebabToConstantCase(configFileFlag.Name))
+			config, err := internal.NewConfig(internal.RuntimeSettings{ConfigFilePath: viper.GetString(configFileFlag.Name)})
+			if err != nil {
+				internal.Logger.Fatal("Failed to load configuration: ", err)
+			}
+
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			if err := internal.BackfillAddressReferences(*config, batchSize); err != nil {
+				internal.Logger.Fatal("Failed to backfill address references: ", err)
+			}
+		},
+	}
+
+	backfillAddressReferencesCmd.Flags().Int("batch-size", internal.DefaultAddressReferenceBatchSize, "Number of contract data entries referenced per transaction.")
+
+	return backfillAddressReferencesCmd
+}
+
+func defineBackfillContractsCommand() *cobra.Command {
+	var backfillContractsCmd = &cobra.Command{
+		Use:   "backfill-contracts",
+		Short: "Record the contracts created before the first indexed ledger",
+		Long: "Walk the stored persistent instance entry of every contract and record its executable in contracts " +
+			"when the contract has no row yet, --batch-size entries per batch. It can run alongside ingestion.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			configFileFlag := cmd.Flag("config-file")
+			viper.BindPFlag(configFileFlag.Name, configFileFlag)
+			viper.BindEnv(configFileFlag.Name, strutils.KebabToConstantCase(configFileFlag.Name))
+			config, err := internal.NewConfig(internal.RuntimeSettings{ConfigFilePath: viper.GetString(configFileFlag.Name)})
+			if err != nil {
+				internal.Logger.Fatal("Failed to load configuration: ", err)
+			}
+
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			if err := internal.BackfillContracts(*config, batchSize); err != nil {
+				internal.Logger.Fatal("Failed to backfill contracts: ", err)
+			}
+		},
+	}
+
+	backfillContractsCmd.Flags().Int("batch-size", internal.DefaultContractBatchSize, "Number of contract instance entries read per batch.")
+
+	return backfillContractsCmd
+}
+
+func defineBackfillEntrySizesCommand() *cobra.Command {
+	var backfillEntrySizesCmd = &cobra.Command{
+		Use:   "backfill-entry-sizes",
+		Short: "Record the entry size of the contract data rows indexed before entry_size_bytes",
+		Long: "Rebuild the ledger entry of every contract data row without an entry size from its stored key and value " +
+			"and record its XDR size, --batch-size rows per statement. It can run alongside ingestion.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			configFileFlag := cmd.Flag("config-file")
+			viper.BindPFlag(configFileFlag.Name, configFileFlag)
+			viper.BindEnv(configFileFlag.Name, strutils.KebabToConstantCase(configFileFlag.Name))
+			config, err := internal.NewConfig(internal.RuntimeSettings{ConfigFilePath: viper.GetString(configFileFlag.Name)})
+			if err != nil {
+				internal.Logger.Fatal("Failed to load configuration: ", err)
+			}
+
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			if err := internal.BackfillEntrySizes(*config, batchSize); err != nil {
+				internal.Logger.Fatal("Failed to backfill entry sizes: ", err)
+			}
+		},
+	}
+
+	backfillEntrySizesCmd.Flags().Int("batch-size", internal.DefaultEntrySizeBatchSize, "Number of contract data rows sized per statement.")
+
+	return backfillEntrySizesCmd
+}
+
+func defineRebuildContractStorageStatsCommand() *cobra.Command {
+	var rebuildContractStorageStatsCmd = &cobra.Command{
+		Use:   "rebuild-contract-storage-stats",
+		Short: "Recompute contract_storage_stats from the indexed contract data",
+		Long: "Recompute the storage stats of every contract with indexed contract data, --batch-size contracts per " +
+			"transaction. Run backfill-entry-sizes first so that every entry is counted with its size. It can run alongside ingestion.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			configFileFlag := cmd.Flag("config-file")
+			viper.BindPFlag(configFileFlag.Name, configFileFlag)
+			viper.BindEnv(configFileFlag.Name, strutils.KebabToConstantCase(configFileFlag.Name))
+			config, err := internal.NewConfig(internal.RuntimeSettings{ConfigFilePath: viper.GetString(configFileFlag.Name)})
+			if err != nil {
+				internal.Logger.Fatal("Failed to load configuration: ", err)
+			}
+
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			if err := internal.RebuildContractStorageStats(*config, batchSize); err != nil {
+				internal.Logger.Fatal("Failed to rebuild contract storage stats: ", err)
+			}
+		},
+	}
+
+	rebuildContractStorageStatsCmd.Flags().Int("batch-size", internal.DefaultStorageStatsBatchSize, "Number of contracts rebuilt per transaction.")
+
+	return rebuildContractStorageStatsCmd
+}
+
+func bindCliParameters(startFlag *pflag.Flag, endFlag *pflag.Flag, configFileFlag *pflag.Flag, backfillFlag *pflag.Flag, metricsPortFlag *pflag.Flag, replayDirFlag *pflag.Flag) internal.RuntimeSettings {
 	settings := internal.RuntimeSettings{}
 
 	viper.BindPFlag(startFlag.Name, startFlag)
@@ -67,5 +233,9 @@ func bindCliParameters(startFlag *pflag.Flag, endFlag *pflag.Flag, configFileFla
 	viper.BindEnv(metricsPortFlag.Name, strutils.KebabToConstantCase(metricsPortFlag.Name))
 	settings.MetricsPort = viper.GetInt(metricsPortFlag.Name)
 
+	viper.BindPFlag(replayDirFlag.Name, replayDirFlag)
+	viper.BindEnv(replayDirFlag.Name, strutils.KebabToConstantCase(replayDirFlag.Name))
+	settings.ReplayDir = viper.GetString(replayDirFlag.Name)
+
 	return settings
 }
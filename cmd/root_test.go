@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"strconv"
 	"testing"
 
+	"github.com/stellar/go-stellar-sdk/strkey"
 	"github.com/stellar/go-stellar-sdk/support/db"
 	"github.com/stellar/go-stellar-sdk/support/db/dbtest"
+	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -15,7 +18,10 @@ type LedgerDataIndexerTestSuite struct {
 	suite.Suite
 	db             *dbtest.DB
 	tempConfigFile string
-	ctx            context.Context
+	// replayDir holds the ledgers generated by writeReplayFixture, transactionHashes the hashes of their transactions
+	replayDir         string
+	transactionHashes map[string]string
+	ctx               context.Context
 }
 
 func TestLedgerDataIndexerTestSuite(t *testing.T) {
@@ -27,6 +33,8 @@ func TestLedgerDataIndexerTestSuite(t *testing.T) {
 }
 
 func (s *LedgerDataIndexerTestSuite) SetupSuite() {
+	s.replayDir = s.T().TempDir()
+	s.transactionHashes = writeReplayFixture(s.T(), s.replayDir)
 	s.db = dbtest.Postgres(s.T())
 	os.Setenv("POSTGRES_CONN_STRING", s.db.DSN)
 }
@@ -43,7 +51,7 @@ func (s *LedgerDataIndexerTestSuite) TestIndex() {
 	var outWriter bytes.Buffer
 	rootCmd.SetErr(&errWriter)
 	rootCmd.SetOut(&outWriter)
-	rootCmd.SetArgs([]string{"append", "--start", "59561994", "--end", "59562000", "--config-file", s.tempConfigFile})
+	rootCmd.SetArgs([]string{"--start", strconv.Itoa(fixtureStartLedger), "--end", strconv.Itoa(fixtureEndLedger), "--config-file", s.tempConfigFile, "--replay-dir", s.replayDir})
 	err := rootCmd.ExecuteContext(s.ctx)
 	require.NoError(err)
 
@@ -56,93 +64,81 @@ func (s *LedgerDataIndexerTestSuite) TestIndex() {
 	defer sess.DB.Close()
 
 	type ContractRow struct {
-		ContractID              string `db:"contract_id"`
-		LedgerSequence          int64  `db:"ledger_sequence"`
-		KeyHash                 string `db:"key_hash"`
-		Durability              string `db:"durability"`
-		KeySymbol               string `db:"key_symbol"`
-		Key                     string `db:"key"`
-		Val                     string `db:"val"`
-		ClosedAt                string `db:"closed_at"`
-		LiveUntilLedgerSequence int64  `db:"live_until_ledger_sequence"`
-	}
-
-	var actualTopRecords []ContractRow
-	expectedTopRecords := []ContractRow{
-		{
-			ContractID:              "CAJJZSGMMM3PD7N33TAPHGBUGTB43OC73HVIK2L2G6BNGGGYOSSYBXBD",
-			LedgerSequence:          59561994,
-			KeyHash:                 "36fe0c8c9820c31c664007bc0e8b0b9dda0792d680cf2e2c5c662c2ca00b9b8e",
-			Durability:              "persistent",
-			KeySymbol:               "Positions",
-			Key:                     "AAAAEAAAAAEAAAACAAAADwAAAAlQb3NpdGlvbnMAAAAAAAASAAAAAAAAAAAwzvi1/ZEwKCNnHK/ga7sh+ZbL98pt+bfr4jULxnvmHA==",
-			Val:                     "AAAAEQAAAAEAAAADAAAADwAAAApjb2xsYXRlcmFsAAAAAAARAAAAAQAAAAEAAAADAAAAAQAAAAoAAAAAAAAAAAAAACz2APNwAAAADwAAAAtsaWFiaWxpdGllcwAAAAARAAAAAQAAAAAAAAAPAAAABnN1cHBseQAAAAAAEQAAAAEAAAAA",
-			ClosedAt:                "2025-10-26T17:15:02Z",
-			LiveUntilLedgerSequence: 0,
-		},
-		{
-			ContractID:              "CAJJZSGMMM3PD7N33TAPHGBUGTB43OC73HVIK2L2G6BNGGGYOSSYBXBD",
-			LedgerSequence:          59561994,
-			KeyHash:                 "ab35299631fa4e2c9dedf9eb4143e3470234a2e27dcf2cf440e9e95930558a13",
-			Durability:              "persistent",
-			KeySymbol:               "Positions",
-			Key:                     "AAAAEAAAAAEAAAACAAAADwAAAAlQb3NpdGlvbnMAAAAAAAASAAAAAWisTBR8X2z/wCNGgwgYLotCqrv+MkfhMsIEwLwvSF6l",
-			Val:                     "AAAAEQAAAAEAAAADAAAADwAAAApjb2xsYXRlcmFsAAAAAAARAAAAAQAAAAAAAAAPAAAAC2xpYWJpbGl0aWVzAAAAABEAAAABAAAAAAAAAA8AAAAGc3VwcGx5AAAAAAARAAAAAQAAAAEAAAADAAAAAQAAAAoAAAAAAAAAAAAAI/sF8TEG",
-			ClosedAt:                "2025-10-26T17:15:02Z",
-			LiveUntilLedgerSequence: 0,
-		},
+		ContractID      string `db:"contract_id"`
+		LedgerSequence  int64  `db:"ledger_sequence"`
+		Durability      string `db:"durability"`
+		Val             string `db:"val"`
+		ClosedAt        string `db:"closed_at"`
+		Deleted         bool   `db:"deleted"`
+		TransactionHash string `db:"transaction_hash"`
 	}
-	require.NoError(sess.SelectRaw(context.Background(), &actualTopRecords, `SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at FROM contract_data where key_symbol != '' order by 1,2,3 limit 2;`))
-	require.Equal(expectedTopRecords, actualTopRecords)
 
-	var actualCount []int
-	expectedCount := []int{964}
-	require.NoError(sess.SelectRaw(context.Background(), &actualCount, `SELECT count(*) FROM contract_data;`))
-	require.Equal(expectedCount, actualCount)
-
-	var actualHistoricalRecords []ContractRow
-	expectedHistoricalRecords := []ContractRow{
-		{
-			ContractID:              "CAS3J7GYLGXMF6TDJBBYYSE3HQ6BBSMLNUQ34T6TZMYMW2EVH34XOWMA",
-			LedgerSequence:          59562000,
-			KeyHash:                 "72b51b3784ece8d155164e1cb15488931742566809555e3b46b8734ef6fbd453",
-			Durability:              "persistent",
-			KeySymbol:               "Balance",
-			Key:                     "AAAAEAAAAAEAAAACAAAADwAAAAdCYWxhbmNlAAAAABIAAAABl2X1uJV1ZMN1DTnAMGclVEeDnW6g/S1+07aaqea1gQo=",
-			Val:                     "AAAAEQAAAAEAAAADAAAADwAAAAZhbW91bnQAAAAAAAoAAAAAAAAAAAAABJfeaJgvAAAADwAAAAphdXRob3JpemVkAAAAAAAAAAAAAQAAAA8AAAAIY2xhd2JhY2sAAAAAAAAAAA==",
-			ClosedAt:                "2025-10-26T17:15:36Z",
-			LiveUntilLedgerSequence: 0,
-		},
+	contractID := strkey.MustEncode(strkey.VersionByteContract, fixtureContractID[:])
+	scValU32 := func(value uint32) string {
+		u32 := xdr.Uint32(value)
+		encoded, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &u32})
+		require.NoError(err)
+		return encoded
 	}
-	require.NoError(sess.SelectRaw(context.Background(), &actualHistoricalRecords, `SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at FROM contract_data where key_hash = '72b51b3784ece8d155164e1cb15488931742566809555e3b46b8734ef6fbd453' order by 1,2,3 limit 10;`))
-	require.Equal(expectedHistoricalRecords, actualHistoricalRecords)
 
-	var actualRecordsWithTTL []ContractRow
-	expectedRecordsWithTTL := []ContractRow{
+	// The counter holds its last value, the removed session entry is kept as a tombstone holding its last value
+	var actualRecords []ContractRow
+	expectedRecords := []ContractRow{
 		{
-			ContractID:              "CAFJZQWSED6YAWZU3GWRTOCNPPCGBN32L7QV43XX5LZLFTK6JLN34DLN",
-			LedgerSequence:          59561998,
-			KeyHash:                 "083c5803f7556890f01084c5b339b331c6a484b482f128c912f5df78df60b00e",
-			Durability:              "temporary",
-			KeySymbol:               "",
-			Key:                     "AAAACQAAAZohhCYgAAAAAAAAAAo=",
-			Val:                     "AAAACgAAAAAAAAAAAAZ2mEKM0VI=",
-			ClosedAt:                "2025-10-26T17:15:24Z",
-			LiveUntilLedgerSequence: 59579279,
+			ContractID:      contractID,
+			LedgerSequence:  101,
+			Durability:      "persistent",
+			Val:             scValU32(2),
+			ClosedAt:        "2025-10-09T08:53:25Z",
+			Deleted:         false,
+			TransactionHash: s.transactionHashes["increment"],
 		},
 		{
-			ContractID:              "CAFJZQWSED6YAWZU3GWRTOCNPPCGBN32L7QV43XX5LZLFTK6JLN34DLN",
-			LedgerSequence:          59561998,
-			KeyHash:                 "0da09bff57620014be2b220c48ab6b880038b2cae4af7d4c00cf5b6bed4741f8",
-			Durability:              "temporary",
-			KeySymbol:               "",
-			Key:                     "AAAACQAAAZohhCYgAAAAAAAAAAA=",
-			Val:                     "AAAACgAAAAAAAAAAnc5VUZBdVuI=",
-			ClosedAt:                "2025-10-26T17:15:24Z",
-			LiveUntilLedgerSequence: 59579279,
+			ContractID:      contractID,
+			LedgerSequence:  103,
+			Durability:      "temporary",
+			Val:             scValU32(7),
+			ClosedAt:        "2025-10-09T08:53:35Z",
+			Deleted:         true,
+			TransactionHash: s.transactionHashes["close"],
 		},
 	}
-	require.NoError(sess.SelectRaw(context.Background(), &actualRecordsWithTTL, `SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence FROM contract_data where live_until_ledger_sequence is not null order by 1,2,3 limit 2;`))
-	require.Equal(expectedRecordsWithTTL, actualRecordsWithTTL)
+	require.NoError(sess.SelectRaw(context.Background(), &actualRecords, `SELECT contract_id, ledger_sequence, durability, val, closed_at, deleted, transaction_hash FROM contract_data order by ledger_sequence;`))
+	require.Equal(expectedRecords, actualRecords)
 
+	// The extended ttl of the counter is applied to its row, which is live as of the last indexed ledger
+	type CurrentRow struct {
+		LedgerSequence          int64  `db:"ledger_sequence"`
+		LiveUntilLedgerSequence int64  `db:"live_until_ledger_sequence"`
+		LivenessState           string `db:"liveness_state"`
+	}
+	var actualCurrentRecords []CurrentRow
+	expectedCurrentRecords := []CurrentRow{{LedgerSequence: 101, LiveUntilLedgerSequence: 2000, LivenessState: "live"}}
+	require.NoError(sess.SelectRaw(context.Background(), &actualCurrentRecords, `SELECT ledger_sequence, live_until_ledger_sequence, liveness_state FROM current_contract_data;`))
+	require.Equal(expectedCurrentRecords, actualCurrentRecords)
+
+	type InvocationRow struct {
+		LedgerSequence  int64  `db:"ledger_sequence"`
+		FunctionName    string `db:"function_name"`
+		TransactionHash string `db:"transaction_hash"`
+	}
+	var actualInvocations []InvocationRow
+	expectedInvocations := []InvocationRow{
+		{LedgerSequence: 100, FunctionName: "init", TransactionHash: s.transactionHashes["init"]},
+		{LedgerSequence: 101, FunctionName: "increment", TransactionHash: s.transactionHashes["increment"]},
+		{LedgerSequence: 103, FunctionName: "close", TransactionHash: s.transactionHashes["close"]},
+	}
+	require.NoError(sess.SelectRaw(context.Background(), &actualInvocations, `SELECT ledger_sequence, function_name, transaction_hash FROM contract_invocations order by ledger_sequence;`))
+	require.Equal(expectedInvocations, actualInvocations)
+
+	var actualCursors []int64
+	expectedCursors := []int64{103, 103, 103, 103, 103, 103, 103, 103, 103, 103, 103, 103, 103, 103, 103}
+	require.NoError(sess.SelectRaw(context.Background(), &actualCursors, `SELECT ledger_sequence FROM ingestion_cursor order by dataset;`))
+	require.Equal(expectedCursors, actualCursors)
+
+	// Every ledger of the range is indexed and chained to the previous one
+	var actualLedgerCounts []int
+	expectedLedgerCounts := []int{4, 3}
+	require.NoError(sess.SelectRaw(context.Background(), &actualLedgerCounts, `SELECT count(*) FROM ledgers UNION ALL SELECT count(*) FROM ledgers l JOIN ledgers p ON p.ledger_sequence = l.ledger_sequence - 1 AND p.ledger_hash = l.previous_ledger_hash;`))
+	require.Equal(expectedLedgerCounts, actualLedgerCounts)
 }
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// DefaultAddressReferenceBatchSize is the number of contract data entries referenced per transaction
+const DefaultAddressReferenceBatchSize = 1000
+
+// addressReferenceOutputs decodes the stored key and value of the entries and finds their addresses the same way
+// as ingestion does. The references of evicted entries are written flagged.
+func addressReferenceOutputs(entries []db.StoredContractData) ([]interface{}, error) {
+	outputs := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		var key, val xdr.ScVal
+		if err := xdr.SafeUnmarshalBase64(string(entry.Key), &key); err != nil {
+			return nil, fmt.Errorf("could not decode key of %s: %w", entry.KeyHash, err)
+		}
+		if err := xdr.SafeUnmarshalBase64(string(entry.Val), &val); err != nil {
+			return nil, fmt.Errorf("could not decode val of %s: %w", entry.KeyHash, err)
+		}
+		outputs = append(outputs, contract.ContractDataOutput{
+			ContractId:     entry.ContractId,
+			LedgerSequence: entry.LedgerSequence,
+			LedgerKeyHash:  entry.KeyHash,
+			ClosedAt:       entry.ClosedAt,
+			KeyAddresses:   contract.ScValAddresses(key),
+			ValAddresses:   contract.ScValAddresses(val),
+			Evicted:        entry.Evicted,
+		})
+	}
+	return outputs, nil
+}
+
+// BackfillAddressReferences references the addresses of every existing contract data entry, e.g. the ones
+// indexed before address_references was added, batchSize entries per transaction. It can run alongside
+// ingestion, entries changed in the meantime keep the references of their latest version.
+func BackfillAddressReferences(config Config, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	metricRecorder := utils.GetNewMetricRecorder(ctx, Logger, prometheus.NewRegistry(), nameSpace)
+	dbOperator := db.NewAddressReferenceDBOperator(*session, metricRecorder)
+
+	var afterKeyHash string
+	var total int
+	for {
+		entries, err := session.StoredContractDataAfter(ctx, afterKeyHash, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+		outputs, err := addressReferenceOutputs(entries)
+		if err != nil {
+			return err
+		}
+		if err := referenceBatch(ctx, session, dbOperator, outputs); err != nil {
+			return err
+		}
+		afterKeyHash = entries[len(entries)-1].KeyHash
+		total += len(entries)
+		Logger.Infof("Referenced the addresses of %d contract data entries, up to key hash %s", total, afterKeyHash)
+	}
+	Logger.Infof("Referenced the addresses of %d contract data entries", total)
+	return nil
+}
+
+// referenceBatch replaces the references of a batch of entries in a single transaction
+func referenceBatch(ctx context.Context, session *db.DBSession, dbOperator db.AddressReferenceDBOperator, outputs []interface{}) error {
+	if err := session.Session().Begin(ctx); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := dbOperator.Upsert(ctx, outputs); err != nil {
+		session.Session().Rollback()
+		return err
+	}
+	if err := session.Session().Commit(); err != nil {
+		return fmt.Errorf("failed to commit address references: %w", err)
+	}
+	return nil
+}
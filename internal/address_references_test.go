@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressReferenceOutputs(t *testing.T) {
+	accountId := xdr.MustAddress("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF")
+	balance := xdr.ScSymbol("Balance")
+	keyVec := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &balance},
+		{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}},
+	}
+	key, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &keyVec})
+	require.NoError(t, err)
+	amount := xdr.Uint32(7)
+	val, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount})
+	require.NoError(t, err)
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+
+	outputs, err := addressReferenceOutputs([]db.StoredContractData{{
+		KeyHash:        "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca",
+		ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		LedgerSequence: 100,
+		ClosedAt:       closedAt,
+		Key:            []byte(key),
+		Val:            []byte(val),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{contract.ContractDataOutput{
+		ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		LedgerSequence: 100,
+		LedgerKeyHash:  "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca",
+		ClosedAt:       closedAt,
+		KeyAddresses:   []string{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"},
+	}}, outputs)
+
+	_, err = addressReferenceOutputs([]db.StoredContractData{{KeyHash: "01", Key: []byte("not xdr")}})
+	assert.Error(t, err)
+}
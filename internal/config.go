@@ -2,11 +2,15 @@ package internal
 
 import (
 	_ "embed"
+	"encoding/hex"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
 	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
 	"github.com/stellar/go-stellar-sdk/support/datastore"
 	"github.com/stellar/go-stellar-sdk/support/log"
 )
@@ -33,6 +37,21 @@ const (
 	adminServerShutdownTimeout = 5 * time.Second
 )
 
+// Supported values for datastore_config.type
+const (
+	DataStoreTypeGCS        = "GCS"
+	DataStoreTypeS3         = "S3"
+	DataStoreTypeFilesystem = "Filesystem"
+)
+
+// Supported values for indexer_config.deleted_entries
+const (
+	// DeletedEntriesTombstone keeps removed entries with deleted set and the ledger they were removed at
+	DeletedEntriesTombstone = "tombstone"
+	// DeletedEntriesRemove deletes the rows of removed entries
+	DeletedEntriesRemove = "remove"
+)
+
 type StellarCoreConfig struct {
 	Network               string `toml:"network"`
 	NetworkPassphrase     string `toml:"network_passphrase"`
@@ -48,6 +67,38 @@ type RuntimeSettings struct {
 	ConfigFilePath string
 	Backfill       bool
 	MetricsPort    int
+	ReplayDir      string
+}
+
+// OptionalDatasets lists the datasets that are only indexed when enabled in indexer_config.optional_datasets
+var OptionalDatasets = []string{"contract_data_history"}
+
+type IndexerConfig struct {
+	DeletedEntries   string   `toml:"deleted_entries"`
+	OptionalDatasets []string `toml:"optional_datasets"`
+	// RPCURL is a Stellar RPC server the current network configuration settings are read from at startup
+	RPCURL string `toml:"rpc_url"`
+}
+
+// DefaultExpiryThresholdLedgers is about a day of ledgers closing every 5 seconds
+const DefaultExpiryThresholdLedgers = uint32(17280)
+
+// ExpiryWatchlistConfig lists the entries to notify about before their TTL runs out. Watching a contract watches
+// all of its contract data entries, key hashes can also be the ledger key hash of contract code.
+type ExpiryWatchlistConfig struct {
+	ContractIds []string `toml:"contract_ids"`
+	KeyHashes   []string `toml:"key_hashes"`
+	// ThresholdLedgers is the number of ledgers before live_until_ledger_sequence at which to notify
+	ThresholdLedgers uint32 `toml:"threshold_ledgers"`
+	// WebhookURL receives a POST with each notification as a JSON body
+	WebhookURL string `toml:"webhook_url"`
+	// NDJSONPath is a local file each notification is appended to as a line of JSON
+	NDJSONPath string `toml:"ndjson_path"`
+}
+
+// Enabled reports whether any entry is watched
+func (c ExpiryWatchlistConfig) Enabled() bool {
+	return len(c.ContractIds) > 0 || len(c.KeyHashes) > 0
 }
 
 type PostgresConfig struct {
@@ -61,10 +112,13 @@ type Config struct {
 	DataStoreConfig   datastore.DataStoreConfig `toml:"datastore_config"`
 	StellarCoreConfig StellarCoreConfig         `toml:"stellar_core_config"`
 	PostgresConfig    PostgresConfig            `toml:"postgres_config"`
+	IndexerConfig     IndexerConfig             `toml:"indexer_config"`
+	ExpiryWatchlist   ExpiryWatchlistConfig     `toml:"expiry_watchlist"`
 	StartLedger       uint32
 	EndLedger         uint32
 	Backfill          bool
 	MetricsPort       int
+	ReplayDir         string
 }
 
 func NewConfig(settings RuntimeSettings) (*Config, error) {
@@ -77,6 +131,7 @@ func NewConfig(settings RuntimeSettings) (*Config, error) {
 	config.EndLedger = uint32(settings.EndLedger)
 	config.Backfill = settings.Backfill
 	config.MetricsPort = settings.MetricsPort
+	config.ReplayDir = settings.ReplayDir
 
 	Logger.Infof("Requested export with start=%d, end=%d, backfill=%t", config.StartLedger, config.EndLedger, config.Backfill)
 
@@ -100,6 +155,21 @@ func (config *Config) processToml(tomlPath string) error {
 		return errors.Wrap(err, "Error unmarshalling TOML config.")
 	}
 
+	// Replay mode reads ledgers from local files, so no datastore is needed
+	if config.ReplayDir == "" {
+		if err = validateDataStoreConfig(config.DataStoreConfig); err != nil {
+			return err
+		}
+	}
+
+	if err = validateIndexerConfig(&config.IndexerConfig); err != nil {
+		return err
+	}
+
+	if err = validateExpiryWatchlistConfig(&config.ExpiryWatchlist); err != nil {
+		return err
+	}
+
 	if config.StellarCoreConfig.Network == "" && (config.StellarCoreConfig.NetworkPassphrase == "" || config.StellarCoreConfig.CaptiveCoreTomlPath == "") {
 		return errors.New("Invalid captive core config, the 'network' parameter must be set to pubnet or testnet or " +
 			"'stellar_core_config.network_passphrase' and 'stellar_core_config.captive_core_toml_path' must be set.")
@@ -134,3 +204,66 @@ func (config *Config) processToml(tomlPath string) error {
 
 	return nil
 }
+
+// validateDataStoreConfig checks that the datastore type is supported and that the
+// parameters it requires are present, so misconfigurations fail before ingestion starts.
+func validateDataStoreConfig(dataStoreConfig datastore.DataStoreConfig) error {
+	switch dataStoreConfig.Type {
+	case DataStoreTypeGCS, DataStoreTypeS3:
+		if dataStoreConfig.Params["destination_bucket_path"] == "" {
+			return errors.Errorf("datastore_config.params.destination_bucket_path must be set for datastore type %s", dataStoreConfig.Type)
+		}
+	case DataStoreTypeFilesystem:
+		if dataStoreConfig.Params["destination_path"] == "" {
+			return errors.Errorf("datastore_config.params.destination_path must be set for datastore type %s", dataStoreConfig.Type)
+		}
+	default:
+		return errors.Errorf("invalid datastore_config.type %q, must be one of %s, %s or %s",
+			dataStoreConfig.Type, DataStoreTypeGCS, DataStoreTypeS3, DataStoreTypeFilesystem)
+	}
+	return nil
+}
+
+// validateIndexerConfig checks the indexer options and fills in the defaults of the ones left unset.
+func validateIndexerConfig(indexerConfig *IndexerConfig) error {
+	switch indexerConfig.DeletedEntries {
+	case "":
+		indexerConfig.DeletedEntries = DeletedEntriesTombstone
+	case DeletedEntriesTombstone, DeletedEntriesRemove:
+	default:
+		return errors.Errorf("invalid indexer_config.deleted_entries %q, must be %s or %s",
+			indexerConfig.DeletedEntries, DeletedEntriesTombstone, DeletedEntriesRemove)
+	}
+
+	for _, dataset := range indexerConfig.OptionalDatasets {
+		if !slices.Contains(OptionalDatasets, dataset) {
+			return errors.Errorf("invalid indexer_config.optional_datasets entry %q, must be one of %s",
+				dataset, strings.Join(OptionalDatasets, ", "))
+		}
+	}
+	return nil
+}
+
+// validateExpiryWatchlistConfig checks the watched entries and where to notify, and fills in the default threshold.
+func validateExpiryWatchlistConfig(watchlistConfig *ExpiryWatchlistConfig) error {
+	if !watchlistConfig.Enabled() {
+		return nil
+	}
+	for _, contractId := range watchlistConfig.ContractIds {
+		if _, err := strkey.Decode(strkey.VersionByteContract, contractId); err != nil {
+			return errors.Errorf("invalid expiry_watchlist.contract_ids entry %q, must be a contract strkey", contractId)
+		}
+	}
+	for _, keyHash := range watchlistConfig.KeyHashes {
+		if decoded, err := hex.DecodeString(keyHash); err != nil || len(decoded) != 32 {
+			return errors.Errorf("invalid expiry_watchlist.key_hashes entry %q, must be a hex encoded 32 byte hash", keyHash)
+		}
+	}
+	if watchlistConfig.WebhookURL == "" && watchlistConfig.NDJSONPath == "" {
+		return errors.New("expiry_watchlist.webhook_url or expiry_watchlist.ndjson_path must be set to notify about watched entries")
+	}
+	if watchlistConfig.ThresholdLedgers == 0 {
+		watchlistConfig.ThresholdLedgers = DefaultExpiryThresholdLedgers
+	}
+	return nil
+}
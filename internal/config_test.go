@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/support/datastore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDataStoreConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  datastore.DataStoreConfig
+		wantErr bool
+	}{
+		{
+			name:   "GCS with bucket path",
+			config: datastore.DataStoreConfig{Type: "GCS", Params: map[string]string{"destination_bucket_path": "bucket/ledgers"}},
+		},
+		{
+			name:   "S3 with bucket path and custom endpoint",
+			config: datastore.DataStoreConfig{Type: "S3", Params: map[string]string{"destination_bucket_path": "bucket/ledgers", "endpoint_url": "http://localhost:9000"}},
+		},
+		{
+			name:   "Filesystem with destination path",
+			config: datastore.DataStoreConfig{Type: "Filesystem", Params: map[string]string{"destination_path": "/data/ledgers"}},
+		},
+		{
+			name:    "S3 without bucket path",
+			config:  datastore.DataStoreConfig{Type: "S3", Params: map[string]string{"region": "us-east-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "Filesystem without destination path",
+			config:  datastore.DataStoreConfig{Type: "Filesystem"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			config:  datastore.DataStoreConfig{Type: "Azure", Params: map[string]string{"destination_bucket_path": "bucket/ledgers"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDataStoreConfig(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateIndexerConfig(t *testing.T) {
+	tests := []struct {
+		name               string
+		config             IndexerConfig
+		wantDeletedEntries string
+		wantErr            bool
+	}{
+		{
+			name:               "defaults to tombstones",
+			config:             IndexerConfig{},
+			wantDeletedEntries: DeletedEntriesTombstone,
+		},
+		{
+			name:               "remove deleted entries",
+			config:             IndexerConfig{DeletedEntries: DeletedEntriesRemove},
+			wantDeletedEntries: DeletedEntriesRemove,
+		},
+		{
+			name:    "unsupported deleted entries mode",
+			config:  IndexerConfig{DeletedEntries: "archive"},
+			wantErr: true,
+		},
+		{
+			name:               "optional contract data history",
+			config:             IndexerConfig{OptionalDatasets: []string{"contract_data_history"}},
+			wantDeletedEntries: DeletedEntriesTombstone,
+		},
+		{
+			name:    "unknown optional dataset",
+			config:  IndexerConfig{OptionalDatasets: []string{"contract_data_history", "offers"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIndexerConfig(&tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeletedEntries, tt.config.DeletedEntries)
+		})
+	}
+}
+
+func TestValidateExpiryWatchlistConfig(t *testing.T) {
+	contractId := "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4"
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	tests := []struct {
+		name          string
+		config        ExpiryWatchlistConfig
+		wantThreshold uint32
+		wantErr       bool
+	}{
+		{
+			name:   "nothing watched",
+			config: ExpiryWatchlistConfig{},
+		},
+		{
+			name:          "defaults the threshold",
+			config:        ExpiryWatchlistConfig{ContractIds: []string{contractId}, NDJSONPath: "expiry.ndjson"},
+			wantThreshold: DefaultExpiryThresholdLedgers,
+		},
+		{
+			name:          "key hashes notified to a webhook",
+			config:        ExpiryWatchlistConfig{KeyHashes: []string{keyHash}, ThresholdLedgers: 100, WebhookURL: "http://localhost:8000/expiry"},
+			wantThreshold: 100,
+		},
+		{
+			name:    "nowhere to notify",
+			config:  ExpiryWatchlistConfig{ContractIds: []string{contractId}},
+			wantErr: true,
+		},
+		{
+			name:    "account instead of contract",
+			config:  ExpiryWatchlistConfig{ContractIds: []string{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"}, NDJSONPath: "expiry.ndjson"},
+			wantErr: true,
+		},
+		{
+			name:    "truncated key hash",
+			config:  ExpiryWatchlistConfig{KeyHashes: []string{keyHash[:62]}, NDJSONPath: "expiry.ndjson"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExpiryWatchlistConfig(&tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantThreshold, tt.config.ThresholdLedgers)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package contract
+
+import (
+	"sort"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ScValAddresses returns the strkeys of the addresses found anywhere inside scVal, e.g. G..., C... or M...,
+// sorted and without duplicates. It returns nil when scVal holds no address.
+func ScValAddresses(scVal xdr.ScVal) []string {
+	found := map[string]bool{}
+	collectScValAddresses(scVal, found)
+	if len(found) == 0 {
+		return nil
+	}
+	addresses := make([]string, 0, len(found))
+	for address := range found {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func collectScValAddresses(scVal xdr.ScVal, found map[string]bool) {
+	switch scVal.Type {
+	case xdr.ScValTypeScvAddress:
+		// Addresses that cannot be encoded as a strkey are not referenced
+		if address, err := scVal.MustAddress().String(); err == nil {
+			found[address] = true
+		}
+	case xdr.ScValTypeScvVec:
+		if vec := scVal.MustVec(); vec != nil {
+			for _, element := range *vec {
+				collectScValAddresses(element, found)
+			}
+		}
+	case xdr.ScValTypeScvMap:
+		if scMap := scVal.MustMap(); scMap != nil {
+			collectScMapAddresses(*scMap, found)
+		}
+	case xdr.ScValTypeScvContractInstance:
+		// Instance storage is held in the value of the contract instance entry
+		if storage := scVal.MustInstance().Storage; storage != nil {
+			collectScMapAddresses(*storage, found)
+		}
+	}
+}
+
+func collectScMapAddresses(scMap xdr.ScMap, found map[string]bool) {
+	for _, entry := range scMap {
+		collectScValAddresses(entry.Key, found)
+		collectScValAddresses(entry.Val, found)
+	}
+}
@@ -0,0 +1,34 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScValAddresses(t *testing.T) {
+	accountId := xdr.MustAddress("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF")
+	var contractId xdr.ContractId
+	account := xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}}
+	contractAddress := xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId}}
+	balance := xdr.ScSymbol("Balance")
+	symbol := xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &balance}
+
+	// Balance(account) with a value holding both addresses, nested in a map and a vec
+	keyVec := &xdr.ScVec{symbol, account}
+	key := xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &keyVec}
+	nested := &xdr.ScVec{contractAddress, account}
+	valMap := &xdr.ScMap{
+		{Key: symbol, Val: xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &nested}},
+		{Key: account, Val: symbol},
+	}
+	val := xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &valMap}
+
+	assert.Equal(t, []string{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"}, ScValAddresses(key))
+	assert.Equal(t, []string{
+		"CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF",
+	}, ScValAddresses(val))
+	assert.Nil(t, ScValAddresses(symbol))
+}
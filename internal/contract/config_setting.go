@@ -0,0 +1,137 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ConfigSettingOutput is the value of a network configuration setting as of the ledger that changed it
+type ConfigSettingOutput struct {
+	// ConfigSettingId is the snake case name of the setting, e.g. contract_compute_v0
+	ConfigSettingId string    `json:"config_setting_id"`
+	LedgerSequence  uint32    `json:"ledger_sequence"`
+	ClosedAt        time.Time `json:"closed_at"`
+	// Value is the JSON encoded setting, with snake case field names, e.g. {"tx_max_instructions": 100000000, ...}
+	Value    string `json:"value"`
+	ValueXDR string `json:"value_xdr"`
+}
+
+// TransformConfigSetting converts a change of a ConfigSetting entry into a ConfigSettingOutput.
+// Config settings are never removed, it returns false for changes without a post state.
+func TransformConfigSetting(ledgerChange ingest.Change, header xdr.LedgerHeaderHistoryEntry) (ConfigSettingOutput, bool, error) {
+	if ledgerChange.Post == nil {
+		return ConfigSettingOutput{}, false, nil
+	}
+	configSetting, ok := ledgerChange.Post.Data.GetConfigSetting()
+	if !ok {
+		return ConfigSettingOutput{}, false, fmt.Errorf("could not extract config setting from ledger entry; actual type is %s", ledgerChange.Post.Data.Type)
+	}
+
+	closedAt, err := TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return ConfigSettingOutput{}, false, err
+	}
+	value, err := ConfigSettingToJSON(configSetting)
+	if err != nil {
+		return ConfigSettingOutput{}, false, err
+	}
+	valueXDR, err := xdr.MarshalBase64(configSetting)
+	if err != nil {
+		return ConfigSettingOutput{}, false, fmt.Errorf("could not encode config setting %s: %w", configSetting.ConfigSettingId, err)
+	}
+
+	return ConfigSettingOutput{
+		ConfigSettingId: ConfigSettingIdName(configSetting.ConfigSettingId),
+		LedgerSequence:  uint32(header.Header.LedgerSeq),
+		ClosedAt:        closedAt,
+		Value:           value,
+		ValueXDR:        valueXDR,
+	}, true, nil
+}
+
+// ConfigSettingIdName returns the snake case name of a config setting id, e.g. contract_compute_v0
+// for ConfigSettingIdConfigSettingContractComputeV0.
+func ConfigSettingIdName(configSettingId xdr.ConfigSettingId) string {
+	return camelToSnakeCase(strings.TrimPrefix(configSettingId.String(), "ConfigSettingIdConfigSetting"))
+}
+
+// ConfigSettingToJSON encodes the value of a config setting as JSON. Settings holding a single number are
+// encoded as that number, other settings as objects keyed by the snake case name of the XDR fields.
+func ConfigSettingToJSON(configSetting xdr.ConfigSettingEntry) (string, error) {
+	armName, ok := configSetting.ArmForSwitch(int32(configSetting.ConfigSettingId))
+	if !ok {
+		return "", fmt.Errorf("unknown config setting id %d", configSetting.ConfigSettingId)
+	}
+	arm := reflect.ValueOf(configSetting).FieldByName(armName)
+	if arm.Kind() == reflect.Ptr {
+		if arm.IsNil() {
+			return "", fmt.Errorf("config setting %s has no value", configSetting.ConfigSettingId)
+		}
+		arm = arm.Elem()
+	}
+
+	encoded, err := json.Marshal(arm.Interface())
+	if err != nil {
+		return "", fmt.Errorf("could not encode config setting %s as json: %w", configSetting.ConfigSettingId, err)
+	}
+	// Decode numbers as json.Number to keep 64 bit values exact
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("could not decode config setting %s: %w", configSetting.ConfigSettingId, err)
+	}
+	encoded, err = json.Marshal(snakeCaseKeys(value))
+	if err != nil {
+		return "", fmt.Errorf("could not encode config setting %s as json: %w", configSetting.ConfigSettingId, err)
+	}
+	return string(encoded), nil
+}
+
+func snakeCaseKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			converted[camelToSnakeCase(key)] = snakeCaseKeys(val)
+		}
+		return converted
+	case []interface{}:
+		for index, val := range v {
+			v[index] = snakeCaseKeys(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// camelToSnakeCase converts Go field names to snake case, e.g. TxMaxInstructions to tx_max_instructions
+// and ContractComputeV0 to contract_compute_v0.
+func camelToSnakeCase(name string) string {
+	runes := []rune(name)
+	var snake strings.Builder
+	for index, r := range runes {
+		if unicode.IsUpper(r) {
+			if index > 0 {
+				previous := runes[index-1]
+				nextIsLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+				if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+					snake.WriteByte('_')
+				}
+			}
+			snake.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		snake.WriteRune(r)
+	}
+	return snake.String()
+}
@@ -0,0 +1,48 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSettingToJSON(t *testing.T) {
+	maxSize := xdr.Uint32(65536)
+	tests := []struct {
+		name  string
+		input xdr.ConfigSettingEntry
+		want  string
+	}{
+		{
+			"single number",
+			xdr.ConfigSettingEntry{ConfigSettingId: xdr.ConfigSettingIdConfigSettingContractMaxSizeBytes, ContractMaxSizeBytes: &maxSize},
+			`65536`,
+		},
+		{
+			"struct",
+			xdr.ConfigSettingEntry{
+				ConfigSettingId: xdr.ConfigSettingIdConfigSettingContractComputeV0,
+				ContractCompute: &xdr.ConfigSettingContractComputeV0{
+					LedgerMaxInstructions:           9223372036854775807,
+					TxMaxInstructions:               100000000,
+					FeeRatePerInstructionsIncrement: 25,
+					TxMemoryLimit:                   41943040,
+				},
+			},
+			`{"fee_rate_per_instructions_increment":25,"ledger_max_instructions":9223372036854775807,"tx_max_instructions":100000000,"tx_memory_limit":41943040}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConfigSettingToJSON(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Equal(t, "contract_compute_v0", ConfigSettingIdName(xdr.ConfigSettingIdConfigSettingContractComputeV0))
+	assert.Equal(t, "contract_cost_params_cpu_instructions", ConfigSettingIdName(xdr.ConfigSettingIdConfigSettingContractCostParamsCpuInstructions))
+}
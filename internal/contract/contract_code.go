@@ -29,6 +29,8 @@ type ContractCodeOutput struct {
 	NImports          uint32 `json:"n_imports"`
 	NExports          uint32 `json:"n_exports"`
 	NDataSegmentBytes uint32 `json:"n_data_segment_bytes"`
+	// Evicted is set when the entry was removed by an eviction, its cost inputs are then empty
+	Evicted bool `json:"evicted"`
 }
 
 // TransformContractCode converts a contract code ledger change entry into a form suitable for BigQuery
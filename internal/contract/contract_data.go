@@ -31,7 +31,27 @@ type ContractDataOutput struct {
 	KeyDecoded                map[string]string `json:"key_decoded"`
 	Val                       map[string]string `json:"val"`
 	ValDecoded                map[string]string `json:"val_decoded"`
+	KeyJSON                   string            `json:"key_json"`
+	KeySymbol                 string            `json:"key_symbol"`
+	KeyArgs                   []interface{}     `json:"key_args"`
+	KeyArgTypes               []string          `json:"key_arg_types"`
+	ValJSON                   string            `json:"val_json"`
 	ContractDataXDR           string            `json:"contract_data_xdr"`
+	// JSONError is set when the key or the value could not be decoded, KeyJSON, ValJSON and the key
+	// decomposition are then left empty for the parts that failed
+	JSONError string `json:"json_error"`
+	// EntrySizeBytes is the size of the XDR encoded ledger entry, which rent is charged on
+	EntrySizeBytes uint32 `json:"entry_size_bytes"`
+	// KeyAddresses and ValAddresses are the addresses found anywhere inside the key and the value
+	KeyAddresses []string `json:"key_addresses"`
+	ValAddresses []string `json:"val_addresses"`
+	// TransactionHash, ApplicationOrder and OperationIndex identify the change that wrote this version,
+	// they are empty for changes made by the ledger itself, e.g. evictions
+	TransactionHash  string  `json:"transaction_hash"`
+	ApplicationOrder uint32  `json:"application_order"`
+	OperationIndex   *uint32 `json:"operation_index"`
+	// Evicted is set when the entry was removed by an eviction, its value is then empty
+	Evicted bool `json:"evicted"`
 }
 
 var (
@@ -130,10 +150,30 @@ func (t *TransformContractDataStruct) TransformContractData(ledgerChange ingest.
 	outputKey, outputKeyDecoded := SerializeScVal(contractData.Key)
 	outputVal, outputValDecoded := SerializeScVal(contractData.Val)
 
+	// Values the JSON encoding does not support do not prevent the entry from being indexed
+	var jsonErrors []string
+	outputKeyJSON, err := ScValToJSON(contractData.Key)
+	if err != nil {
+		jsonErrors = append(jsonErrors, fmt.Sprintf("could not encode contract data key: %v", err))
+	}
+	outputValJSON, err := ScValToJSON(contractData.Val)
+	if err != nil {
+		jsonErrors = append(jsonErrors, fmt.Sprintf("could not encode contract data val: %v", err))
+	}
+
+	outputDecomposedKey, err := DecomposeContractDataKey(contractData.Key)
+	if err != nil {
+		jsonErrors = append(jsonErrors, fmt.Sprintf("could not decompose contract data key: %v", err))
+	}
+
 	outputContractDataXDR, err := xdr.MarshalBase64(contractData)
 	if err != nil {
 		return ContractDataOutput{}, err, false
 	}
+	ledgerEntryBytes, err := ledgerEntry.MarshalBinary()
+	if err != nil {
+		return ContractDataOutput{}, fmt.Errorf("could not encode contract data ledger entry: %w", err), false
+	}
 
 	transformedData := ContractDataOutput{
 		ContractId:                outputContractDataContractId,
@@ -154,7 +194,16 @@ func (t *TransformContractDataStruct) TransformContractData(ledgerChange ingest.
 		KeyDecoded:                outputKeyDecoded,
 		Val:                       outputVal,
 		ValDecoded:                outputValDecoded,
+		KeyJSON:                   outputKeyJSON,
+		KeySymbol:                 outputDecomposedKey.Symbol,
+		KeyArgs:                   outputDecomposedKey.Args,
+		KeyArgTypes:               outputDecomposedKey.ArgTypes,
+		ValJSON:                   outputValJSON,
+		JSONError:                 strings.Join(jsonErrors, "; "),
 		ContractDataXDR:           outputContractDataXDR,
+		EntrySizeBytes:            uint32(len(ledgerEntryBytes)),
+		KeyAddresses:              ScValAddresses(contractData.Key),
+		ValAddresses:              ScValAddresses(contractData.Val),
 	}
 	return transformedData, nil, true
 }
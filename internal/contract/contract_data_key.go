@@ -0,0 +1,91 @@
+package contract
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ContractDataKey is the decomposition of a contract data key built from a #[contracttype] enum variant,
+// which is stored as a Vec holding the variant symbol followed by its arguments, e.g. Balance(address).
+type ContractDataKey struct {
+	// Symbol is the enum variant, empty if the key is not an enum variant
+	Symbol string
+	// Args are the normalized variant arguments: addresses are strkeys, integers are numbers (*big.Int for
+	// 64 bit and wider), bytes are hex and symbols and strings are strings. Other values are encoded as in ScValToJSON.
+	Args []interface{}
+	// ArgTypes are the ScVal types of Args, e.g. address, u32 or i128
+	ArgTypes []string
+}
+
+// DecomposeContractDataKey splits a contract data key into its enum variant symbol and arguments.
+// Keys that are not enum variants, e.g. plain symbols or the contract instance key, return an empty ContractDataKey.
+func DecomposeContractDataKey(key xdr.ScVal) (ContractDataKey, error) {
+	vec, ok := key.GetVec()
+	if !ok || vec == nil || len(*vec) == 0 {
+		return ContractDataKey{}, nil
+	}
+	symbol, ok := (*vec)[0].GetSym()
+	if !ok {
+		return ContractDataKey{}, nil
+	}
+
+	contractDataKey := ContractDataKey{Symbol: string(symbol)}
+	for index, arg := range (*vec)[1:] {
+		value, err := normalizeKeyArg(arg)
+		if err != nil {
+			return ContractDataKey{}, fmt.Errorf("could not normalize argument %d of key %s: %w", index, symbol, err)
+		}
+		contractDataKey.Args = append(contractDataKey.Args, value)
+		contractDataKey.ArgTypes = append(contractDataKey.ArgTypes, ScValTypeName(arg.Type))
+	}
+	return contractDataKey, nil
+}
+
+// ScValTypeName returns the short name of an ScVal type, e.g. address for ScValTypeScvAddress.
+func ScValTypeName(scValType xdr.ScValType) string {
+	return strings.ToLower(strings.TrimPrefix(scValType.String(), "ScValTypeScv"))
+}
+
+func normalizeKeyArg(arg xdr.ScVal) (interface{}, error) {
+	switch arg.Type {
+	case xdr.ScValTypeScvAddress:
+		return arg.MustAddress().String()
+	case xdr.ScValTypeScvBool:
+		return arg.MustB(), nil
+	case xdr.ScValTypeScvU32:
+		return uint32(arg.MustU32()), nil
+	case xdr.ScValTypeScvI32:
+		return int32(arg.MustI32()), nil
+	case xdr.ScValTypeScvU64:
+		return partsToBigInt(false, uint64(arg.MustU64())), nil
+	case xdr.ScValTypeScvI64:
+		return partsToBigInt(true, uint64(arg.MustI64())), nil
+	case xdr.ScValTypeScvTimepoint:
+		return partsToBigInt(false, uint64(arg.MustTimepoint())), nil
+	case xdr.ScValTypeScvDuration:
+		return partsToBigInt(false, uint64(arg.MustDuration())), nil
+	case xdr.ScValTypeScvU128:
+		parts := arg.MustU128()
+		return partsToBigInt(false, uint64(parts.Hi), uint64(parts.Lo)), nil
+	case xdr.ScValTypeScvI128:
+		parts := arg.MustI128()
+		return partsToBigInt(true, uint64(parts.Hi), uint64(parts.Lo)), nil
+	case xdr.ScValTypeScvU256:
+		parts := arg.MustU256()
+		return partsToBigInt(false, uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo)), nil
+	case xdr.ScValTypeScvI256:
+		parts := arg.MustI256()
+		return partsToBigInt(true, uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo)), nil
+	case xdr.ScValTypeScvBytes:
+		return hex.EncodeToString(arg.MustBytes()), nil
+	case xdr.ScValTypeScvSymbol:
+		return string(arg.MustSym()), nil
+	case xdr.ScValTypeScvString:
+		return escapeJSONBString(string(arg.MustStr())), nil
+	default:
+		return scValToJSONValue(arg)
+	}
+}
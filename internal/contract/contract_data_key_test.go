@@ -0,0 +1,78 @@
+package contract
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecomposeContractDataKey(t *testing.T) {
+	balanceSym := xdr.ScSymbol("Balance")
+	adminSym := xdr.ScSymbol("Admin")
+	contractID := xdr.ContractId{}
+	address := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID}
+	amount := xdr.Int128Parts{Hi: 0, Lo: 1000}
+	u32 := xdr.Uint32(3)
+	bytes := xdr.ScBytes{0x01, 0x02}
+
+	balanceKey := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &balanceSym},
+		{Type: xdr.ScValTypeScvAddress, Address: &address},
+		{Type: xdr.ScValTypeScvI128, I128: &amount},
+		{Type: xdr.ScValTypeScvU32, U32: &u32},
+		{Type: xdr.ScValTypeScvBytes, Bytes: &bytes},
+	}
+	unitKey := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &adminSym},
+	}
+	notEnumKey := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvU32, U32: &u32},
+		{Type: xdr.ScValTypeScvSymbol, Sym: &adminSym},
+	}
+
+	tests := []struct {
+		name  string
+		input xdr.ScVal
+		want  ContractDataKey
+	}{
+		{
+			"enum variant with arguments",
+			xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &balanceKey},
+			ContractDataKey{
+				Symbol:   "Balance",
+				Args:     []interface{}{"CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4", big.NewInt(1000), uint32(3), "0102"},
+				ArgTypes: []string{"address", "i128", "u32", "bytes"},
+			},
+		},
+		{
+			"unit enum variant",
+			xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &unitKey},
+			ContractDataKey{Symbol: "Admin"},
+		},
+		{
+			"vec not starting with a symbol",
+			xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &notEnumKey},
+			ContractDataKey{},
+		},
+		{
+			"plain symbol",
+			xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &adminSym},
+			ContractDataKey{},
+		},
+		{
+			"contract instance",
+			xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+			ContractDataKey{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecomposeContractDataKey(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
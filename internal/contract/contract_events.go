@@ -15,6 +15,7 @@ import (
 type ContractEventOutput struct {
 	TransactionHash          string                         `json:"transaction_hash"`
 	TransactionID            int64                          `json:"transaction_id"`
+	EventIndex               uint32                         `json:"event_index"`
 	Successful               bool                           `json:"successful"`
 	LedgerSequence           uint32                         `json:"ledger_sequence"`
 	ClosedAt                 time.Time                      `json:"closed_at"`
@@ -46,15 +47,14 @@ func TransformContractEvent(transaction ingest.LedgerTransaction, lhe xdr.Ledger
 		return []ContractEventOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", outputLedgerSequence, transactionIndex, outputTransactionID, err)
 	}
 
-	// GetDiagnosticEvents will return all contract events and diagnostic events emitted
-	contractEvents, err := transaction.GetDiagnosticEvents()
+	contractEvents, err := getTransactionEvents(transaction)
 	if err != nil {
 		return []ContractEventOutput{}, err
 	}
 
 	var transformedContractEvents []ContractEventOutput
 
-	for _, contractEvent := range contractEvents {
+	for eventIndex, contractEvent := range contractEvents {
 		var outputContractId string
 		outputTopicsJson := make(map[string][]map[string]string, 1)
 		outputTopicsDecodedJson := make(map[string][]map[string]string, 1)
@@ -91,6 +91,7 @@ func TransformContractEvent(transaction ingest.LedgerTransaction, lhe xdr.Ledger
 		transformedDiagnosticEvent := ContractEventOutput{
 			TransactionHash:          outputTransactionHash,
 			TransactionID:            outputTransactionID,
+			EventIndex:               uint32(eventIndex),
 			Successful:               outputSuccessful,
 			LedgerSequence:           outputLedgerSequence,
 			ClosedAt:                 outputCloseTime,
@@ -111,6 +112,59 @@ func TransformContractEvent(transaction ingest.LedgerTransaction, lhe xdr.Ledger
 	return transformedContractEvents, nil
 }
 
+// getTransactionEvents returns the events emitted by a transaction in a stable order, which is used as the event index.
+// Contract events come first, followed by the diagnostic events, so that the index of a contract event does not
+// depend on whether the node that produced the meta had diagnostic events enabled.
+// From TransactionMetaV4 onwards, contract events are stored per operation and transaction level events
+// (e.g. fees) separately, while the diagnostic events only hold diagnostic events.
+// Transactions with older meta versions predate Soroban and have no events.
+func getTransactionEvents(transaction ingest.LedgerTransaction) ([]xdr.DiagnosticEvent, error) {
+	switch transaction.UnsafeMeta.V {
+	case 3:
+		return getTransactionEventsV3(transaction), nil
+	case 4:
+		return getTransactionEventsV4(transaction), nil
+	default:
+		return nil, nil
+	}
+}
+
+// getTransactionEventsV3 returns the contract events of the Soroban meta followed by the diagnostic events. With
+// diagnostic events enabled, the latter also repeat the contract events of successful calls, which are skipped.
+func getTransactionEventsV3(transaction ingest.LedgerTransaction) []xdr.DiagnosticEvent {
+	sorobanMeta := transaction.UnsafeMeta.MustV3().SorobanMeta
+	if sorobanMeta == nil {
+		return nil
+	}
+	var events []xdr.DiagnosticEvent
+	for _, event := range sorobanMeta.Events {
+		events = append(events, xdr.DiagnosticEvent{InSuccessfulContractCall: true, Event: event})
+	}
+	for _, diagnosticEvent := range sorobanMeta.DiagnosticEvents {
+		if diagnosticEvent.InSuccessfulContractCall && diagnosticEvent.Event.Type != xdr.ContractEventTypeDiagnostic {
+			continue
+		}
+		events = append(events, diagnosticEvent)
+	}
+	return events
+}
+
+func getTransactionEventsV4(transaction ingest.LedgerTransaction) []xdr.DiagnosticEvent {
+	metaV4 := transaction.UnsafeMeta.MustV4()
+	var events []xdr.DiagnosticEvent
+	for _, transactionEvent := range metaV4.Events {
+		events = append(events, xdr.DiagnosticEvent{InSuccessfulContractCall: true, Event: transactionEvent.Event})
+	}
+	successful := transaction.Result.Successful()
+	for _, operation := range metaV4.Operations {
+		for _, event := range operation.Events {
+			events = append(events, xdr.DiagnosticEvent{InSuccessfulContractCall: successful, Event: event})
+		}
+	}
+	events = append(events, metaV4.DiagnosticEvents...)
+	return events
+}
+
 // TODO this should be a stellar/go/xdr function
 func getEventTopics(eventBody xdr.ContractEventBody) []xdr.ScVal {
 	switch eventBody.V {
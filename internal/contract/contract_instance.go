@@ -0,0 +1,223 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ContractInstanceOutput is the executable of a contract instance entry after a change, along with
+// the executable it replaced when the change is an upgrade
+type ContractInstanceOutput struct {
+	ContractId     string    `json:"contract_id"`
+	ExecutableType string    `json:"executable_type"`
+	WasmHash       string    `json:"wasm_hash"`
+	Asset          string    `json:"asset"`
+	Created        bool      `json:"created"`
+	Upgraded       bool      `json:"upgraded"`
+	LedgerSequence uint32    `json:"ledger_sequence"`
+	ClosedAt       time.Time `json:"closed_at"`
+	// PreviousExecutableType and PreviousWasmHash are only set for upgrades
+	PreviousExecutableType string `json:"previous_executable_type"`
+	PreviousWasmHash       string `json:"previous_wasm_hash"`
+	// Deployer and Salt are only set for created instances deployed from an address, when the transaction creating
+	// them holds the preimage of the contract id. They are empty otherwise, e.g. for contracts deployed by a factory
+	// contract from its own address, and are written as NULL.
+	Deployer string `json:"deployer"`
+	Salt     string `json:"salt"`
+	// TransactionHash, ApplicationOrder and OperationIndex identify the change, see ContractDataOutput
+	TransactionHash  string  `json:"transaction_hash"`
+	ApplicationOrder uint32  `json:"application_order"`
+	OperationIndex   *uint32 `json:"operation_index"`
+}
+
+// TransformContractInstance converts a change of a contract instance entry into a ContractInstanceOutput.
+// It returns false for any other contract data, for removed instances and for updates that kept the executable,
+// e.g. changes of the instance storage.
+func TransformContractInstance(ledgerChange ingest.Change, passphrase string, header xdr.LedgerHeaderHistoryEntry) (ContractInstanceOutput, bool, error) {
+	if ledgerChange.Post == nil {
+		return ContractInstanceOutput{}, false, nil
+	}
+	executable, ok := contractInstanceExecutable(*ledgerChange.Post)
+	if !ok {
+		return ContractInstanceOutput{}, false, nil
+	}
+
+	created := ledgerChange.ChangeType == xdr.LedgerEntryChangeTypeLedgerEntryCreated
+	var previousExecutable *xdr.ContractExecutable
+	if ledgerChange.Pre != nil {
+		if pre, ok := contractInstanceExecutable(*ledgerChange.Pre); ok && !sameExecutable(pre, executable) {
+			previousExecutable = &pre
+		}
+	}
+	if !created && previousExecutable == nil {
+		return ContractInstanceOutput{}, false, nil
+	}
+
+	closedAt, err := TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return ContractInstanceOutput{}, false, err
+	}
+
+	output, _, err := ContractInstanceFromEntry(*ledgerChange.Post, passphrase)
+	if err != nil {
+		return ContractInstanceOutput{}, false, err
+	}
+	output.Created = created
+	output.Upgraded = previousExecutable != nil
+	output.LedgerSequence = uint32(header.Header.LedgerSeq)
+	output.ClosedAt = closedAt
+	if previousExecutable != nil {
+		output.PreviousExecutableType = ContractExecutableTypeName(previousExecutable.Type)
+		if wasmHash, ok := previousExecutable.GetWasmHash(); ok {
+			output.PreviousWasmHash = wasmHash.HexString()
+		}
+	}
+	return output, true, nil
+}
+
+// ContractInstanceFromEntry returns the contract id and executable of a contract instance entry, or false for any
+// other ledger entry. The fields describing the change that wrote the entry are left unset.
+func ContractInstanceFromEntry(ledgerEntry xdr.LedgerEntry, passphrase string) (ContractInstanceOutput, bool, error) {
+	executable, ok := contractInstanceExecutable(ledgerEntry)
+	if !ok {
+		return ContractInstanceOutput{}, false, nil
+	}
+	contractId, err := ledgerEntry.Data.MustContractData().Contract.String()
+	if err != nil {
+		return ContractInstanceOutput{}, false, fmt.Errorf("could not encode contract id of instance: %w", err)
+	}
+
+	output := ContractInstanceOutput{
+		ContractId:     contractId,
+		ExecutableType: ContractExecutableTypeName(executable.Type),
+	}
+	if wasmHash, ok := executable.GetWasmHash(); ok {
+		output.WasmHash = wasmHash.HexString()
+	}
+	if executable.Type == xdr.ContractExecutableTypeContractExecutableStellarAsset {
+		if asset := AssetFromContractData(ledgerEntry, passphrase); asset != nil {
+			output.Asset = asset.StringCanonical()
+		}
+	}
+	return output, true, nil
+}
+
+// ContractExecutableTypeName maps a ContractExecutableType to wasm or stellar_asset.
+func ContractExecutableTypeName(executableType xdr.ContractExecutableType) string {
+	switch executableType {
+	case xdr.ContractExecutableTypeContractExecutableWasm:
+		return "wasm"
+	case xdr.ContractExecutableTypeContractExecutableStellarAsset:
+		return "stellar_asset"
+	default:
+		return executableType.String()
+	}
+}
+
+func sameExecutable(a xdr.ContractExecutable, b xdr.ContractExecutable) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	aWasmHash, _ := a.GetWasmHash()
+	bWasmHash, _ := b.GetWasmHash()
+	return aWasmHash == bWasmHash
+}
+
+func contractInstanceExecutable(ledgerEntry xdr.LedgerEntry) (xdr.ContractExecutable, bool) {
+	contractData, ok := ledgerEntry.Data.GetContractData()
+	if !ok || contractData.Key.Type != xdr.ScValTypeScvLedgerKeyContractInstance {
+		return xdr.ContractExecutable{}, false
+	}
+	instance, ok := contractData.Val.GetInstance()
+	if !ok {
+		return xdr.ContractExecutable{}, false
+	}
+	return instance.Executable, true
+}
+
+// ContractIdPreimages returns the preimages of the contracts a transaction may create, keyed by contract id.
+// They come from the CreateContract host functions of its operations and of their auth entries, which
+// covers contracts deployed by a factory contract on behalf of an address. A factory deploying from its own
+// address needs no auth entry, so contracts it creates have no preimage.
+func ContractIdPreimages(transaction ingest.LedgerTransaction, passphrase string) (map[string]xdr.ContractIdPreimage, error) {
+	var preimages []xdr.ContractIdPreimage
+	for _, operation := range transaction.Envelope.Operations() {
+		invokeHostFunction, ok := operation.Body.GetInvokeHostFunctionOp()
+		if !ok {
+			continue
+		}
+		preimages = append(preimages, hostFunctionPreimages(invokeHostFunction.HostFunction)...)
+		for _, authEntry := range invokeHostFunction.Auth {
+			preimages = append(preimages, authorizedInvocationPreimages(authEntry.RootInvocation)...)
+		}
+	}
+
+	networkId := xdr.Hash(network.ID(passphrase))
+	preimagesById := map[string]xdr.ContractIdPreimage{}
+	for _, preimage := range preimages {
+		hashIdPreimage := xdr.HashIdPreimage{
+			Type: xdr.EnvelopeTypeEnvelopeTypeContractId,
+			ContractId: &xdr.HashIdPreimageContractId{
+				NetworkId:          networkId,
+				ContractIdPreimage: preimage,
+			},
+		}
+		preimageBytes, err := hashIdPreimage.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("could not encode contract id preimage: %w", err)
+		}
+		contractIdHash := sha256.Sum256(preimageBytes)
+		contractId, err := strkey.Encode(strkey.VersionByteContract, contractIdHash[:])
+		if err != nil {
+			return nil, fmt.Errorf("could not encode contract id: %w", err)
+		}
+		preimagesById[contractId] = preimage
+	}
+	return preimagesById, nil
+}
+
+// ContractDeployer returns the address and hex encoded salt a contract was deployed with,
+// or empty strings for contracts deployed from an asset.
+func ContractDeployer(preimage xdr.ContractIdPreimage) (string, string, error) {
+	fromAddress, ok := preimage.GetFromAddress()
+	if !ok {
+		return "", "", nil
+	}
+	deployer, err := fromAddress.Address.String()
+	if err != nil {
+		return "", "", fmt.Errorf("could not encode deployer: %w", err)
+	}
+	return deployer, hex.EncodeToString(fromAddress.Salt[:]), nil
+}
+
+func hostFunctionPreimages(hostFunction xdr.HostFunction) []xdr.ContractIdPreimage {
+	switch hostFunction.Type {
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+		return []xdr.ContractIdPreimage{hostFunction.MustCreateContract().ContractIdPreimage}
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+		return []xdr.ContractIdPreimage{hostFunction.MustCreateContractV2().ContractIdPreimage}
+	default:
+		return nil
+	}
+}
+
+func authorizedInvocationPreimages(invocation xdr.SorobanAuthorizedInvocation) []xdr.ContractIdPreimage {
+	var preimages []xdr.ContractIdPreimage
+	switch invocation.Function.Type {
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractHostFn:
+		preimages = append(preimages, invocation.Function.MustCreateContractHostFn().ContractIdPreimage)
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn:
+		preimages = append(preimages, invocation.Function.MustCreateContractV2HostFn().ContractIdPreimage)
+	}
+	for _, subInvocation := range invocation.SubInvocations {
+		preimages = append(preimages, authorizedInvocationPreimages(subInvocation)...)
+	}
+	return preimages
+}
@@ -0,0 +1,213 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ContractInvocationOutput is a representation of an InvokeHostFunction operation along with the Soroban
+// resources declared by its transaction and the fees charged for them
+type ContractInvocationOutput struct {
+	TransactionHash    string              `json:"transaction_hash"`
+	TransactionID      int64               `json:"transaction_id"`
+	OperationID        int64               `json:"operation_id"`
+	OperationIndex     uint32              `json:"operation_index"`
+	LedgerSequence     uint32              `json:"ledger_sequence"`
+	ClosedAt           time.Time           `json:"closed_at"`
+	SourceAccount      string              `json:"source_account"`
+	Successful         bool                `json:"successful"`
+	ResultCode         string              `json:"result_code"`
+	HostFunctionType   string              `json:"host_function_type"`
+	ContractId         string              `json:"contract_id"`
+	FunctionName       string              `json:"function_name"`
+	Args               []map[string]string `json:"args"`
+	ArgsDecoded        []map[string]string `json:"args_decoded"`
+	Auth               []map[string]string `json:"auth"`
+	ReturnValue        map[string]string   `json:"return_value"`
+	ReturnValueDecoded map[string]string   `json:"return_value_decoded"`
+	// Resources declared by the transaction, the resources it actually used are not part of the meta
+	DeclaredResourceFee   int64  `json:"declared_resource_fee"`
+	DeclaredInstructions  uint32 `json:"declared_instructions"`
+	DeclaredDiskReadBytes uint32 `json:"declared_disk_read_bytes"`
+	DeclaredWriteBytes    uint32 `json:"declared_write_bytes"`
+	// Fees charged, from the transaction result and meta
+	FeeCharged                      int64 `json:"fee_charged"`
+	NonRefundableResourceFeeCharged int64 `json:"non_refundable_resource_fee_charged"`
+	RefundableResourceFeeCharged    int64 `json:"refundable_resource_fee_charged"`
+	RentFeeCharged                  int64 `json:"rent_fee_charged"`
+}
+
+// TransformContractInvocation converts the InvokeHostFunction operations of a transaction into ContractInvocationOutputs.
+// Transactions without InvokeHostFunction operations return no output.
+func TransformContractInvocation(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]ContractInvocationOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionIndex := int32(transaction.Index)
+	transactionID := toid.New(int32(ledgerSequence), transactionIndex, 0).ToInt64()
+
+	closedAt, err := TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transactionIndex, transactionID, err)
+	}
+
+	var invocations []ContractInvocationOutput
+	for operationIndex, operation := range transaction.Envelope.Operations() {
+		invokeHostFunction, ok := operation.Body.GetInvokeHostFunctionOp()
+		if !ok {
+			continue
+		}
+
+		sourceAccount := transaction.Envelope.SourceAccount()
+		if operation.SourceAccount != nil {
+			sourceAccount = *operation.SourceAccount
+		}
+
+		invocation := ContractInvocationOutput{
+			TransactionHash:  HashToHexString(transaction.Result.TransactionHash),
+			TransactionID:    transactionID,
+			OperationID:      toid.New(int32(ledgerSequence), transactionIndex, int32(operationIndex+1)).ToInt64(),
+			OperationIndex:   uint32(operationIndex),
+			LedgerSequence:   ledgerSequence,
+			ClosedAt:         closedAt,
+			SourceAccount:    sourceAccount.ToAccountId().Address(),
+			Successful:       transaction.Result.Successful(),
+			ResultCode:       invokeHostFunctionResultCode(transaction, operationIndex),
+			HostFunctionType: HostFunctionTypeName(invokeHostFunction.HostFunction.Type),
+			FeeCharged:       int64(transaction.Result.Result.FeeCharged),
+		}
+
+		var args []xdr.ScVal
+		switch invokeHostFunction.HostFunction.Type {
+		case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+			invokeContract := invokeHostFunction.HostFunction.MustInvokeContract()
+			contractId, err := invokeContract.ContractAddress.String()
+			if err != nil {
+				return nil, fmt.Errorf("could not encode invoked contract of transaction %s: %w", invocation.TransactionHash, err)
+			}
+			invocation.ContractId = contractId
+			invocation.FunctionName = string(invokeContract.FunctionName)
+			args = invokeContract.Args
+		case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+			args = invokeHostFunction.HostFunction.MustCreateContractV2().ConstructorArgs
+		}
+		invocation.Args, invocation.ArgsDecoded = SerializeScValArray(args)
+
+		invocation.Auth, err = serializeAuthEntries(invokeHostFunction.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("could not serialize auth entries of transaction %s: %w", invocation.TransactionHash, err)
+		}
+
+		if sorobanData, ok := getSorobanTransactionData(transaction.Envelope); ok {
+			invocation.DeclaredResourceFee = int64(sorobanData.ResourceFee)
+			invocation.DeclaredInstructions = uint32(sorobanData.Resources.Instructions)
+			invocation.DeclaredDiskReadBytes = uint32(sorobanData.Resources.DiskReadBytes)
+			invocation.DeclaredWriteBytes = uint32(sorobanData.Resources.WriteBytes)
+		}
+
+		returnValue, metaExt := getSorobanMeta(transaction.UnsafeMeta)
+		if returnValue != nil {
+			invocation.ReturnValue, invocation.ReturnValueDecoded = SerializeScVal(*returnValue)
+		}
+		if extV1, ok := metaExt.GetV1(); ok {
+			invocation.NonRefundableResourceFeeCharged = int64(extV1.TotalNonRefundableResourceFeeCharged)
+			invocation.RefundableResourceFeeCharged = int64(extV1.TotalRefundableResourceFeeCharged)
+			invocation.RentFeeCharged = int64(extV1.RentFeeCharged)
+		}
+
+		invocations = append(invocations, invocation)
+	}
+	return invocations, nil
+}
+
+// HostFunctionTypeName maps a HostFunctionType to invoke_contract, create_contract, upload_contract_wasm or create_contract_v2.
+func HostFunctionTypeName(hostFunctionType xdr.HostFunctionType) string {
+	switch hostFunctionType {
+	case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+		return "invoke_contract"
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+		return "create_contract"
+	case xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm:
+		return "upload_contract_wasm"
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+		return "create_contract_v2"
+	default:
+		return strings.ToLower(hostFunctionType.String())
+	}
+}
+
+// invokeHostFunctionResultCode returns the result code of the operation, or the code of the transaction
+// when it failed before its operations were applied.
+func invokeHostFunctionResultCode(transaction ingest.LedgerTransaction, operationIndex int) string {
+	operationResults, ok := transaction.Result.OperationResults()
+	if !ok || operationIndex >= len(operationResults) {
+		return transaction.Result.Result.Result.Code.String()
+	}
+	operationResult := operationResults[operationIndex]
+	result, ok := operationResult.GetTr()
+	if !ok {
+		return operationResult.Code.String()
+	}
+	invokeHostFunctionResult, ok := result.GetInvokeHostFunctionResult()
+	if !ok {
+		return result.Type.String()
+	}
+	return invokeHostFunctionResult.Code.String()
+}
+
+// serializeAuthEntries returns the credentials type, the authorizing address and the XDR of every auth entry.
+// Entries authorized by the source account have no address.
+func serializeAuthEntries(authEntries []xdr.SorobanAuthorizationEntry) ([]map[string]string, error) {
+	serialized := make([]map[string]string, 0, len(authEntries))
+	for _, authEntry := range authEntries {
+		entryXDR, err := xdr.MarshalBase64(authEntry)
+		if err != nil {
+			return nil, err
+		}
+		entry := map[string]string{"credentials": "source_account", "address": "", "xdr": entryXDR}
+		if credentials, ok := authEntry.Credentials.GetAddress(); ok {
+			address, err := credentials.Address.String()
+			if err != nil {
+				return nil, err
+			}
+			entry["credentials"] = "address"
+			entry["address"] = address
+		}
+		serialized = append(serialized, entry)
+	}
+	return serialized, nil
+}
+
+// getSorobanTransactionData returns the Soroban resources declared by a transaction, or of the inner transaction of a fee bump.
+func getSorobanTransactionData(envelope xdr.TransactionEnvelope) (xdr.SorobanTransactionData, bool) {
+	var ext xdr.TransactionExt
+	switch envelope.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		ext = envelope.MustV1().Tx.Ext
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		ext = envelope.MustFeeBump().Tx.InnerTx.MustV1().Tx.Ext
+	default:
+		return xdr.SorobanTransactionData{}, false
+	}
+	return ext.GetSorobanData()
+}
+
+// getSorobanMeta returns the return value and the fees charged of a Soroban transaction, from TransactionMetaV3 onwards.
+func getSorobanMeta(meta xdr.TransactionMeta) (*xdr.ScVal, xdr.SorobanTransactionMetaExt) {
+	switch meta.V {
+	case 3:
+		sorobanMeta := meta.MustV3().SorobanMeta
+		if sorobanMeta != nil {
+			return &sorobanMeta.ReturnValue, sorobanMeta.Ext
+		}
+	case 4:
+		sorobanMeta := meta.MustV4().SorobanMeta
+		if sorobanMeta != nil {
+			return sorobanMeta.ReturnValue, sorobanMeta.Ext
+		}
+	}
+	return nil, xdr.SorobanTransactionMetaExt{}
+}
@@ -0,0 +1,273 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	contractSpecSection    = "contractspecv0"
+	contractMetaSection    = "contractmetav0"
+	contractEnvMetaSection = "contractenvmetav0"
+	// contractSdkVersionMetaKey is the meta key the Rust SDK records its version under
+	contractSdkVersionMetaKey = "rssdkver"
+)
+
+// ContractSpecOutput is the interface and metadata embedded in the custom sections of an uploaded Wasm
+type ContractSpecOutput struct {
+	ContractCodeHash string                    `json:"contract_code_hash"`
+	LedgerSequence   uint32                    `json:"ledger_sequence"`
+	ClosedAt         time.Time                 `json:"closed_at"`
+	Entries          []ContractSpecEntryOutput `json:"entries"`
+	Meta             map[string]string         `json:"meta"`
+	SdkVersion       string                    `json:"sdk_version"`
+	// EnvProtocolVersion and EnvPreReleaseVersion are the interface version of the host the Wasm was built for
+	EnvProtocolVersion   uint32 `json:"env_protocol_version"`
+	EnvPreReleaseVersion uint32 `json:"env_pre_release_version"`
+	// ParseError is set when a custom section could not be decoded, the sections decoded before it are kept
+	ParseError string `json:"parse_error"`
+}
+
+// ContractSpecEntryOutput is a function, user defined type or event of a contract spec
+type ContractSpecEntryOutput struct {
+	EntryIndex uint32 `json:"entry_index"`
+	// EntryType is one of function, struct, union, enum, error_enum or event
+	EntryType string `json:"entry_type"`
+	Name      string `json:"name"`
+	Lib       string `json:"lib"`
+	Doc       string `json:"doc"`
+	// Definition is the JSON encoded signature of a function, or the fields, cases or params of a type or event,
+	// with types named as in the Rust SDK, e.g. Option<Vec<Address>>
+	Definition string `json:"definition"`
+	EntryXDR   string `json:"entry_xdr"`
+}
+
+// TransformContractSpec reads the contract spec and metadata of a contract code change.
+// It returns false for removed contract code, as the Wasm is no longer available.
+func TransformContractSpec(ledgerChange ingest.Change, header xdr.LedgerHeaderHistoryEntry) (ContractSpecOutput, bool, error) {
+	if ledgerChange.Post == nil {
+		return ContractSpecOutput{}, false, nil
+	}
+	contractCode, ok := ledgerChange.Post.Data.GetContractCode()
+	if !ok {
+		return ContractSpecOutput{}, false, fmt.Errorf("could not extract contract code from ledger entry; actual type is %s", ledgerChange.Post.Data.Type)
+	}
+
+	closedAt, err := TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return ContractSpecOutput{}, false, err
+	}
+
+	output := ContractSpecOutput{
+		ContractCodeHash: contractCode.Hash.HexString(),
+		LedgerSequence:   uint32(header.Header.LedgerSeq),
+		ClosedAt:         closedAt,
+		Entries:          []ContractSpecEntryOutput{},
+		Meta:             map[string]string{},
+	}
+	// The Wasm is validated by the network, but its custom sections are arbitrary bytes
+	if err := readContractSpec(contractCode.Code, &output); err != nil {
+		output.ParseError = err.Error()
+	}
+	return output, true, nil
+}
+
+func readContractSpec(code []byte, output *ContractSpecOutput) error {
+	sections, err := WasmCustomSections(code)
+	if err != nil {
+		return err
+	}
+	decoder := xdr.NewBytesDecoder()
+
+	envMeta := sections[contractEnvMetaSection]
+	for offset := 0; offset < len(envMeta); {
+		var entry xdr.ScEnvMetaEntry
+		n, err := decoder.DecodeBytes(&entry, envMeta[offset:])
+		if err != nil {
+			return fmt.Errorf("could not decode %s: %w", contractEnvMetaSection, err)
+		}
+		offset += n
+		if interfaceVersion, ok := entry.GetInterfaceVersion(); ok {
+			output.EnvProtocolVersion = uint32(interfaceVersion.Protocol)
+			output.EnvPreReleaseVersion = uint32(interfaceVersion.PreRelease)
+		}
+	}
+
+	meta := sections[contractMetaSection]
+	for offset := 0; offset < len(meta); {
+		var entry xdr.ScMetaEntry
+		n, err := decoder.DecodeBytes(&entry, meta[offset:])
+		if err != nil {
+			return fmt.Errorf("could not decode %s: %w", contractMetaSection, err)
+		}
+		offset += n
+		if metaV0, ok := entry.GetV0(); ok {
+			output.Meta[escapeJSONBString(metaV0.Key)] = escapeJSONBString(metaV0.Val)
+		}
+	}
+	output.SdkVersion = output.Meta[contractSdkVersionMetaKey]
+
+	spec := sections[contractSpecSection]
+	for offset := 0; offset < len(spec); {
+		var entry xdr.ScSpecEntry
+		n, err := decoder.DecodeBytes(&entry, spec[offset:])
+		if err != nil {
+			return fmt.Errorf("could not decode %s entry %d: %w", contractSpecSection, len(output.Entries), err)
+		}
+		offset += n
+		specEntry, err := transformContractSpecEntry(entry)
+		if err != nil {
+			return err
+		}
+		specEntry.EntryIndex = uint32(len(output.Entries))
+		output.Entries = append(output.Entries, specEntry)
+	}
+	return nil
+}
+
+func transformContractSpecEntry(entry xdr.ScSpecEntry) (ContractSpecEntryOutput, error) {
+	var output ContractSpecEntryOutput
+	var definition interface{}
+	switch entry.Kind {
+	case xdr.ScSpecEntryKindScSpecEntryFunctionV0:
+		function := entry.MustFunctionV0()
+		inputs := []interface{}{}
+		for _, input := range function.Inputs {
+			inputs = append(inputs, map[string]interface{}{"name": escapeJSONBString(input.Name), "type": ScSpecTypeName(input.Type), "doc": escapeJSONBString(input.Doc)})
+		}
+		outputs := []interface{}{}
+		for _, functionOutput := range function.Outputs {
+			outputs = append(outputs, ScSpecTypeName(functionOutput))
+		}
+		output = ContractSpecEntryOutput{EntryType: "function", Name: string(function.Name), Doc: function.Doc}
+		definition = map[string]interface{}{"inputs": inputs, "outputs": outputs}
+	case xdr.ScSpecEntryKindScSpecEntryUdtStructV0:
+		udtStruct := entry.MustUdtStructV0()
+		fields := []interface{}{}
+		for _, field := range udtStruct.Fields {
+			fields = append(fields, map[string]interface{}{"name": escapeJSONBString(field.Name), "type": ScSpecTypeName(field.Type), "doc": escapeJSONBString(field.Doc)})
+		}
+		output = ContractSpecEntryOutput{EntryType: "struct", Name: udtStruct.Name, Lib: udtStruct.Lib, Doc: udtStruct.Doc}
+		definition = map[string]interface{}{"fields": fields}
+	case xdr.ScSpecEntryKindScSpecEntryUdtUnionV0:
+		union := entry.MustUdtUnionV0()
+		cases := []interface{}{}
+		for _, unionCase := range union.Cases {
+			if tupleCase, ok := unionCase.GetTupleCase(); ok {
+				types := []interface{}{}
+				for _, caseType := range tupleCase.Type {
+					types = append(types, ScSpecTypeName(caseType))
+				}
+				cases = append(cases, map[string]interface{}{"name": escapeJSONBString(tupleCase.Name), "types": types, "doc": escapeJSONBString(tupleCase.Doc)})
+			} else if voidCase, ok := unionCase.GetVoidCase(); ok {
+				cases = append(cases, map[string]interface{}{"name": escapeJSONBString(voidCase.Name), "types": []interface{}{}, "doc": escapeJSONBString(voidCase.Doc)})
+			}
+		}
+		output = ContractSpecEntryOutput{EntryType: "union", Name: union.Name, Lib: union.Lib, Doc: union.Doc}
+		definition = map[string]interface{}{"cases": cases}
+	case xdr.ScSpecEntryKindScSpecEntryUdtEnumV0:
+		enum := entry.MustUdtEnumV0()
+		cases := []interface{}{}
+		for _, enumCase := range enum.Cases {
+			cases = append(cases, map[string]interface{}{"name": escapeJSONBString(enumCase.Name), "value": uint32(enumCase.Value), "doc": escapeJSONBString(enumCase.Doc)})
+		}
+		output = ContractSpecEntryOutput{EntryType: "enum", Name: enum.Name, Lib: enum.Lib, Doc: enum.Doc}
+		definition = map[string]interface{}{"cases": cases}
+	case xdr.ScSpecEntryKindScSpecEntryUdtErrorEnumV0:
+		errorEnum := entry.MustUdtErrorEnumV0()
+		cases := []interface{}{}
+		for _, errorCase := range errorEnum.Cases {
+			cases = append(cases, map[string]interface{}{"name": escapeJSONBString(errorCase.Name), "value": uint32(errorCase.Value), "doc": escapeJSONBString(errorCase.Doc)})
+		}
+		output = ContractSpecEntryOutput{EntryType: "error_enum", Name: errorEnum.Name, Lib: errorEnum.Lib, Doc: errorEnum.Doc}
+		definition = map[string]interface{}{"cases": cases}
+	case xdr.ScSpecEntryKindScSpecEntryEventV0:
+		event := entry.MustEventV0()
+		prefixTopics := []interface{}{}
+		for _, topic := range event.PrefixTopics {
+			prefixTopics = append(prefixTopics, escapeJSONBString(string(topic)))
+		}
+		params := []interface{}{}
+		for _, param := range event.Params {
+			params = append(params, map[string]interface{}{
+				"name":     escapeJSONBString(param.Name),
+				"type":     ScSpecTypeName(param.Type),
+				"location": strings.ToLower(strings.TrimPrefix(param.Location.String(), "ScSpecEventParamLocationV0ScSpecEventParamLocation")),
+				"doc":      escapeJSONBString(param.Doc),
+			})
+		}
+		output = ContractSpecEntryOutput{EntryType: "event", Name: string(event.Name), Lib: event.Lib, Doc: event.Doc}
+		definition = map[string]interface{}{
+			"prefix_topics": prefixTopics,
+			"params":        params,
+			"data_format":   strings.ToLower(strings.TrimPrefix(event.DataFormat.String(), "ScSpecEventDataFormatScSpecEventDataFormat")),
+		}
+	default:
+		output = ContractSpecEntryOutput{EntryType: entry.Kind.String()}
+		definition = map[string]interface{}{}
+	}
+	output.Name = escapeJSONBString(output.Name)
+	output.Lib = escapeJSONBString(output.Lib)
+	output.Doc = escapeJSONBString(output.Doc)
+
+	definitionJSON, err := json.Marshal(definition)
+	if err != nil {
+		return ContractSpecEntryOutput{}, fmt.Errorf("could not encode definition of spec entry %s: %w", output.Name, err)
+	}
+	output.Definition = string(definitionJSON)
+	if output.EntryXDR, err = xdr.MarshalBase64(entry); err != nil {
+		return ContractSpecEntryOutput{}, fmt.Errorf("could not encode spec entry %s: %w", output.Name, err)
+	}
+	return output, nil
+}
+
+// ScSpecTypeName returns the name of a spec type as written in the Rust SDK, e.g. u32, Address,
+// Option<Vec<i128>>, BytesN<32>, or the name of a user defined type.
+func ScSpecTypeName(typeDef xdr.ScSpecTypeDef) string {
+	switch typeDef.Type {
+	case xdr.ScSpecTypeScSpecTypeAddress:
+		return "Address"
+	case xdr.ScSpecTypeScSpecTypeMuxedAddress:
+		return "MuxedAddress"
+	case xdr.ScSpecTypeScSpecTypeBytes:
+		return "Bytes"
+	case xdr.ScSpecTypeScSpecTypeString:
+		return "String"
+	case xdr.ScSpecTypeScSpecTypeSymbol:
+		return "Symbol"
+	case xdr.ScSpecTypeScSpecTypeVal:
+		return "Val"
+	case xdr.ScSpecTypeScSpecTypeError:
+		return "Error"
+	case xdr.ScSpecTypeScSpecTypeVoid:
+		return "()"
+	case xdr.ScSpecTypeScSpecTypeOption:
+		return fmt.Sprintf("Option<%s>", ScSpecTypeName(typeDef.MustOption().ValueType))
+	case xdr.ScSpecTypeScSpecTypeResult:
+		result := typeDef.MustResult()
+		return fmt.Sprintf("Result<%s, %s>", ScSpecTypeName(result.OkType), ScSpecTypeName(result.ErrorType))
+	case xdr.ScSpecTypeScSpecTypeVec:
+		return fmt.Sprintf("Vec<%s>", ScSpecTypeName(typeDef.MustVec().ElementType))
+	case xdr.ScSpecTypeScSpecTypeMap:
+		scMap := typeDef.MustMap()
+		return fmt.Sprintf("Map<%s, %s>", ScSpecTypeName(scMap.KeyType), ScSpecTypeName(scMap.ValueType))
+	case xdr.ScSpecTypeScSpecTypeTuple:
+		var valueTypes []string
+		for _, valueType := range typeDef.MustTuple().ValueTypes {
+			valueTypes = append(valueTypes, ScSpecTypeName(valueType))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(valueTypes, ", "))
+	case xdr.ScSpecTypeScSpecTypeBytesN:
+		return fmt.Sprintf("BytesN<%d>", typeDef.MustBytesN().N)
+	case xdr.ScSpecTypeScSpecTypeUdt:
+		return escapeJSONBString(typeDef.MustUdt().Name)
+	default:
+		// Numbers and bool are named as in Rust, e.g. ScSpecTypeScSpecTypeU32 is u32
+		return strings.ToLower(strings.TrimPrefix(typeDef.Type.String(), "ScSpecTypeScSpecType"))
+	}
+}
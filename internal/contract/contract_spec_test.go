@@ -0,0 +1,167 @@
+package contract
+
+import (
+	"encoding"
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformContractSpec(t *testing.T) {
+	balance := xdr.ScSpecEntry{
+		Kind: xdr.ScSpecEntryKindScSpecEntryFunctionV0,
+		FunctionV0: &xdr.ScSpecFunctionV0{
+			Doc:  "Returns the balance of id",
+			Name: "balance",
+			Inputs: []xdr.ScSpecFunctionInputV0{
+				{Name: "id", Type: xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeAddress}},
+			},
+			Outputs: []xdr.ScSpecTypeDef{{Type: xdr.ScSpecTypeScSpecTypeI128}},
+		},
+	}
+	dataKey := xdr.ScSpecEntry{
+		Kind: xdr.ScSpecEntryKindScSpecEntryUdtUnionV0,
+		UdtUnionV0: &xdr.ScSpecUdtUnionV0{
+			Name: "DataKey",
+			Cases: []xdr.ScSpecUdtUnionCaseV0{
+				{Kind: xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseVoidV0, VoidCase: &xdr.ScSpecUdtUnionCaseVoidV0{Name: "Admin"}},
+				{
+					Kind: xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseTupleV0,
+					TupleCase: &xdr.ScSpecUdtUnionCaseTupleV0{
+						Name: "Balance",
+						Type: []xdr.ScSpecTypeDef{{Type: xdr.ScSpecTypeScSpecTypeAddress}},
+					},
+				},
+			},
+		},
+	}
+	sdkVersion := xdr.ScMetaEntry{Kind: xdr.ScMetaKindScMetaV0, V0: &xdr.ScMetaV0{Key: "rssdkver", Val: "22.0.0#abc"}}
+	interfaceVersion := xdr.ScEnvMetaEntry{
+		Kind:             xdr.ScEnvMetaKindScEnvMetaKindInterfaceVersion,
+		InterfaceVersion: &xdr.ScEnvMetaEntryInterfaceVersion{Protocol: 22, PreRelease: 0},
+	}
+
+	code := makeWasmTestModule(map[string][]byte{
+		"contractspecv0":    xdrTestBytes(t, balance, dataKey),
+		"contractmetav0":    xdrTestBytes(t, sdkVersion),
+		"contractenvmetav0": xdrTestBytes(t, interfaceVersion),
+	})
+	balanceXDR, err := xdr.MarshalBase64(balance)
+	require.NoError(t, err)
+	dataKeyXDR, err := xdr.MarshalBase64(dataKey)
+	require.NoError(t, err)
+
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{ScpValue: xdr.StellarValue{CloseTime: 1000}, LedgerSeq: 10}}
+	change := ingest.Change{
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Type:       xdr.LedgerEntryTypeContractCode,
+		Post: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:         xdr.LedgerEntryTypeContractCode,
+				ContractCode: &xdr.ContractCodeEntry{Hash: xdr.Hash{1}, Code: code},
+			},
+		},
+	}
+
+	output, ok, err := TransformContractSpec(change, header)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, ContractSpecOutput{
+		ContractCodeHash: "0100000000000000000000000000000000000000000000000000000000000000",
+		LedgerSequence:   10,
+		ClosedAt:         time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+		Entries: []ContractSpecEntryOutput{
+			{
+				EntryIndex: 0,
+				EntryType:  "function",
+				Name:       "balance",
+				Doc:        "Returns the balance of id",
+				Definition: `{"inputs":[{"doc":"","name":"id","type":"Address"}],"outputs":["i128"]}`,
+				EntryXDR:   balanceXDR,
+			},
+			{
+				EntryIndex: 1,
+				EntryType:  "union",
+				Name:       "DataKey",
+				Definition: `{"cases":[{"doc":"","name":"Admin","types":[]},{"doc":"","name":"Balance","types":["Address"]}]}`,
+				EntryXDR:   dataKeyXDR,
+			},
+		},
+		Meta:               map[string]string{"rssdkver": "22.0.0#abc"},
+		SdkVersion:         "22.0.0#abc",
+		EnvProtocolVersion: 22,
+	}, output)
+
+	// Undecodable custom sections are reported without failing the change
+	change.Post.Data.ContractCode.Code = makeWasmTestModule(map[string][]byte{"contractspecv0": {0, 0, 0, 42}})
+	output, ok, err = TransformContractSpec(change, header)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Empty(t, output.Entries)
+	assert.Contains(t, output.ParseError, "could not decode contractspecv0 entry 0")
+
+	// Removed code has no Wasm to read
+	_, ok, err = TransformContractSpec(ingest.Change{ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Type: xdr.LedgerEntryTypeContractCode, Pre: change.Post}, header)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestScSpecTypeName(t *testing.T) {
+	i128 := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeI128}
+	address := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeAddress}
+	vec := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeVec, Vec: &xdr.ScSpecTypeVec{ElementType: address}}
+
+	tests := []struct {
+		input xdr.ScSpecTypeDef
+		want  string
+	}{
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeU32}, "u32"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeBool}, "bool"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeOption, Option: &xdr.ScSpecTypeOption{ValueType: vec}}, "Option<Vec<Address>>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeMap, Map: &xdr.ScSpecTypeMap{KeyType: address, ValueType: i128}}, "Map<Address, i128>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeTuple, Tuple: &xdr.ScSpecTypeTuple{ValueTypes: []xdr.ScSpecTypeDef{address, i128}}}, "(Address, i128)"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeBytesN, BytesN: &xdr.ScSpecTypeBytesN{N: 32}}, "BytesN<32>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeUdt, Udt: &xdr.ScSpecTypeUdt{Name: "DataKey"}}, "DataKey"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ScSpecTypeName(tt.input))
+	}
+}
+
+// makeWasmTestModule builds a Wasm module holding a type section followed by the given custom sections.
+func makeWasmTestModule(customSections map[string][]byte) []byte {
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, 0x01, 0x01, 0x00)
+	for name, content := range customSections {
+		payload := append(appendLEB128(nil, uint64(len(name))), name...)
+		payload = append(payload, content...)
+		module = append(module, 0x00)
+		module = append(appendLEB128(module, uint64(len(payload))), payload...)
+	}
+	return module
+}
+
+func xdrTestBytes(t *testing.T, values ...encoding.BinaryMarshaler) []byte {
+	var content []byte
+	for _, value := range values {
+		encoded, err := value.MarshalBinary()
+		require.NoError(t, err)
+		content = append(content, encoded...)
+	}
+	return content
+}
+
+func appendLEB128(data []byte, value uint64) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			return append(data, b)
+		}
+		data = append(data, b|0x80)
+	}
+}
@@ -0,0 +1,67 @@
+package contract
+
+import (
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// LedgerOutput is a representation of a ledger header along with the Soroban state of the ledger close meta
+type LedgerOutput struct {
+	Sequence           uint32    `json:"sequence"`
+	LedgerHash         string    `json:"ledger_hash"`
+	PreviousLedgerHash string    `json:"previous_ledger_hash"`
+	ClosedAt           time.Time `json:"closed_at"`
+	ProtocolVersion    uint32    `json:"protocol_version"`
+	BaseFee            uint32    `json:"base_fee"`
+	BaseReserve        uint32    `json:"base_reserve"`
+	MaxTxSetSize       uint32    `json:"max_tx_set_size"`
+	TransactionCount   uint32    `json:"transaction_count"`
+	// SorobanFeeWrite1Kb and TotalByteSizeOfLiveSorobanState are only present in LedgerCloseMeta V1 and above
+	SorobanFeeWrite1Kb              *int64  `json:"soroban_fee_write_1kb"`
+	TotalByteSizeOfLiveSorobanState *uint64 `json:"total_byte_size_of_live_soroban_state"`
+}
+
+// TransformLedger converts the header of a ledger close meta into a LedgerOutput
+func TransformLedger(ledgerCloseMeta xdr.LedgerCloseMeta) (LedgerOutput, error) {
+	header := ledgerCloseMeta.LedgerHeaderHistoryEntry().Header
+
+	closedAt, err := TimePointToUTCTimeStamp(header.ScpValue.CloseTime)
+	if err != nil {
+		return LedgerOutput{}, err
+	}
+
+	var sorobanFeeWrite1Kb *int64
+	var totalByteSizeOfLiveSorobanState *uint64
+	var ext xdr.LedgerCloseMetaExt
+	switch ledgerCloseMeta.V {
+	case 1:
+		v1 := ledgerCloseMeta.MustV1()
+		totalByteSize := uint64(v1.TotalByteSizeOfLiveSorobanState)
+		totalByteSizeOfLiveSorobanState = &totalByteSize
+		ext = v1.Ext
+	case 2:
+		v2 := ledgerCloseMeta.MustV2()
+		totalByteSize := uint64(v2.TotalByteSizeOfLiveSorobanState)
+		totalByteSizeOfLiveSorobanState = &totalByteSize
+		ext = v2.Ext
+	}
+	if extV1, ok := ext.GetV1(); ok {
+		feeWrite1Kb := int64(extV1.SorobanFeeWrite1Kb)
+		sorobanFeeWrite1Kb = &feeWrite1Kb
+	}
+
+	return LedgerOutput{
+		Sequence:                        uint32(header.LedgerSeq),
+		LedgerHash:                      ledgerCloseMeta.LedgerHash().HexString(),
+		PreviousLedgerHash:              ledgerCloseMeta.PreviousLedgerHash().HexString(),
+		ClosedAt:                        closedAt,
+		ProtocolVersion:                 uint32(header.LedgerVersion),
+		BaseFee:                         uint32(header.BaseFee),
+		BaseReserve:                     uint32(header.BaseReserve),
+		MaxTxSetSize:                    uint32(header.MaxTxSetSize),
+		TransactionCount:                uint32(ledgerCloseMeta.CountTransactions()),
+		SorobanFeeWrite1Kb:              sorobanFeeWrite1Kb,
+		TotalByteSizeOfLiveSorobanState: totalByteSizeOfLiveSorobanState,
+	}, nil
+}
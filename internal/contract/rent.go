@@ -0,0 +1,128 @@
+package contract
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ttlEntrySizeBytes is the size of the XDR encoded TTL ledger entry written when extending an entry
+const ttlEntrySizeBytes = 48
+
+// RentFeeConfiguration holds the network parameters rent fees are computed from, fees are in stroops
+type RentFeeConfiguration struct {
+	// FeeRent1Kb is the rent fee per 1KB written for a single ledger, before applying the rent rate
+	// denominator. It is computed by the network every ledger from the size of the live Soroban state.
+	FeeRent1Kb                    int64
+	FeeWrite1Kb                   int64
+	FeeWriteLedgerEntry           int64
+	PersistentRentRateDenominator int64
+	TemporaryRentRateDenominator  int64
+}
+
+// Validate checks that the configuration can be used to compute fees
+func (c RentFeeConfiguration) Validate() error {
+	if c.FeeRent1Kb <= 0 {
+		return fmt.Errorf("fee_rent_1kb must be positive, got %d", c.FeeRent1Kb)
+	}
+	if c.PersistentRentRateDenominator <= 0 {
+		return fmt.Errorf("persistent_rent_rate_denominator must be positive, got %d", c.PersistentRentRateDenominator)
+	}
+	if c.TemporaryRentRateDenominator <= 0 {
+		return fmt.Errorf("temporary_rent_rate_denominator must be positive, got %d", c.TemporaryRentRateDenominator)
+	}
+	if c.FeeWrite1Kb < 0 || c.FeeWriteLedgerEntry < 0 {
+		return fmt.Errorf("write fees must not be negative")
+	}
+	return nil
+}
+
+// RentFee returns the rent to keep sizeBytes of persistent or temporary storage alive for rentLedgers more ledgers.
+// It is computed like the network does for a single entry, so the rent of several entries computed from their
+// total size can be a few stroops lower than the sum of their individual rents.
+func (c RentFeeConfiguration) RentFee(persistent bool, sizeBytes uint64, rentLedgers uint32) int64 {
+	byteLedgers := new(big.Int).SetUint64(sizeBytes)
+	byteLedgers.Mul(byteLedgers, new(big.Int).SetUint64(uint64(rentLedgers)))
+	return c.byteLedgersRentFee(persistent, byteLedgers)
+}
+
+// byteLedgersRentFee returns the rent of byteLedgers, the sum of the size of each entry multiplied by the number of
+// ledgers it is kept alive for
+func (c RentFeeConfiguration) byteLedgersRentFee(persistent bool, byteLedgers *big.Int) int64 {
+	denominator := c.TemporaryRentRateDenominator
+	if persistent {
+		denominator = c.PersistentRentRateDenominator
+	}
+	numerator := new(big.Int).Mul(byteLedgers, big.NewInt(c.FeeRent1Kb))
+	return ceilDiv(numerator, big.NewInt(1024*denominator))
+}
+
+// TTLWriteFee returns the fee to write the TTL entries of entryCount extended entries
+func (c RentFeeConfiguration) TTLWriteFee(entryCount uint64) int64 {
+	writeBytes := new(big.Int).SetUint64(entryCount * ttlEntrySizeBytes)
+	writeBytes.Mul(writeBytes, big.NewInt(c.FeeWrite1Kb))
+	return int64(entryCount)*c.FeeWriteLedgerEntry + ceilDiv(writeBytes, big.NewInt(1024))
+}
+
+// EntryRentFee returns the fee to extend the TTL of a single live entry by extendLedgers
+func (c RentFeeConfiguration) EntryRentFee(persistent bool, sizeBytes uint32, extendLedgers uint32) int64 {
+	return c.RentFee(persistent, uint64(sizeBytes), extendLedgers) + c.TTLWriteFee(1)
+}
+
+// ExtensionLedgers returns the number of ledgers the TTL of an entry live until liveUntilLedger is extended by to
+// keep it live for extendLedgers past ledgerSequence, zero when it already is.
+func ExtensionLedgers(liveUntilLedger uint32, ledgerSequence uint32, extendLedgers uint32) uint32 {
+	target := uint64(ledgerSequence) + uint64(extendLedgers)
+	if uint64(liveUntilLedger) >= target {
+		return 0
+	}
+	return uint32(target - uint64(liveUntilLedger))
+}
+
+// ContractStorageSize is the live storage of a contract, split by durability, along with the extension of its
+// entries needed to keep all of them live up to a target ledger
+type ContractStorageSize struct {
+	ContractId          string
+	PersistentEntries   uint64
+	PersistentSizeBytes uint64
+	TemporaryEntries    uint64
+	TemporarySizeBytes  uint64
+	// PersistentExtensionByteLedgers and TemporaryExtensionByteLedgers sum the size of each entry multiplied by
+	// the number of ledgers its TTL is extended by
+	PersistentExtensionByteLedgers uint64
+	TemporaryExtensionByteLedgers  uint64
+	// ExtendedEntries is the number of entries live until a ledger below the target, whose TTL is extended
+	ExtendedEntries uint64
+}
+
+// ContractRentEstimate is the fee to extend the TTL of the live entries of a contract up to ExtendLedgers past the
+// ledger the storage was read at
+type ContractRentEstimate struct {
+	ContractStorageSize
+	ExtendLedgers     uint32
+	PersistentRentFee int64
+	TemporaryRentFee  int64
+	TTLWriteFee       int64
+	RentFee           int64
+}
+
+// EstimateContractRent estimates the rent to extend the TTL of the live entries of a contract up to extendLedgers
+// past the ledger its storage was read at. Entries already live until then are not extended.
+func (c RentFeeConfiguration) EstimateContractRent(storage ContractStorageSize, extendLedgers uint32) ContractRentEstimate {
+	estimate := ContractRentEstimate{
+		ContractStorageSize: storage,
+		ExtendLedgers:       extendLedgers,
+		PersistentRentFee:   c.byteLedgersRentFee(true, new(big.Int).SetUint64(storage.PersistentExtensionByteLedgers)),
+		TemporaryRentFee:    c.byteLedgersRentFee(false, new(big.Int).SetUint64(storage.TemporaryExtensionByteLedgers)),
+		TTLWriteFee:         c.TTLWriteFee(storage.ExtendedEntries),
+	}
+	estimate.RentFee = estimate.PersistentRentFee + estimate.TemporaryRentFee + estimate.TTLWriteFee
+	return estimate
+}
+
+func ceilDiv(numerator *big.Int, denominator *big.Int) int64 {
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient.Int64()
+}
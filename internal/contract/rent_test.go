@@ -0,0 +1,71 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRentFeeConfiguration = RentFeeConfiguration{
+	FeeRent1Kb:                    10000,
+	FeeWrite1Kb:                   3500,
+	FeeWriteLedgerEntry:           1000,
+	PersistentRentRateDenominator: 2103,
+	TemporaryRentRateDenominator:  4206,
+}
+
+func TestEntryRentFee(t *testing.T) {
+	// ceil(136 * 10000 * 535680 / (1024 * 2103)) + 1000 + ceil(48 * 3500 / 1024)
+	assert.Equal(t, int64(338303+1165), testRentFeeConfiguration.EntryRentFee(true, 136, 535680))
+	// Temporary storage has a higher rent rate denominator
+	assert.Equal(t, int64(169152+1165), testRentFeeConfiguration.EntryRentFee(false, 136, 535680))
+	// Extending by no ledgers only writes the TTL entry
+	assert.Equal(t, int64(1165), testRentFeeConfiguration.EntryRentFee(true, 136, 0))
+}
+
+func TestEstimateContractRent(t *testing.T) {
+	// Every entry is extended by 100 ledgers
+	storage := ContractStorageSize{
+		ContractId:                     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		PersistentEntries:              2,
+		PersistentSizeBytes:            1000,
+		TemporaryEntries:               1,
+		TemporarySizeBytes:             500,
+		PersistentExtensionByteLedgers: 1000 * 100,
+		TemporaryExtensionByteLedgers:  500 * 100,
+		ExtendedEntries:                3,
+	}
+	assert.Equal(t, ContractRentEstimate{
+		ContractStorageSize: storage,
+		ExtendLedgers:       100,
+		PersistentRentFee:   465,
+		TemporaryRentFee:    117,
+		TTLWriteFee:         3493,
+		RentFee:             4075,
+	}, testRentFeeConfiguration.EstimateContractRent(storage, 100))
+
+	// Sizes beyond int64 once multiplied by the fee and the ledgers do not overflow
+	storage = ContractStorageSize{PersistentEntries: 1, PersistentSizeBytes: 1 << 40, PersistentExtensionByteLedgers: (1 << 40) * 535680, ExtendedEntries: 1}
+	estimate := testRentFeeConfiguration.EstimateContractRent(storage, 535680)
+	assert.Equal(t, int64(2735054780220257), estimate.PersistentRentFee)
+
+	// Entries already live until the target are neither charged rent nor a TTL write
+	storage = ContractStorageSize{PersistentEntries: 1, PersistentSizeBytes: 1000}
+	estimate = testRentFeeConfiguration.EstimateContractRent(storage, 100)
+	assert.Equal(t, int64(0), estimate.RentFee)
+}
+
+func TestExtensionLedgers(t *testing.T) {
+	// The entry is live until 150, extending it to 100 ledgers past 100 only adds the missing 50
+	assert.Equal(t, uint32(50), ExtensionLedgers(150, 100, 100))
+	assert.Equal(t, uint32(0), ExtensionLedgers(200, 100, 100))
+	assert.Equal(t, uint32(0), ExtensionLedgers(300, 100, 100))
+}
+
+func TestRentFeeConfigurationValidate(t *testing.T) {
+	assert.NoError(t, testRentFeeConfiguration.Validate())
+
+	configuration := testRentFeeConfiguration
+	configuration.TemporaryRentRateDenominator = 0
+	assert.ErrorContains(t, configuration.Validate(), "temporary_rent_rate_denominator")
+}
@@ -0,0 +1,203 @@
+package contract
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ScValToJSON encodes an ScVal as JSON so that it can be stored in a jsonb column and filtered on nested fields.
+// Every value is an object keyed by its type, following the JSON representation of stellar-xdr, e.g.
+//
+//	{"vec":[{"symbol":"Balance"},{"address":"CA..."}]}
+//
+// Types without a value, void and the contract instance key, are the strings "void" and "ledger_key_contract_instance".
+// 32 bit integers are JSON numbers. Wider integers are decimal strings, as they do not fit a JSON number.
+// Bytes and hashes are hex encoded, addresses are strkeys and maps are lists of {"key": ..., "val": ...}
+// objects to keep the order and non string keys of ScMap.
+func ScValToJSON(scVal xdr.ScVal) (string, error) {
+	value, err := scValToJSONValue(scVal)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("could not encode %s as json: %w", scVal.Type, err)
+	}
+	return string(encoded), nil
+}
+
+func scValToJSONValue(scVal xdr.ScVal) (interface{}, error) {
+	switch scVal.Type {
+	case xdr.ScValTypeScvBool:
+		return map[string]interface{}{"bool": scVal.MustB()}, nil
+	case xdr.ScValTypeScvVoid:
+		return "void", nil
+	case xdr.ScValTypeScvError:
+		scError := scVal.MustError()
+		errorJSON := map[string]interface{}{"type": scError.Type.String()}
+		if scError.ContractCode != nil {
+			errorJSON["code"] = uint32(*scError.ContractCode)
+		} else if scError.Code != nil {
+			errorJSON["code"] = scError.Code.String()
+		}
+		return map[string]interface{}{"error": errorJSON}, nil
+	case xdr.ScValTypeScvU32:
+		return map[string]interface{}{"u32": uint32(scVal.MustU32())}, nil
+	case xdr.ScValTypeScvI32:
+		return map[string]interface{}{"i32": int32(scVal.MustI32())}, nil
+	case xdr.ScValTypeScvU64:
+		return map[string]interface{}{"u64": strconv.FormatUint(uint64(scVal.MustU64()), 10)}, nil
+	case xdr.ScValTypeScvI64:
+		return map[string]interface{}{"i64": strconv.FormatInt(int64(scVal.MustI64()), 10)}, nil
+	case xdr.ScValTypeScvTimepoint:
+		return map[string]interface{}{"timepoint": strconv.FormatUint(uint64(scVal.MustTimepoint()), 10)}, nil
+	case xdr.ScValTypeScvDuration:
+		return map[string]interface{}{"duration": strconv.FormatUint(uint64(scVal.MustDuration()), 10)}, nil
+	case xdr.ScValTypeScvU128:
+		parts := scVal.MustU128()
+		return map[string]interface{}{"u128": partsToBigInt(false, uint64(parts.Hi), uint64(parts.Lo)).String()}, nil
+	case xdr.ScValTypeScvI128:
+		parts := scVal.MustI128()
+		return map[string]interface{}{"i128": partsToBigInt(true, uint64(parts.Hi), uint64(parts.Lo)).String()}, nil
+	case xdr.ScValTypeScvU256:
+		parts := scVal.MustU256()
+		return map[string]interface{}{"u256": partsToBigInt(false, uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo)).String()}, nil
+	case xdr.ScValTypeScvI256:
+		parts := scVal.MustI256()
+		return map[string]interface{}{"i256": partsToBigInt(true, uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo)).String()}, nil
+	case xdr.ScValTypeScvBytes:
+		return map[string]interface{}{"bytes": hex.EncodeToString(scVal.MustBytes())}, nil
+	case xdr.ScValTypeScvString:
+		return map[string]interface{}{"string": escapeJSONBString(string(scVal.MustStr()))}, nil
+	case xdr.ScValTypeScvSymbol:
+		return map[string]interface{}{"symbol": string(scVal.MustSym())}, nil
+	case xdr.ScValTypeScvVec:
+		vec := scVal.MustVec()
+		// A nil vec is a valid, empty vec
+		if vec == nil {
+			return map[string]interface{}{"vec": []interface{}{}}, nil
+		}
+		values, err := scValsToJSONValues(*vec)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"vec": values}, nil
+	case xdr.ScValTypeScvMap:
+		scMap := scVal.MustMap()
+		if scMap == nil {
+			return map[string]interface{}{"map": []interface{}{}}, nil
+		}
+		entries, err := scMapToJSONValue(*scMap)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"map": entries}, nil
+	case xdr.ScValTypeScvAddress:
+		address, err := scVal.MustAddress().String()
+		if err != nil {
+			return nil, fmt.Errorf("could not encode address: %w", err)
+		}
+		return map[string]interface{}{"address": address}, nil
+	case xdr.ScValTypeScvContractInstance:
+		instance := scVal.MustInstance()
+		instanceJSON := map[string]interface{}{}
+		switch instance.Executable.Type {
+		case xdr.ContractExecutableTypeContractExecutableWasm:
+			instanceJSON["executable"] = map[string]interface{}{"wasm": instance.Executable.MustWasmHash().HexString()}
+		case xdr.ContractExecutableTypeContractExecutableStellarAsset:
+			instanceJSON["executable"] = "stellar_asset"
+		default:
+			return nil, fmt.Errorf("unsupported contract executable type %s", instance.Executable.Type)
+		}
+		storage := []interface{}{}
+		if instance.Storage != nil {
+			var err error
+			if storage, err = scMapToJSONValue(*instance.Storage); err != nil {
+				return nil, err
+			}
+		}
+		instanceJSON["storage"] = storage
+		return map[string]interface{}{"contract_instance": instanceJSON}, nil
+	case xdr.ScValTypeScvLedgerKeyContractInstance:
+		return "ledger_key_contract_instance", nil
+	case xdr.ScValTypeScvLedgerKeyNonce:
+		nonce := scVal.MustNonceKey()
+		return map[string]interface{}{"ledger_key_nonce": map[string]interface{}{"nonce": strconv.FormatInt(int64(nonce.Nonce), 10)}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ScVal type %s", scVal.Type)
+	}
+}
+
+func scValsToJSONValues(scVals []xdr.ScVal) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(scVals))
+	for _, scVal := range scVals {
+		value, err := scValToJSONValue(scVal)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func scMapToJSONValue(scMap xdr.ScMap) ([]interface{}, error) {
+	entries := make([]interface{}, 0, len(scMap))
+	for _, entry := range scMap {
+		key, err := scValToJSONValue(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		val, err := scValToJSONValue(entry.Val)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, map[string]interface{}{"key": key, "val": val})
+	}
+	return entries, nil
+}
+
+// partsToBigInt joins the 64 bit parts of a 128 or 256 bit integer, most significant first.
+// Signed integers are stored in two's complement, with the sign in the most significant part.
+func partsToBigInt(signed bool, parts ...uint64) *big.Int {
+	value := new(big.Int)
+	for _, part := range parts {
+		value.Lsh(value, 64)
+		value.Or(value, new(big.Int).SetUint64(part))
+	}
+	if signed && len(parts) > 0 && int64(parts[0]) < 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(64*len(parts))))
+	}
+	return value
+}
+
+// escapeJSONBString escapes the strings that can not be stored in jsonb, which rejects NUL characters and invalid UTF-8.
+// Backslashes, NUL and invalid bytes are escaped as \\, \0 and \xNN. Backslashes are escaped in every string, so that
+// an escaped string can not be mistaken for one holding the escape sequence itself.
+func escapeJSONBString(value string) string {
+	if utf8.ValidString(value) && !strings.ContainsAny(value, "\x00\\") {
+		return value
+	}
+	var escaped strings.Builder
+	for len(value) > 0 {
+		r, size := utf8.DecodeRuneInString(value)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&escaped, "\\x%02x", value[0])
+		case r == 0:
+			escaped.WriteString("\\0")
+		case r == '\\':
+			escaped.WriteString("\\\\")
+		default:
+			escaped.WriteRune(r)
+		}
+		value = value[size:]
+	}
+	return escaped.String()
+}
@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScValToJSON(t *testing.T) {
+	symbol := xdr.ScSymbol("Balance")
+	str := xdr.ScString("hello")
+	nulStr := xdr.ScString("a\x00b\\")
+	// Holds the text of an escaped NUL, which must not read back as a NUL
+	escapeStr := xdr.ScString("a\\0b")
+	bytes := xdr.ScBytes{0xde, 0xad, 0xbe, 0xef}
+	u32 := xdr.Uint32(7)
+	i64 := xdr.Int64(-42)
+	i128 := xdr.Int128Parts{Hi: -1, Lo: math.MaxUint64}
+	u128 := xdr.UInt128Parts{Hi: 1, Lo: 0}
+	u256 := xdr.UInt256Parts{HiHi: 0, HiLo: 0, LoHi: 0, LoLo: 255}
+	contractID := xdr.ContractId{}
+	address := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID}
+	boolean := true
+
+	vec := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &symbol},
+		{Type: xdr.ScValTypeScvAddress, Address: &address},
+	}
+	scMap := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &symbol}, Val: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &i128}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &u32}, Val: xdr.ScVal{Type: xdr.ScValTypeScvVoid}},
+	}
+	var emptyVec *xdr.ScVec
+
+	tests := []struct {
+		name  string
+		input xdr.ScVal
+		want  string
+	}{
+		{"bool", xdr.ScVal{Type: xdr.ScValTypeScvBool, B: &boolean}, `{"bool":true}`},
+		{"void", xdr.ScVal{Type: xdr.ScValTypeScvVoid}, `"void"`},
+		{"u32", xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &u32}, `{"u32":7}`},
+		{"i64", xdr.ScVal{Type: xdr.ScValTypeScvI64, I64: &i64}, `{"i64":"-42"}`},
+		{"negative i128", xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &i128}, `{"i128":"-1"}`},
+		{"u128", xdr.ScVal{Type: xdr.ScValTypeScvU128, U128: &u128}, `{"u128":"18446744073709551616"}`},
+		{"u256", xdr.ScVal{Type: xdr.ScValTypeScvU256, U256: &u256}, `{"u256":"255"}`},
+		{"bytes", xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &bytes}, `{"bytes":"deadbeef"}`},
+		{"string", xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &str}, `{"string":"hello"}`},
+		// jsonb can not store NUL characters
+		{"string with NUL", xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &nulStr}, `{"string":"a\\0b\\\\"}`},
+		// Backslashes are escaped in every string
+		{"string with backslash", xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &escapeStr}, `{"string":"a\\\\0b"}`},
+		{"symbol", xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &symbol}, `{"symbol":"Balance"}`},
+		{
+			"vec",
+			xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &vec},
+			`{"vec":[{"symbol":"Balance"},{"address":"CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4"}]}`,
+		},
+		{"empty vec", xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &emptyVec}, `{"vec":[]}`},
+		{
+			"map",
+			xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &scMap},
+			`{"map":[{"key":{"symbol":"Balance"},"val":{"i128":"-1"}},{"key":{"u32":7},"val":"void"}]}`,
+		},
+		{"ledger key contract instance", xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance}, `"ledger_key_contract_instance"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScValToJSON(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package contract
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// TokenTransferOutput is a SEP-41 token event: a transfer, mint, burn, clawback, approve or set_admin
+type TokenTransferOutput struct {
+	TransactionHash string    `json:"transaction_hash"`
+	TransactionID   int64     `json:"transaction_id"`
+	EventIndex      uint32    `json:"event_index"`
+	LedgerSequence  uint32    `json:"ledger_sequence"`
+	ClosedAt        time.Time `json:"closed_at"`
+	ContractId      string    `json:"contract_id"`
+	EventType       string    `json:"event_type"`
+	// From is the address the amount leaves, or the owner of an allowance. It is empty for mints.
+	From string `json:"from"`
+	// To is the address the amount goes to, the spender of an allowance or the new admin. It is empty for burns and clawbacks.
+	To string `json:"to"`
+	// Admin is the admin that minted or clawed back in the older event shapes, or the admin replaced by set_admin
+	Admin string `json:"admin"`
+	// Amount is a decimal string, as i128 amounts do not fit an int64. It is empty for set_admin.
+	Amount string `json:"amount"`
+	// ToMuxedId is the muxed id of the destination of CAP-67 transfers and mints
+	ToMuxedId string `json:"to_muxed_id"`
+	// ExpirationLedger is only set for approvals
+	ExpirationLedger *uint32 `json:"expiration_ledger"`
+	// Asset is the classic asset of Stellar Asset Contracts, e.g. native or USDC:GA5Z...
+	Asset string `json:"asset"`
+}
+
+// tokenEventAddressTopics lists the accepted number of address topics following the event name.
+// mint and clawback have an extra leading admin topic in the older SAC and SEP-41 shapes.
+var tokenEventAddressTopics = map[string][]int{
+	"transfer":  {2},
+	"mint":      {1, 2},
+	"burn":      {1},
+	"clawback":  {1, 2},
+	"approve":   {2},
+	"set_admin": {1},
+}
+
+// TransformTokenTransfer returns the SEP-41 token events of a transaction, with the event index of TransformContractEvent.
+func TransformTokenTransfer(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passphrase string) ([]TokenTransferOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+	closedAt, err := TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []TokenTransferOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	events, err := getTransactionEvents(transaction)
+	if err != nil {
+		return []TokenTransferOutput{}, err
+	}
+	// Events of failed transactions were rolled back
+	if !transaction.Result.Successful() {
+		return []TokenTransferOutput{}, nil
+	}
+
+	tokenTransfers := []TokenTransferOutput{}
+	for eventIndex, event := range events {
+		tokenTransfer, ok := transformTokenEvent(event, passphrase)
+		if !ok {
+			continue
+		}
+		tokenTransfer.TransactionHash = HashToHexString(transaction.Result.TransactionHash)
+		tokenTransfer.TransactionID = transactionID
+		tokenTransfer.EventIndex = uint32(eventIndex)
+		tokenTransfer.LedgerSequence = ledgerSequence
+		tokenTransfer.ClosedAt = closedAt
+		tokenTransfers = append(tokenTransfers, tokenTransfer)
+	}
+	return tokenTransfers, nil
+}
+
+// transformTokenEvent recognizes SEP-41 token events from the shape of their topics and data.
+// Both the CAP-67 shapes and the older Stellar Asset Contract shapes are supported. Stellar Asset Contracts
+// add the asset as a last string topic, which is only kept when it matches the contract id.
+// It returns false for any other event, and for events of calls that were rolled back.
+func transformTokenEvent(diagnosticEvent xdr.DiagnosticEvent, passphrase string) (TokenTransferOutput, bool) {
+	contractEvent := diagnosticEvent.Event
+	if contractEvent.Type != xdr.ContractEventTypeContract || !diagnosticEvent.InSuccessfulContractCall || contractEvent.ContractId == nil {
+		return TokenTransferOutput{}, false
+	}
+	contractId, err := strkey.Encode(strkey.VersionByteContract, contractEvent.ContractId[:])
+	if err != nil {
+		return TokenTransferOutput{}, false
+	}
+	topics := getEventTopics(contractEvent.Body)
+	data := getEventData(contractEvent.Body)
+	if len(topics) == 0 {
+		return TokenTransferOutput{}, false
+	}
+	name, ok := topics[0].GetSym()
+	if !ok {
+		return TokenTransferOutput{}, false
+	}
+	acceptedAddressCounts, ok := tokenEventAddressTopics[string(name)]
+	if !ok {
+		return TokenTransferOutput{}, false
+	}
+
+	var addresses []string
+	var assetTopic *xdr.ScString
+	for index, topic := range topics[1:] {
+		if scAddress, ok := topic.GetAddress(); ok && assetTopic == nil {
+			address, err := scAddress.String()
+			if err != nil {
+				return TokenTransferOutput{}, false
+			}
+			addresses = append(addresses, address)
+			continue
+		}
+		// Only a last string topic is accepted, for the asset of Stellar Asset Contracts
+		if str, ok := topic.GetStr(); ok && index == len(topics)-2 {
+			assetTopic = &str
+			continue
+		}
+		return TokenTransferOutput{}, false
+	}
+	if !slices.Contains(acceptedAddressCounts, len(addresses)) {
+		return TokenTransferOutput{}, false
+	}
+
+	output := TokenTransferOutput{
+		ContractId: contractId,
+		EventType:  string(name),
+	}
+	switch name {
+	case "transfer", "approve":
+		output.From, output.To = addresses[0], addresses[1]
+	case "mint":
+		output.To = addresses[len(addresses)-1]
+		if len(addresses) == 2 {
+			output.Admin = addresses[0]
+		}
+	case "burn":
+		output.From = addresses[0]
+	case "clawback":
+		output.From = addresses[len(addresses)-1]
+		if len(addresses) == 2 {
+			output.Admin = addresses[0]
+		}
+	case "set_admin":
+		output.Admin = addresses[0]
+	}
+
+	if !readTokenEventData(string(name), data, &output) {
+		return TokenTransferOutput{}, false
+	}
+
+	if assetTopic != nil {
+		if asset, ok := stellarAssetFromTopic(string(*assetTopic), *contractEvent.ContractId, passphrase); ok {
+			output.Asset = asset.StringCanonical()
+		}
+	}
+	return output, true
+}
+
+// readTokenEventData reads the amount, or the new admin of set_admin, from the event data.
+// CAP-67 transfers and mints to a muxed account have a map holding the amount and to_muxed_id.
+// approve has a vec holding the amount and the expiration ledger.
+func readTokenEventData(name string, data xdr.ScVal, output *TokenTransferOutput) bool {
+	switch name {
+	case "set_admin":
+		scAddress, ok := data.GetAddress()
+		if !ok {
+			return false
+		}
+		newAdmin, err := scAddress.String()
+		if err != nil {
+			return false
+		}
+		output.To = newAdmin
+		return true
+	case "approve":
+		vec, ok := data.GetVec()
+		if !ok || vec == nil || len(*vec) != 2 {
+			return false
+		}
+		amount, ok := tokenAmount((*vec)[0])
+		if !ok {
+			return false
+		}
+		expirationLedger, ok := (*vec)[1].GetU32()
+		if !ok {
+			return false
+		}
+		output.Amount = amount
+		expiration := uint32(expirationLedger)
+		output.ExpirationLedger = &expiration
+		return true
+	}
+
+	if amount, ok := tokenAmount(data); ok {
+		output.Amount = amount
+		return true
+	}
+	scMap, ok := data.GetMap()
+	if !ok || scMap == nil || (name != "transfer" && name != "mint") {
+		return false
+	}
+	for _, entry := range *scMap {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return false
+		}
+		switch key {
+		case "amount":
+			if output.Amount, ok = tokenAmount(entry.Val); !ok {
+				return false
+			}
+		case "to_muxed_id":
+			switch entry.Val.Type {
+			case xdr.ScValTypeScvU64:
+				output.ToMuxedId = strconv.FormatUint(uint64(entry.Val.MustU64()), 10)
+			case xdr.ScValTypeScvBytes:
+				output.ToMuxedId = hex.EncodeToString(entry.Val.MustBytes())
+			case xdr.ScValTypeScvString:
+				output.ToMuxedId = escapeJSONBString(string(entry.Val.MustStr()))
+			}
+		}
+	}
+	return output.Amount != ""
+}
+
+func tokenAmount(scVal xdr.ScVal) (string, bool) {
+	parts, ok := scVal.GetI128()
+	if !ok {
+		return "", false
+	}
+	return partsToBigInt(true, uint64(parts.Hi), uint64(parts.Lo)).String(), true
+}
+
+// stellarAssetFromTopic parses the asset topic of a Stellar Asset Contract event, e.g. native or USDC:GA5Z...,
+// and checks that it is the asset of the contract that emitted the event.
+func stellarAssetFromTopic(topic string, contractId xdr.ContractId, passphrase string) (xdr.Asset, bool) {
+	var asset xdr.Asset
+	if topic == "native" {
+		asset = xdr.MustNewNativeAsset()
+	} else {
+		code, issuer, found := strings.Cut(topic, ":")
+		if !found {
+			return xdr.Asset{}, false
+		}
+		var err error
+		if asset, err = xdr.NewCreditAsset(code, issuer); err != nil {
+			return xdr.Asset{}, false
+		}
+	}
+	expectedId, err := asset.ContractID(passphrase)
+	if err != nil || expectedId != contractId {
+		return xdr.Asset{}, false
+	}
+	return asset, true
+}
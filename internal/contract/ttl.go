@@ -17,6 +17,11 @@ type TtlOutput struct {
 	Deleted            bool      `json:"deleted"`
 	ClosedAt           time.Time `json:"closed_at"`
 	LedgerSequence     uint32    `json:"ledger_sequence"`
+	// TransactionHash, ApplicationOrder and OperationIndex identify the change that wrote this ttl,
+	// they are empty for changes made by the ledger itself, e.g. evictions
+	TransactionHash  string  `json:"transaction_hash"`
+	ApplicationOrder uint32  `json:"application_order"`
+	OperationIndex   *uint32 `json:"operation_index"`
 }
 
 // TransformTtl converts an ttl ledger change entry into a form suitable for BigQuery
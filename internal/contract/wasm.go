@@ -0,0 +1,58 @@
+package contract
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+// WasmCustomSections returns the payload of the custom sections of a Wasm module, keyed by section name.
+// Sections sharing a name, e.g. when several crates of a contract export a spec, are concatenated in order.
+func WasmCustomSections(code []byte) (map[string][]byte, error) {
+	if len(code) < 8 || !bytes.Equal(code[:4], wasmMagic) {
+		return nil, fmt.Errorf("not a wasm module")
+	}
+	sections := map[string][]byte{}
+	offset := 8
+	for offset < len(code) {
+		sectionId := code[offset]
+		offset++
+		size, n, err := readLEB128(code[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("could not read size of section %d: %w", sectionId, err)
+		}
+		offset += n
+		if uint64(len(code)-offset) < size {
+			return nil, fmt.Errorf("section %d is truncated", sectionId)
+		}
+		payload := code[offset : offset+int(size)]
+		offset += int(size)
+		if sectionId != 0 {
+			continue
+		}
+
+		nameLength, n, err := readLEB128(payload)
+		if err != nil {
+			return nil, fmt.Errorf("could not read name of custom section: %w", err)
+		}
+		if uint64(len(payload)-n) < nameLength {
+			return nil, fmt.Errorf("name of custom section is truncated")
+		}
+		name := string(payload[n : n+int(nameLength)])
+		sections[name] = append(sections[name], payload[n+int(nameLength):]...)
+	}
+	return sections, nil
+}
+
+// readLEB128 reads an unsigned 32 bit LEB128 integer, returning the value and the number of bytes read.
+func readLEB128(data []byte) (uint64, int, error) {
+	var value uint64
+	for i := 0; i < len(data) && i < 5; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid leb128 integer")
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// DefaultStorageStatsBatchSize is the number of contracts whose stats are rebuilt per transaction
+const DefaultStorageStatsBatchSize = 100
+
+// RebuildContractStorageStats recomputes contract_storage_stats from contract_data, batchSize contracts per
+// transaction, e.g. for the contracts indexed before it was added. It can run alongside ingestion, which waits for
+// each batch before applying its changes. Sizes are read from entry_size_bytes, so backfill-entry-sizes should run
+// first.
+func RebuildContractStorageStats(config Config, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var afterContractId string
+	var total int
+	for {
+		contractIds, err := session.StoredContractIdsAfter(ctx, afterContractId, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(contractIds) == 0 {
+			break
+		}
+		if err := session.RebuildContractStorageStats(ctx, contractIds); err != nil {
+			return err
+		}
+		afterContractId = contractIds[len(contractIds)-1]
+		total += len(contractIds)
+		Logger.Infof("Rebuilt the storage stats of %d contracts, up to %s", total, afterContractId)
+	}
+	Logger.Infof("Rebuilt the storage stats of %d contracts", total)
+	return nil
+}
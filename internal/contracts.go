@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+)
+
+// DefaultContractBatchSize is the number of contract instance entries read per batch
+const DefaultContractBatchSize = 1000
+
+// contractInstanceOutputs decodes the stored instance entries into the executable of their contract, the same way
+// as ingestion does. The contracts are recorded as of the last change of their instance entry.
+func contractInstanceOutputs(entries []db.StoredContractData, passphrase string) ([]contract.ContractInstanceOutput, error) {
+	outputs := make([]contract.ContractInstanceOutput, 0, len(entries))
+	for _, entry := range entries {
+		ledgerEntry, err := storedContractDataEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		output, ok, err := contract.ContractInstanceFromEntry(ledgerEntry, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("could not read contract instance %s: %w", entry.KeyHash, err)
+		}
+		if !ok {
+			continue
+		}
+		output.LedgerSequence = entry.LedgerSequence
+		output.ClosedAt = entry.ClosedAt
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// BackfillContracts records the executable of the contracts created before the first indexed ledger, e.g. the ones
+// indexed before contracts was added, from the persistent instance entries of contract_data, batchSize entries per
+// batch. It can run alongside ingestion, contracts already recorded are left as is.
+func BackfillContracts(config Config, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var afterKeyHash string
+	var total int
+	for {
+		entries, err := session.StoredContractInstancesAfter(ctx, afterKeyHash, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+		outputs, err := contractInstanceOutputs(entries, config.StellarCoreConfig.NetworkPassphrase)
+		if err != nil {
+			return err
+		}
+		inserted, err := session.InsertMissingContracts(ctx, outputs)
+		if err != nil {
+			return err
+		}
+		afterKeyHash = entries[len(entries)-1].KeyHash
+		total += inserted
+		Logger.Infof("Recorded %d contracts, up to key hash %s", total, afterKeyHash)
+	}
+	Logger.Infof("Recorded %d contracts", total)
+	return nil
+}
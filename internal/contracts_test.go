@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractInstanceOutputs(t *testing.T) {
+	key, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance})
+	require.NoError(t, err)
+	wasmHash := xdr.Hash{1}
+	val, err := xdr.MarshalBase64(xdr.ScVal{
+		Type: xdr.ScValTypeScvContractInstance,
+		Instance: &xdr.ScContractInstance{
+			Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash},
+		},
+	})
+	require.NoError(t, err)
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+
+	outputs, err := contractInstanceOutputs([]db.StoredContractData{{
+		KeyHash:        "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca",
+		ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		Durability:     "persistent",
+		LedgerSequence: 100,
+		ClosedAt:       closedAt,
+		Key:            []byte(key),
+		Val:            []byte(val),
+	}}, "unit test")
+	require.NoError(t, err)
+	// Backfilled contracts have no creation, their deployer is unknown
+	assert.Equal(t, []contract.ContractInstanceOutput{{
+		ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		ExecutableType: "wasm",
+		WasmHash:       wasmHash.HexString(),
+		LedgerSequence: 100,
+		ClosedAt:       closedAt,
+	}}, outputs)
+
+	_, err = contractInstanceOutputs([]db.StoredContractData{{KeyHash: "01", Key: []byte("not xdr")}}, "unit test")
+	assert.Error(t, err)
+}
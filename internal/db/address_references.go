@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type AddressReferenceDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type addressReferenceDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewAddressReferenceDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) AddressReferenceDBOperator {
+	return &addressReferenceDBOperator{session: dbSession, table: "address_references", dataset: "address_references", metricRecorder: metricRecorder}
+}
+
+func (i *addressReferenceDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+
+	// Only the latest version of an entry in the batch is referenced
+	latest := map[string]contract.ContractDataOutput{}
+	var keyHashOrder []string
+	for _, rawRecord := range rawRecords {
+		contractData, ok := rawRecord.(contract.ContractDataOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
+		}
+		existing, found := latest[contractData.LedgerKeyHash]
+		if !found {
+			keyHashOrder = append(keyHashOrder, contractData.LedgerKeyHash)
+		}
+		if !found || contractData.LedgerSequence >= existing.LedgerSequence {
+			latest[contractData.LedgerKeyHash] = contractData
+		}
+	}
+	if len(keyHashOrder) == 0 {
+		return nil
+	}
+
+	var changedKeyHash, changedLedgerSequence []interface{}
+	var evictedKeyHash, evictedLedgerSequence, evictedClosedAt, evictedFlag []interface{}
+	var address, contractId, ledgerKeyHash, location, ledgerSequence, closedAt, evicted []interface{}
+	for _, keyHash := range keyHashOrder {
+		contractData := latest[keyHash]
+		// Evicted persistent entries can be restored from the archive. Evictions only carry the key of the entry,
+		// so its references are kept and flagged instead of replaced.
+		if contractData.Evicted && contractData.Deleted && contractData.ContractDurability == "ContractDataDurabilityPersistent" {
+			evictedKeyHash = append(evictedKeyHash, keyHash)
+			evictedLedgerSequence = append(evictedLedgerSequence, contractData.LedgerSequence)
+			evictedClosedAt = append(evictedClosedAt, contractData.ClosedAt)
+			evictedFlag = append(evictedFlag, true)
+			continue
+		}
+		changedKeyHash = append(changedKeyHash, keyHash)
+		changedLedgerSequence = append(changedLedgerSequence, contractData.LedgerSequence)
+		if contractData.Deleted {
+			continue
+		}
+		for _, reference := range []struct {
+			location  string
+			addresses []string
+		}{
+			{"key", contractData.KeyAddresses},
+			{"val", contractData.ValAddresses},
+		} {
+			for _, referencedAddress := range reference.addresses {
+				address = append(address, referencedAddress)
+				contractId = append(contractId, contractData.ContractId)
+				ledgerKeyHash = append(ledgerKeyHash, keyHash)
+				location = append(location, reference.location)
+				ledgerSequence = append(ledgerSequence, contractData.LedgerSequence)
+				closedAt = append(closedAt, contractData.ClosedAt)
+				evicted = append(evicted, contractData.Evicted)
+			}
+		}
+	}
+
+	// The references of a changed entry are replaced, unless they were recorded at a later ledger
+	deleteFields := []UpsertField{
+		{"key_hash", "text", changedKeyHash},
+		{"ledger_sequence", "int", changedLedgerSequence},
+	}
+	deleteConditions := []UpsertCondition{
+		{"ledger_sequence", OpGE},
+	}
+	if err := i.flagEvicted(ctx, evictedKeyHash, evictedLedgerSequence, evictedClosedAt, evictedFlag); err != nil {
+		return err
+	}
+	if len(changedKeyHash) == 0 {
+		return nil
+	}
+	if _, err := i.session.DeleteRows(ctx, i.table, "key_hash", deleteFields, deleteConditions); err != nil {
+		return err
+	}
+	if len(address) == 0 {
+		return nil
+	}
+
+	upsertFields := []UpsertField{
+		{"address", "text", address},
+		{"contract_id", "text", contractId},
+		{"key_hash", "text", ledgerKeyHash},
+		{"location", "text", location},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"evicted", "boolean", evicted},
+	}
+	upsertConditions := []UpsertCondition{
+		{"ledger_sequence", OpGE},
+	}
+	// Replaying an older ledger does not reference addresses of an entry already indexed at a later ledger
+	filter := "NOT EXISTS (SELECT 1 FROM contract_data WHERE contract_data.key_hash = r.key_hash AND contract_data.ledger_sequence > r.ledger_sequence)" +
+		" AND NOT EXISTS (SELECT 1 FROM address_references later WHERE later.key_hash = r.key_hash AND later.ledger_sequence > r.ledger_sequence)"
+	rowsAffected, err := i.session.upsertRows(ctx, i.table, "address, key_hash, location", upsertFields, upsertConditions, filter)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+// flagEvicted flags the references of the evicted entries, unless they were recorded at a later ledger
+func (i *addressReferenceDBOperator) flagEvicted(ctx context.Context, keyHash, ledgerSequence, closedAt, evicted []interface{}) error {
+	if len(keyHash) == 0 {
+		return nil
+	}
+	evictFields := []UpsertField{
+		{"key_hash", "text", keyHash},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"evicted", "boolean", evicted},
+	}
+	condition := "data_source.ledger_sequence > " + i.table + ".ledger_sequence"
+	_, err := i.session.EnrichExistingRows(ctx, i.table, "key_hash", evictFields, condition)
+	return err
+}
+
+// StoredContractData is the stored key and value of an indexed contract data entry, as base64 encoded XDR
+type StoredContractData struct {
+	KeyHash        string    `db:"key_hash"`
+	ContractId     string    `db:"contract_id"`
+	Durability     string    `db:"durability"`
+	LedgerSequence uint32    `db:"ledger_sequence"`
+	ClosedAt       time.Time `db:"closed_at"`
+	Key            []byte    `db:"key"`
+	Val            []byte    `db:"val"`
+	Evicted        bool      `db:"evicted"`
+}
+
+// StoredContractDataAfter returns up to limit existing contract data entries whose key hash follows afterKeyHash,
+// ordered by key hash, so that every entry can be read in batches. Evicted entries keep their last value and are
+// included.
+func (q *DBSession) StoredContractDataAfter(ctx context.Context, afterKeyHash string, limit int) ([]StoredContractData, error) {
+	var entries []StoredContractData
+	query := `
+		SELECT key_hash, contract_id, ledger_sequence, closed_at, key, val, evicted
+		FROM contract_data
+		WHERE key_hash > ? AND (NOT deleted OR evicted)
+		ORDER BY key_hash
+		LIMIT ?`
+	if err := q.session.SelectRaw(ctx, &entries, query, afterKeyHash, limit); err != nil {
+		return nil, fmt.Errorf("failed to read contract data after %s: %w", afterKeyHash, err)
+	}
+	return entries, nil
+}
+
+func (i *addressReferenceDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *addressReferenceDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *addressReferenceDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *addressReferenceDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressReferences(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	dbOperator := NewAddressReferenceDBOperator(*session, metricRecorder)
+
+	account := "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+	contractAddress := "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4"
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	entry := func(ledgerSequence uint32, deleted bool, valAddresses ...string) contract.ContractDataOutput {
+		return contract.ContractDataOutput{
+			ContractId:     contractAddress,
+			LedgerSequence: ledgerSequence,
+			LedgerKeyHash:  keyHash,
+			ClosedAt:       closedAt,
+			Deleted:        deleted,
+			KeyAddresses:   []string{account},
+			ValAddresses:   valAddresses,
+		}
+	}
+	type reference struct {
+		Address        string `db:"address"`
+		Location       string `db:"location"`
+		LedgerSequence uint32 `db:"ledger_sequence"`
+		Evicted        bool   `db:"evicted"`
+	}
+	references := func() []reference {
+		var rows []reference
+		require.NoError(t, session.session.SelectRaw(ctx, &rows,
+			`SELECT address, location, ledger_sequence, evicted FROM address_references WHERE key_hash = ? ORDER BY address, location`, keyHash))
+		return rows
+	}
+
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{entry(100, false, account, contractAddress)}))
+	assert.Equal(t, []reference{
+		{contractAddress, "val", 100, false},
+		{account, "key", 100, false},
+		{account, "val", 100, false},
+	}, references())
+
+	// A later version replaces the references of the entry
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{entry(110, false, contractAddress)}))
+	assert.Equal(t, []reference{
+		{contractAddress, "val", 110, false},
+		{account, "key", 110, false},
+	}, references())
+
+	// Replaying an older version does not bring its references back
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{entry(100, false, account)}))
+	assert.Equal(t, []reference{
+		{contractAddress, "val", 110, false},
+		{account, "key", 110, false},
+	}, references())
+
+	// Evicting the entry keeps its references flagged, restoring it replaces them with live ones
+	evicted := entry(115, true)
+	evicted.Evicted = true
+	evicted.ContractDurability = "ContractDataDurabilityPersistent"
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{evicted}))
+	assert.Equal(t, []reference{
+		{contractAddress, "val", 115, true},
+		{account, "key", 115, true},
+	}, references())
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{entry(118, false, contractAddress)}))
+	assert.Equal(t, []reference{
+		{contractAddress, "val", 118, false},
+		{account, "key", 118, false},
+	}, references())
+
+	// Removing the entry removes its references
+	require.NoError(t, dbOperator.Upsert(ctx, []interface{}{entry(120, true)}))
+	assert.Empty(t, references())
+}
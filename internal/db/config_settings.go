@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ConfigSettingDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type configSettingDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewConfigSettingDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ConfigSettingDBOperator {
+	return &configSettingDBOperator{session: dbSession, table: "config_settings", dataset: "config_settings", metricRecorder: metricRecorder}
+}
+
+func (i *configSettingDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var configSettingId, ledgerSequence, closedAt, value, valueXDR []interface{}
+
+	for _, rawRecord := range rawRecords {
+		configSetting, ok := rawRecord.(contract.ConfigSettingOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ConfigSettingOutput")
+		}
+		configSettingId = append(configSettingId, configSetting.ConfigSettingId)
+		ledgerSequence = append(ledgerSequence, configSetting.LedgerSequence)
+		closedAt = append(closedAt, configSetting.ClosedAt)
+		value = append(value, configSetting.Value)
+		valueXDR = append(valueXDR, configSetting.ValueXDR)
+	}
+
+	upsertFields := []UpsertField{
+		{"config_setting_id", "text", configSettingId},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"value", "jsonb", value},
+		{"value_xdr", "text", valueXDR},
+	}
+	// Every version is kept, replaying a ledger rewrites the same rows
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "config_setting_id, ledger_sequence", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+// IndexedConfigSettingIds returns the ids of the config settings with at least one indexed version
+func (q *DBSession) IndexedConfigSettingIds(ctx context.Context) ([]string, error) {
+	var ids []string
+	if err := q.session.SelectRaw(ctx, &ids, `SELECT DISTINCT config_setting_id FROM config_settings`); err != nil {
+		return nil, fmt.Errorf("failed to read indexed config settings: %w", err)
+	}
+	return ids, nil
+}
+
+func (i *configSettingDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *configSettingDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *configSettingDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *configSettingDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractCodeDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractCodeDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+	// removeDeleted deletes the rows of removed entries instead of keeping them as tombstones
+	removeDeleted bool
+}
+
+func NewContractCodeDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder, removeDeleted bool) ContractCodeDBOperator {
+	return &contractCodeDBOperator{session: dbSession, table: "contract_code", dataset: "contract_code", metricRecorder: metricRecorder, removeDeleted: removeDeleted}
+}
+
+func (i *contractCodeDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var codeHash, keyHash, ledgerSequence, lastModifiedLedger, closedAt []interface{}
+	var nInstructions, nFunctions, nGlobals, nTableEntries, nTypes, nDataSegments, nElemSegments, nImports, nExports, nDataSegmentBytes []interface{}
+	var notDeleted, notDeletedAtLedger []interface{}
+	var removedCodeHash, removedLedgerSequence []interface{}
+	var deletedCodeHash, deletedLedgerSequence, deletedClosedAt, deletedEvicted, deleted []interface{}
+
+	for _, rawRecord := range rawRecords {
+		contractCode, ok := rawRecord.(contract.ContractCodeOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractCodeOutput")
+		}
+		if contractCode.Deleted {
+			// Evicted entries can still be restored and are kept in both modes
+			if i.removeDeleted && !contractCode.Evicted {
+				removedCodeHash = append(removedCodeHash, contractCode.ContractCodeHash)
+				removedLedgerSequence = append(removedLedgerSequence, contractCode.LedgerSequence)
+				continue
+			}
+			deletedCodeHash = append(deletedCodeHash, contractCode.ContractCodeHash)
+			deletedLedgerSequence = append(deletedLedgerSequence, contractCode.LedgerSequence)
+			deletedClosedAt = append(deletedClosedAt, contractCode.ClosedAt)
+			deletedEvicted = append(deletedEvicted, contractCode.Evicted)
+			deleted = append(deleted, true)
+			continue
+		}
+		notDeleted = append(notDeleted, false)
+		notDeletedAtLedger = append(notDeletedAtLedger, nil)
+		codeHash = append(codeHash, contractCode.ContractCodeHash)
+		keyHash = append(keyHash, contractCode.LedgerKeyHash)
+		ledgerSequence = append(ledgerSequence, contractCode.LedgerSequence)
+		lastModifiedLedger = append(lastModifiedLedger, contractCode.LastModifiedLedger)
+		closedAt = append(closedAt, contractCode.ClosedAt)
+		nInstructions = append(nInstructions, contractCode.NInstructions)
+		nFunctions = append(nFunctions, contractCode.NFunctions)
+		nGlobals = append(nGlobals, contractCode.NGlobals)
+		nTableEntries = append(nTableEntries, contractCode.NTableEntries)
+		nTypes = append(nTypes, contractCode.NTypes)
+		nDataSegments = append(nDataSegments, contractCode.NDataSegments)
+		nElemSegments = append(nElemSegments, contractCode.NElemSegments)
+		nImports = append(nImports, contractCode.NImports)
+		nExports = append(nExports, contractCode.NExports)
+		nDataSegmentBytes = append(nDataSegmentBytes, contractCode.NDataSegmentBytes)
+	}
+
+	upsertFields := []UpsertField{
+		{"code_hash", "text", codeHash},
+		{"key_hash", "text", keyHash},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"last_modified_ledger", "int", lastModifiedLedger},
+		{"closed_at", "timestamp", closedAt},
+		{"n_instructions", "int", nInstructions},
+		{"n_functions", "int", nFunctions},
+		{"n_globals", "int", nGlobals},
+		{"n_table_entries", "int", nTableEntries},
+		{"n_types", "int", nTypes},
+		{"n_data_segments", "int", nDataSegments},
+		{"n_elem_segments", "int", nElemSegments},
+		{"n_imports", "int", nImports},
+		{"n_exports", "int", nExports},
+		{"n_data_segment_bytes", "int", nDataSegmentBytes},
+		{"deleted", "boolean", notDeleted},
+		{"deleted_at_ledger", "int", notDeletedAtLedger},
+		{"evicted", "boolean", notDeleted},
+	}
+	if len(codeHash) > 0 {
+		// Uploading or restoring a removed entry makes it live again, so the tombstone fields are reset
+		if err := resetRemovedTTLs(ctx, i.session, i.table, "code_hash", codeHash, ledgerSequence); err != nil {
+			return err
+		}
+		upsertConditions := []UpsertCondition{
+			{"ledger_sequence", OpGT},
+		}
+		rowsAffected, err := i.session.UpsertRows(ctx, i.table, "code_hash", upsertFields, upsertConditions)
+		i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+		if err != nil {
+			return err
+		}
+		if _, err := applyBufferedTTLs(ctx, i.session, i.table, keyHash); err != nil {
+			return err
+		}
+	}
+
+	if len(deletedCodeHash) > 0 {
+		// Removals only carry the key of an evicted entry, so the row keeps its last cost inputs. There is nothing
+		// to write for an entry that is not indexed yet, and a backfill of an older ledger must not remove an entry
+		// that was written again later on.
+		tombstoneFields := []UpsertField{
+			{"code_hash", "text", deletedCodeHash},
+			{"ledger_sequence", "int", deletedLedgerSequence},
+			{"closed_at", "timestamp", deletedClosedAt},
+			{"deleted", "boolean", deleted},
+			{"deleted_at_ledger", "int", deletedLedgerSequence},
+			{"evicted", "boolean", deletedEvicted},
+		}
+		condition := fmt.Sprintf("data_source.ledger_sequence > %s.ledger_sequence", i.table)
+		rowsAffected, err := i.session.EnrichExistingRows(ctx, i.table, "code_hash", tombstoneFields, condition)
+		i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(removedCodeHash) > 0 {
+		deleteFields := []UpsertField{
+			{"code_hash", "text", removedCodeHash},
+			{"ledger_sequence", "int", removedLedgerSequence},
+		}
+		// Backfills of older ledgers must not delete an entry that was uploaded again later on
+		deleteConditions := []UpsertCondition{
+			{"ledger_sequence", OpGE},
+		}
+		if _, err := i.session.DeleteRows(ctx, i.table, "code_hash", deleteFields, deleteConditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *contractCodeDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractCodeDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractCodeDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractCodeDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
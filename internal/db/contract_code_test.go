@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractCodeTombstone(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	contractCodeOperator := NewContractCodeDBOperator(*session, metricRecorder, true)
+
+	codeHash := "0101010101010101010101010101010101010101010101010101010101010101"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	code := func(ledgerSequence uint32, nInstructions uint32, evicted bool) []interface{} {
+		return []interface{}{contract.ContractCodeOutput{
+			ContractCodeHash: codeHash,
+			LedgerSequence:   ledgerSequence,
+			LedgerKeyHash:    "b23a7523df5007be33f86b6eeba74debadbb3743806af27f5c1cdf9d3b6e6cfe",
+			ClosedAt:         closedAt,
+			NInstructions:    nInstructions,
+			Deleted:          evicted,
+			Evicted:          evicted,
+		}}
+	}
+	type row struct {
+		LedgerSequence int64 `db:"ledger_sequence"`
+		NInstructions  int64 `db:"n_instructions"`
+		Deleted        bool  `db:"deleted"`
+		Evicted        bool  `db:"evicted"`
+	}
+	readRows := func() []row {
+		var rows []row
+		require.NoError(t, session.session.SelectRaw(ctx, &rows,
+			`SELECT ledger_sequence, n_instructions, deleted, evicted FROM contract_code WHERE code_hash = ?`, codeHash))
+		return rows
+	}
+
+	// Evicted code keeps its cost inputs, even when removed entries are not kept, and an older version is ignored
+	require.NoError(t, contractCodeOperator.Upsert(ctx, code(100, 50, false)))
+	require.NoError(t, contractCodeOperator.Upsert(ctx, code(200, 0, true)))
+	require.NoError(t, contractCodeOperator.Upsert(ctx, code(150, 50, false)))
+	assert.Equal(t, []row{{LedgerSequence: 200, NInstructions: 50, Deleted: true, Evicted: true}}, readRows())
+
+	// Restoring the code makes it live again
+	require.NoError(t, contractCodeOperator.Upsert(ctx, code(300, 50, false)))
+	assert.Equal(t, []row{{LedgerSequence: 300, NInstructions: 50}}, readRows())
+}
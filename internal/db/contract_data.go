@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 
+	"github.com/lib/pq"
 	"github.com/stellar/go-stellar-sdk/support/db"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
@@ -15,7 +15,8 @@ type ContractDataDBOperator interface {
 	Upsert(ctx context.Context, data any) error
 	TableName() string
 	Session() db.SessionInterface
-	GetMaxLedgerSequence(ctx context.Context) (uint32, error)
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
 }
 
 type contractDataDBOperator struct {
@@ -23,73 +24,391 @@ type contractDataDBOperator struct {
 	table          string
 	dataset        string
 	metricRecorder utils.MetricRecorder
+	// removeDeleted deletes the rows of removed entries instead of keeping them as tombstones
+	removeDeleted bool
 }
 
-func NewContractDataDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractDataDBOperator {
-	return &contractDataDBOperator{session: dbSession, table: "contract_data", dataset: "contract_data", metricRecorder: metricRecorder}
+func NewContractDataDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder, removeDeleted bool) ContractDataDBOperator {
+	return &contractDataDBOperator{session: dbSession, table: "contract_data", dataset: "contract_data", metricRecorder: metricRecorder, removeDeleted: removeDeleted}
 }
 
-func ExtractSymbol(keyDecoded map[string]string) string {
-	KeyDecodedBytes, _ := json.Marshal(keyDecoded)
-	var obj struct {
-		Type  string `json:"type"`
-		Value string `json:"value"`
+// keyArgsJSON encodes the arguments of an enum variant key, or returns nil for keys that are not enum variants.
+func keyArgsJSON(contractData contract.ContractDataOutput) (interface{}, interface{}, error) {
+	if contractData.KeySymbol == "" {
+		return nil, nil, nil
 	}
-	if err := json.Unmarshal(KeyDecodedBytes, &obj); err != nil {
-		panic(err)
+	args := contractData.KeyArgs
+	if args == nil {
+		args = []interface{}{}
 	}
-	fields := strings.Fields(obj.Value)
-	symbol := ""
-	if len(fields) != 0 && obj.Type == "Vec" {
-		symbol = strings.TrimLeft(fields[0], "[")
-		symbol = strings.TrimRight(symbol, "]")
+	argTypes := contractData.KeyArgTypes
+	if argTypes == nil {
+		argTypes = []string{}
 	}
-	return symbol
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not encode arguments of key %s: %w", contractData.LedgerKeyHash, err)
+	}
+	argTypesJSON, err := json.Marshal(argTypes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not encode argument types of key %s: %w", contractData.LedgerKeyHash, err)
+	}
+	return string(argsJSON), string(argTypesJSON), nil
 }
 
-func (i *contractDataDBOperator) Upsert(ctx context.Context, data any) error {
-	rawRecords := data.([]interface{})
-	var contractId, ledgerSequence, ledgerKeyHash, contractDurability, keySymbol, closedAt, key, val []interface{}
+// jsonOrNull returns value as a jsonb argument, or NULL for values that could not be encoded.
+func jsonOrNull(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// removedContractDataTable keeps the key and the ledger of the entries whose rows were deleted, see removeDeleted
+const removedContractDataTable = "removed_contract_data"
 
+// removedLedgers returns the ledger the given entries were last removed at, for the entries whose rows were deleted.
+func (i *contractDataDBOperator) removedLedgers(ctx context.Context, keyHashes []string) (map[string]uint32, error) {
+	var rows []struct {
+		KeyHash        string `db:"key_hash"`
+		LedgerSequence uint32 `db:"ledger_sequence"`
+	}
+	query := "SELECT key_hash, ledger_sequence FROM " + removedContractDataTable + " WHERE key_hash = ANY(?::text[])"
+	if err := i.session.session.SelectRaw(ctx, &rows, query, pq.Array(keyHashes)); err != nil {
+		return nil, fmt.Errorf("failed to read removed contract data entries: %w", err)
+	}
+	removedAt := make(map[string]uint32, len(rows))
+	for _, row := range rows {
+		removedAt[row.KeyHash] = row.LedgerSequence
+	}
+	return removedAt, nil
+}
+
+// changeSource returns the transaction hash, application order and operation index of a change as column values,
+// NULL for changes made by the ledger itself.
+func changeSource(transactionHash string, applicationOrder uint32, operationIndex *uint32) (interface{}, interface{}, interface{}) {
+	if transactionHash == "" {
+		return nil, nil, nil
+	}
+	if operationIndex == nil {
+		return transactionHash, applicationOrder, nil
+	}
+	return transactionHash, applicationOrder, *operationIndex
+}
+
+// contractDataBatch holds the columns of a batch of contract data changes, split by how each change is written
+type contractDataBatch struct {
+	contractId, ledgerSequence, ledgerKeyHash, contractDurability, keySymbol, keyArgs, keyArgTypes, closedAt []interface{}
+	key, val, keyJSON, valJSON, deleted, deletedAtLedger, evicted                                            []interface{}
+	transactionHash, applicationOrder, operationIndex, entrySizeBytes                                        []interface{}
+	// removedKeyHash and removedLedgerSequence are the entries whose rows are deleted, see removeDeleted
+	removedKeyHash, removedLedgerSequence []interface{}
+	// evicted* are the entries whose rows are flagged as evicted, see addEvicted
+	evictedKeyHash, evictedLedgerSequence, evictedClosedAt, evictedPersistent, evictedDeleted, evictedChangeSource []interface{}
+
+	// stats are the changes of the batch as counted in contract_storage_stats
+	stats storageStatsChanges
+}
+
+// newContractDataBatch splits the changes of rawRecords into rows written with their value, removed rows and evicted
+// rows. Changes at or before the ledger their entry was last removed at, as given by removedAt, are skipped.
+func newContractDataBatch(rawRecords []interface{}, removedAt map[string]uint32, removeDeleted bool) (*contractDataBatch, error) {
+	batch := &contractDataBatch{stats: newStorageStatsChanges()}
 	for _, rawRecord := range rawRecords {
 		contractData, ok := rawRecord.(contract.ContractDataOutput)
 		if !ok {
-			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
+			return nil, fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
 		}
-		keyBytes := []byte(contractData.Key["value"])
-		valBytes := []byte(contractData.Val["value"])
+		if removedLedger, ok := removedAt[contractData.LedgerKeyHash]; ok && contractData.LedgerSequence <= removedLedger {
+			continue
+		}
+		batch.stats.changed(contractData.LedgerKeyHash, contractData.LedgerSequence)
+		persistent := contractData.ContractDurability == "ContractDataDurabilityPersistent"
+		// Evicted persistent entries can be restored from the archive, evicted temporary entries are gone for good
+		// and are removed like any other entry when removed entries are not kept.
+		if contractData.Evicted && (persistent || !removeDeleted) {
+			batch.addEvicted(contractData, persistent)
+			continue
+		}
+		if contractData.Deleted && removeDeleted {
+			batch.addRemoved(contractData)
+			continue
+		}
+		if err := batch.addWritten(contractData, persistent); err != nil {
+			return nil, err
+		}
+	}
+	return batch, nil
+}
 
-		symbol := ExtractSymbol(contractData.KeyDecoded)
+// addWritten adds a row written with the value of the entry
+func (b *contractDataBatch) addWritten(contractData contract.ContractDataOutput, persistent bool) error {
+	entryKeyArgs, entryKeyArgTypes, err := keyArgsJSON(contractData)
+	if err != nil {
+		return err
+	}
+
+	if persistent {
+		contractData.ContractDurability = "persistent"
+	} else {
+		contractData.ContractDurability = "temporary"
+	}
+
+	// Removed entries are kept as tombstones holding their last value. An entry created again
+	// under the same key is live again, so the tombstone fields are reset.
+	var entryDeletedAtLedger interface{}
+	if contractData.Deleted {
+		entryDeletedAtLedger = contractData.LedgerSequence
+	}
+	b.contractId = append(b.contractId, contractData.ContractId)
+	b.ledgerSequence = append(b.ledgerSequence, contractData.LedgerSequence)
+	b.ledgerKeyHash = append(b.ledgerKeyHash, contractData.LedgerKeyHash)
+	b.contractDurability = append(b.contractDurability, contractData.ContractDurability)
+	b.keySymbol = append(b.keySymbol, contractData.KeySymbol)
+	b.keyArgs = append(b.keyArgs, entryKeyArgs)
+	b.keyArgTypes = append(b.keyArgTypes, entryKeyArgTypes)
+	b.closedAt = append(b.closedAt, contractData.ClosedAt)
+	b.key = append(b.key, []byte(contractData.Key["value"]))
+	b.val = append(b.val, []byte(contractData.Val["value"]))
+	b.keyJSON = append(b.keyJSON, jsonOrNull(contractData.KeyJSON))
+	b.valJSON = append(b.valJSON, jsonOrNull(contractData.ValJSON))
+	b.deleted = append(b.deleted, contractData.Deleted)
+	b.evicted = append(b.evicted, false)
+	b.deletedAtLedger = append(b.deletedAtLedger, entryDeletedAtLedger)
+	entryTransactionHash, entryApplicationOrder, entryOperationIndex := changeSource(contractData.TransactionHash, contractData.ApplicationOrder, contractData.OperationIndex)
+	b.transactionHash = append(b.transactionHash, entryTransactionHash)
+	b.applicationOrder = append(b.applicationOrder, entryApplicationOrder)
+	b.operationIndex = append(b.operationIndex, entryOperationIndex)
+	b.entrySizeBytes = append(b.entrySizeBytes, contractData.EntrySizeBytes)
+	b.stats.written[contractData.LedgerKeyHash] = storageStatsEntry{
+		KeyHash:        contractData.LedgerKeyHash,
+		ContractId:     contractData.ContractId,
+		Durability:     contractData.ContractDurability,
+		LedgerSequence: contractData.LedgerSequence,
+		Deleted:        contractData.Deleted,
+		SizeBytes:      int64(contractData.EntrySizeBytes),
+	}
+	return nil
+}
+
+// liveKeyHashes returns the key hashes and ledgers of the written rows of the batch that are not tombstones
+func (b *contractDataBatch) liveKeyHashes() ([]interface{}, []interface{}) {
+	var keyHash, ledgerSequence []interface{}
+	for index, deleted := range b.deleted {
+		if !deleted.(bool) {
+			keyHash = append(keyHash, b.ledgerKeyHash[index])
+			ledgerSequence = append(ledgerSequence, b.ledgerSequence[index])
+		}
+	}
+	return keyHash, ledgerSequence
+}
+
+// addRemoved adds a row deleted when removed entries are not kept
+func (b *contractDataBatch) addRemoved(contractData contract.ContractDataOutput) {
+	b.removedKeyHash = append(b.removedKeyHash, contractData.LedgerKeyHash)
+	b.removedLedgerSequence = append(b.removedLedgerSequence, contractData.LedgerSequence)
+	b.stats.removed[contractData.LedgerKeyHash] = contractData.LedgerSequence
+}
+
+// addEvicted adds an evicted entry. Evictions only carry the key of the entry, so the row keeps its last value and
+// is flagged instead.
+func (b *contractDataBatch) addEvicted(contractData contract.ContractDataOutput, persistent bool) {
+	b.evictedKeyHash = append(b.evictedKeyHash, contractData.LedgerKeyHash)
+	b.evictedLedgerSequence = append(b.evictedLedgerSequence, contractData.LedgerSequence)
+	b.evictedClosedAt = append(b.evictedClosedAt, contractData.ClosedAt)
+	b.evictedPersistent = append(b.evictedPersistent, persistent)
+	b.evictedDeleted = append(b.evictedDeleted, true)
+	b.evictedChangeSource = append(b.evictedChangeSource, nil)
+	b.stats.evicted[contractData.LedgerKeyHash] = contractData.LedgerSequence
+}
+
+func (b *contractDataBatch) upsertFields() []UpsertField {
+	return []UpsertField{
+		{"contract_id", "text", b.contractId},
+		{"ledger_sequence", "int", b.ledgerSequence},
+		{"key_hash", "text", b.ledgerKeyHash},
+		{"durability", "text", b.contractDurability},
+		{"key_symbol", "text", b.keySymbol},
+		{"key_args", "jsonb", b.keyArgs},
+		{"key_arg_types", "jsonb", b.keyArgTypes},
+		{"key", "bytea", b.key},
+		{"val", "bytea", b.val},
+		{"key_json", "jsonb", b.keyJSON},
+		{"val_json", "jsonb", b.valJSON},
+		{"closed_at", "timestamp", b.closedAt},
+		{"deleted", "boolean", b.deleted},
+		{"deleted_at_ledger", "int", b.deletedAtLedger},
+		{"evicted", "boolean", b.evicted},
+		{"transaction_hash", "text", b.transactionHash},
+		{"application_order", "int", b.applicationOrder},
+		{"operation_index", "int", b.operationIndex},
+		{"entry_size_bytes", "int", b.entrySizeBytes},
+	}
+}
 
-		if contractData.ContractDurability == "ContractDataDurabilityPersistent" {
-			contractData.ContractDurability = "persistent"
-		} else {
-			contractData.ContractDurability = "temporary"
+// existingKeyHashes returns the key hashes of the batch whose rows were not inserted by it
+func (b *contractDataBatch) existingKeyHashes(inserted map[string]bool) []interface{} {
+	var existingKeyHash []interface{}
+	for _, keyHash := range append(append(append([]interface{}{}, b.ledgerKeyHash...), b.removedKeyHash...), b.evictedKeyHash...) {
+		if !inserted[keyHash.(string)] {
+			existingKeyHash = append(existingKeyHash, keyHash)
 		}
-		contractId = append(contractId, contractData.ContractId)
-		ledgerSequence = append(ledgerSequence, contractData.LedgerSequence)
-		ledgerKeyHash = append(ledgerKeyHash, contractData.LedgerKeyHash)
-		contractDurability = append(contractDurability, contractData.ContractDurability)
-		keySymbol = append(keySymbol, symbol)
-		closedAt = append(closedAt, contractData.ClosedAt)
-		key = append(key, keyBytes)
-		val = append(val, valBytes)
-	}
-
-	upsertFields := []UpsertField{
-		{"contract_id", "text", contractId},
-		{"ledger_sequence", "int", ledgerSequence},
-		{"key_hash", "text", ledgerKeyHash},
-		{"durability", "text", contractDurability},
-		{"key_symbol", "text", keySymbol},
-		{"key", "bytea", key},
-		{"val", "bytea", val},
-		{"closed_at", "timestamp", closedAt},
 	}
+	return existingKeyHash
+}
+
+func (i *contractDataDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+
+	removedAt, err := i.batchRemovedLedgers(ctx, rawRecords)
+	if err != nil {
+		return err
+	}
+	batch, err := newContractDataBatch(rawRecords, removedAt, i.removeDeleted)
+	if err != nil {
+		return err
+	}
+
+	// contract_storage_stats is updated from the rows of the batch as they were before and after it. Entries without
+	// a row are inserted first, then the rows replaced or removed by the rest of the batch are read and locked.
+	inserted, err := i.insertNewRows(ctx, batch)
+	if err != nil {
+		return err
+	}
+	stats, err := startStorageStatsUpdate(ctx, i.session, batch.existingKeyHashes(inserted))
+	if err != nil {
+		return err
+	}
+
+	if batch.stats.appliedTTLs, err = i.upsertRows(ctx, batch); err != nil {
+		return err
+	}
+	if err := i.removeRows(ctx, batch); err != nil {
+		return err
+	}
+	if err := i.clearRemovals(ctx, batch); err != nil {
+		return err
+	}
+	if err := i.evictRows(ctx, batch); err != nil {
+		return err
+	}
+	return stats.finishContractData(ctx, i.session, batch.stats)
+}
+
+// batchRemovedLedgers returns the ledger the entries of rawRecords were last removed at, when removed entries are not
+// kept. Deleted rows leave no row to compare versions with, so backfills of ledgers before an entry was removed
+// must not write it again.
+func (i *contractDataDBOperator) batchRemovedLedgers(ctx context.Context, rawRecords []interface{}) (map[string]uint32, error) {
+	if !i.removeDeleted {
+		return map[string]uint32{}, nil
+	}
+	var keyHashes []string
+	for _, rawRecord := range rawRecords {
+		if contractData, ok := rawRecord.(contract.ContractDataOutput); ok {
+			keyHashes = append(keyHashes, contractData.LedgerKeyHash)
+		}
+	}
+	return i.removedLedgers(ctx, keyHashes)
+}
+
+// insertNewRows inserts the written rows of the batch whose entry has no row yet, and returns their key hashes
+func (i *contractDataDBOperator) insertNewRows(ctx context.Context, batch *contractDataBatch) (map[string]bool, error) {
+	inserted := map[string]bool{}
+	if len(batch.ledgerKeyHash) == 0 {
+		return inserted, nil
+	}
+	insertedKeyHash, err := i.session.InsertNewRows(ctx, i.table, "key_hash", batch.upsertFields())
+	i.metricRecorder.RecordUpsertCount(i.dataset, int64(len(insertedKeyHash)))
+	if err != nil {
+		return nil, err
+	}
+	for _, keyHash := range insertedKeyHash {
+		inserted[keyHash] = true
+	}
+	return inserted, nil
+}
+
+// upsertRows writes the rows of the batch over older versions of their entry, then applies the ttl entries buffered
+// for them and returns the applied live until ledgers by key hash
+func (i *contractDataDBOperator) upsertRows(ctx context.Context, batch *contractDataBatch) (map[string]int64, error) {
+	if len(batch.ledgerKeyHash) == 0 {
+		return map[string]int64{}, nil
+	}
+	liveKeyHash, liveLedgerSequence := batch.liveKeyHashes()
+	if err := resetRemovedTTLs(ctx, i.session, i.table, "key_hash", liveKeyHash, liveLedgerSequence); err != nil {
+		return nil, err
+	}
+	// The rows inserted by insertNewRows are left as is, they are at the same ledger as their version in the batch
 	upsertConditions := []UpsertCondition{
 		{"ledger_sequence", OpGT},
 	}
-	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "key_hash", upsertFields, upsertConditions)
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "key_hash", batch.upsertFields(), upsertConditions)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	if err != nil {
+		return nil, err
+	}
+	return applyBufferedTTLs(ctx, i.session, i.table, batch.ledgerKeyHash)
+}
+
+// removeRows deletes the rows of the removed entries of the batch and records their removal
+func (i *contractDataDBOperator) removeRows(ctx context.Context, batch *contractDataBatch) error {
+	if len(batch.removedKeyHash) == 0 {
+		return nil
+	}
+	deleteFields := []UpsertField{
+		{"key_hash", "text", batch.removedKeyHash},
+		{"ledger_sequence", "int", batch.removedLedgerSequence},
+	}
+	// Backfills of older ledgers must not delete an entry that was created again later on
+	deleteConditions := []UpsertCondition{
+		{"ledger_sequence", OpGE},
+	}
+	if _, err := i.session.DeleteRows(ctx, i.table, "key_hash", deleteFields, deleteConditions); err != nil {
+		return err
+	}
+	removedConditions := []UpsertCondition{
+		{"ledger_sequence", OpGT},
+	}
+	_, err := i.session.UpsertRows(ctx, removedContractDataTable, "key_hash", deleteFields, removedConditions)
+	return err
+}
+
+// clearRemovals forgets the removal of the entries created again by the batch, when removed entries are not kept
+func (i *contractDataDBOperator) clearRemovals(ctx context.Context, batch *contractDataBatch) error {
+	if !i.removeDeleted || len(batch.ledgerKeyHash) == 0 {
+		return nil
+	}
+	removalFields := []UpsertField{
+		{"key_hash", "text", batch.ledgerKeyHash},
+		{"ledger_sequence", "int", batch.ledgerSequence},
+	}
+	removalConditions := []UpsertCondition{
+		{"ledger_sequence", OpGT},
+	}
+	_, err := i.session.DeleteRows(ctx, removedContractDataTable, "key_hash", removalFields, removalConditions)
+	return err
+}
+
+// evictRows flags the rows of the evicted entries of the batch as deleted, and as evicted for persistent entries
+func (i *contractDataDBOperator) evictRows(ctx context.Context, batch *contractDataBatch) error {
+	if len(batch.evictedKeyHash) == 0 {
+		return nil
+	}
+	evictFields := []UpsertField{
+		{"key_hash", "text", batch.evictedKeyHash},
+		{"ledger_sequence", "int", batch.evictedLedgerSequence},
+		{"closed_at", "timestamp", batch.evictedClosedAt},
+		{"deleted", "boolean", batch.evictedDeleted},
+		{"deleted_at_ledger", "int", batch.evictedLedgerSequence},
+		{"evicted", "boolean", batch.evictedPersistent},
+		{"transaction_hash", "text", batch.evictedChangeSource},
+		{"application_order", "int", batch.evictedChangeSource},
+		{"operation_index", "int", batch.evictedChangeSource},
+	}
+	// There is no value to write for an entry that is not indexed yet, and a backfill of an older ledger
+	// must not evict an entry that was written again later on
+	condition := fmt.Sprintf("data_source.ledger_sequence > %s.ledger_sequence", i.table)
+	rowsAffected, err := i.session.EnrichExistingRows(ctx, i.table, "key_hash", evictFields, condition)
 	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
 	return err
 }
@@ -102,6 +421,10 @@ func (i *contractDataDBOperator) Session() db.SessionInterface {
 	return i.session.session
 }
 
-func (i *contractDataDBOperator) GetMaxLedgerSequence(ctx context.Context) (uint32, error) {
-	return i.session.GetMaxLedgerSequence(ctx, i.table)
+func (i *contractDataDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractDataDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
 }
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractDataHistoryDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractDataHistoryDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractDataHistoryDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractDataHistoryDBOperator {
+	return &contractDataHistoryDBOperator{session: dbSession, table: "contract_data_history", dataset: "contract_data_history", metricRecorder: metricRecorder}
+}
+
+// ChangeTypeName maps a LedgerEntryChangeType to created, updated, removed or restored.
+func ChangeTypeName(changeType uint32) string {
+	return strings.ToLower(strings.TrimPrefix(xdr.LedgerEntryChangeType(changeType).String(), "LedgerEntryChangeTypeLedgerEntry"))
+}
+
+func (i *contractDataHistoryDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var contractId, ledgerSequence, ledgerKeyHash, contractDurability, keySymbol, changeType, lastModifiedLedger, closedAt, key, val []interface{}
+	var transactionHash, applicationOrder, operationIndex []interface{}
+
+	for _, rawRecord := range rawRecords {
+		contractData, ok := rawRecord.(contract.ContractDataOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
+		}
+
+		if contractData.ContractDurability == "ContractDataDurabilityPersistent" {
+			contractData.ContractDurability = "persistent"
+		} else {
+			contractData.ContractDurability = "temporary"
+		}
+		contractId = append(contractId, contractData.ContractId)
+		ledgerSequence = append(ledgerSequence, contractData.LedgerSequence)
+		ledgerKeyHash = append(ledgerKeyHash, contractData.LedgerKeyHash)
+		contractDurability = append(contractDurability, contractData.ContractDurability)
+		keySymbol = append(keySymbol, contractData.KeySymbol)
+		changeType = append(changeType, ChangeTypeName(contractData.LedgerEntryChange))
+		lastModifiedLedger = append(lastModifiedLedger, contractData.LastModifiedLedger)
+		closedAt = append(closedAt, contractData.ClosedAt)
+		key = append(key, []byte(contractData.Key["value"]))
+		val = append(val, []byte(contractData.Val["value"]))
+		entryTransactionHash, entryApplicationOrder, entryOperationIndex := changeSource(contractData.TransactionHash, contractData.ApplicationOrder, contractData.OperationIndex)
+		transactionHash = append(transactionHash, entryTransactionHash)
+		applicationOrder = append(applicationOrder, entryApplicationOrder)
+		operationIndex = append(operationIndex, entryOperationIndex)
+	}
+
+	upsertFields := []UpsertField{
+		{"contract_id", "text", contractId},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"key_hash", "text", ledgerKeyHash},
+		{"durability", "text", contractDurability},
+		{"key_symbol", "text", keySymbol},
+		{"change_type", "text", changeType},
+		{"last_modified_ledger", "int", lastModifiedLedger},
+		{"key", "bytea", key},
+		{"val", "bytea", val},
+		{"closed_at", "timestamp", closedAt},
+		{"transaction_hash", "text", transactionHash},
+		{"application_order", "int", applicationOrder},
+		{"operation_index", "int", operationIndex},
+	}
+	// History rows are append-only, replaying a ledger rewrites the versions recorded for its changes
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "key_hash, ledger_sequence, application_order, operation_index", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractDataHistoryDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractDataHistoryDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractDataHistoryDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractDataHistoryDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeTypeName(t *testing.T) {
+	assert.Equal(t, "created", ChangeTypeName(uint32(xdr.LedgerEntryChangeTypeLedgerEntryCreated)))
+	assert.Equal(t, "updated", ChangeTypeName(uint32(xdr.LedgerEntryChangeTypeLedgerEntryUpdated)))
+	assert.Equal(t, "removed", ChangeTypeName(uint32(xdr.LedgerEntryChangeTypeLedgerEntryRemoved)))
+	assert.Equal(t, "restored", ChangeTypeName(uint32(xdr.LedgerEntryChangeTypeLedgerEntryRestored)))
+}
+
+func TestContractDataHistoryKeepsEveryChangeOfALedger(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	historyOperator := NewContractDataHistoryDBOperator(*session, metricRecorder)
+
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	version := func(transactionHash string, applicationOrder uint32, operationIndex *uint32, val string) contract.ContractDataOutput {
+		return contract.ContractDataOutput{
+			ContractId:         "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ContractDurability: "ContractDataDurabilityPersistent",
+			LedgerEntryChange:  uint32(xdr.LedgerEntryChangeTypeLedgerEntryUpdated),
+			LedgerSequence:     100,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC),
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": val},
+			TransactionHash:    transactionHash,
+			ApplicationOrder:   applicationOrder,
+			OperationIndex:     operationIndex,
+		}
+	}
+	operationIndex := uint32(0)
+	versions := []interface{}{
+		version("0101010101010101010101010101010101010101010101010101010101010101", 1, &operationIndex, "AAAAAQ=="),
+		version("0202020202020202020202020202020202020202020202020202020202020202", 2, &operationIndex, "AAAAAA=="),
+		// Evictions are made by the ledger itself, outside of any transaction
+		version("", 0, nil, "AAAAAA=="),
+	}
+
+	// Replaying the ledger rewrites the same rows, including the one with no transaction
+	require.NoError(t, historyOperator.Upsert(ctx, versions))
+	require.NoError(t, historyOperator.Upsert(ctx, versions))
+
+	var transactionHashes []*string
+	require.NoError(t, session.session.SelectRaw(ctx, &transactionHashes,
+		`SELECT transaction_hash FROM contract_data_history WHERE key_hash = ? AND ledger_sequence = 100
+		ORDER BY application_order DESC NULLS FIRST, operation_index DESC NULLS FIRST`, keyHash))
+	require.Len(t, transactionHashes, 3)
+	assert.Nil(t, transactionHashes[0])
+	assert.Equal(t, "0202020202020202020202020202020202020202020202020202020202020202", *transactionHashes[1])
+	assert.Equal(t, "0101010101010101010101010101010101010101010101010101010101010101", *transactionHashes[2])
+}
@@ -1,17 +1,139 @@
 package db
 
 import (
+	"context"
+	"math/big"
 	"testing"
+	"time"
 
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestExtractSymbol(t *testing.T) {
-	keyDecoded := map[string]string{
-		"type":  "Vec",
-		"value": "[XLM] some other data",
+func TestKeyArgsJSON(t *testing.T) {
+	keyArgs, keyArgTypes, err := keyArgsJSON(contract.ContractDataOutput{
+		KeySymbol:   "Allowance",
+		KeyArgs:     []interface{}{"GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF", new(big.Int).Lsh(big.NewInt(1), 100), uint32(7)},
+		KeyArgTypes: []string{"address", "i128", "u32"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `["GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF",1267650600228229401496703205376,7]`, keyArgs)
+	assert.Equal(t, `["address","i128","u32"]`, keyArgTypes)
+
+	// Unit variants have no arguments
+	keyArgs, keyArgTypes, err = keyArgsJSON(contract.ContractDataOutput{KeySymbol: "Admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, keyArgs)
+	assert.Equal(t, `[]`, keyArgTypes)
+
+	// Keys that are not enum variants have no arguments to index
+	keyArgs, keyArgTypes, err = keyArgsJSON(contract.ContractDataOutput{})
+	assert.NoError(t, err)
+	assert.Nil(t, keyArgs)
+	assert.Nil(t, keyArgTypes)
+}
+
+func TestChangeSource(t *testing.T) {
+	operationIndex := uint32(0)
+	transactionHash, applicationOrder, index := changeSource("0101010101010101010101010101010101010101010101010101010101010101", 3, &operationIndex)
+	assert.Equal(t, "0101010101010101010101010101010101010101010101010101010101010101", transactionHash)
+	assert.Equal(t, uint32(3), applicationOrder)
+	assert.Equal(t, uint32(0), index)
+
+	// Changes made by the transaction outside of its operations
+	_, _, index = changeSource("01", 1, nil)
+	assert.Nil(t, index)
+
+	// Changes made by the ledger itself, e.g. evictions
+	transactionHash, applicationOrder, index = changeSource("", 0, nil)
+	assert.Nil(t, transactionHash)
+	assert.Nil(t, applicationOrder)
+	assert.Nil(t, index)
+}
+
+func TestContractDataEviction(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	contractDataOperator := NewContractDataDBOperator(*session, metricRecorder, true)
+
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	entry := func(keyHash string, durability string, ledgerSequence uint32, val string, evicted bool) []interface{} {
+		return []interface{}{contract.ContractDataOutput{
+			ContractId:         "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ContractDurability: durability,
+			LedgerSequence:     ledgerSequence,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           closedAt,
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": val},
+			Deleted:            evicted,
+			Evicted:            evicted,
+		}}
+	}
+	type row struct {
+		LedgerSequence int64  `db:"ledger_sequence"`
+		Val            string `db:"val"`
+		Deleted        bool   `db:"deleted"`
+		Evicted        bool   `db:"evicted"`
+	}
+	readRows := func(keyHash string) []row {
+		var rows []row
+		require.NoError(t, session.session.SelectRaw(ctx, &rows,
+			`SELECT ledger_sequence, convert_from(val, 'UTF8') AS val, deleted, evicted FROM contract_data WHERE key_hash = ?`, keyHash))
+		return rows
 	}
-	symbol := ExtractSymbol(keyDecoded)
-	expected := "XLM"
-	assert.Equal(t, expected, symbol, "expected %s, got %s", expected, symbol)
+
+	// An evicted persistent entry keeps its last value, even when removed entries are not kept
+	persistentKeyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(persistentKeyHash, "ContractDataDurabilityPersistent", 100, "AAAAAQ==", false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(persistentKeyHash, "ContractDataDurabilityPersistent", 200, "AAAAAA==", true)))
+	assert.Equal(t, []row{{LedgerSequence: 200, Val: "AAAAAQ==", Deleted: true, Evicted: true}}, readRows(persistentKeyHash))
+
+	// Restoring the entry makes it live again
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(persistentKeyHash, "ContractDataDurabilityPersistent", 300, "AAAAAQ==", false)))
+	assert.Equal(t, []row{{LedgerSequence: 300, Val: "AAAAAQ==", Deleted: false, Evicted: false}}, readRows(persistentKeyHash))
+
+	// An evicted temporary entry is gone for good
+	temporaryKeyHash := "0101010101010101010101010101010101010101010101010101010101010101"
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(temporaryKeyHash, "ContractDataDurabilityTemporary", 100, "AAAAAQ==", false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(temporaryKeyHash, "ContractDataDurabilityTemporary", 200, "AAAAAA==", true)))
+	assert.Empty(t, readRows(temporaryKeyHash))
+}
+
+func TestContractDataRemovedBeforeBackfill(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	contractDataOperator := NewContractDataDBOperator(*session, metricRecorder, true)
+
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	entry := func(ledgerSequence uint32, deleted bool) []interface{} {
+		return []interface{}{contract.ContractDataOutput{
+			ContractId:         "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ContractDurability: "ContractDataDurabilityTemporary",
+			LedgerSequence:     ledgerSequence,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           closedAt,
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": "AAAAAQ=="},
+			Deleted:            deleted,
+		}}
+	}
+	readLedgers := func() []int64 {
+		var ledgers []int64
+		require.NoError(t, session.session.SelectRaw(ctx, &ledgers, `SELECT ledger_sequence FROM contract_data WHERE key_hash = ?`, keyHash))
+		return ledgers
+	}
+
+	// A backfill of a ledger before the removal does not write the entry again
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(100, false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(200, true)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(150, false)))
+	assert.Empty(t, readLedgers())
+
+	// The entry created again after the removal is written
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(300, false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(150, false)))
+	assert.Equal(t, []int64{300}, readLedgers())
 }
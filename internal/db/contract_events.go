@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// indexedTopicCount is the number of leading decoded topics stored in their own column so they can be indexed
+const indexedTopicCount = 4
+
+type ContractEventDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractEventDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractEventDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractEventDBOperator {
+	return &contractEventDBOperator{session: dbSession, table: "contract_events", dataset: "contract_events", metricRecorder: metricRecorder}
+}
+
+// EventTypeName maps the XDR contract event type name, e.g. ContractEventTypeContract, to contract, system or diagnostic.
+func EventTypeName(typeString string) string {
+	return strings.ToLower(strings.TrimPrefix(typeString, "ContractEventType"))
+}
+
+// decodedTopics returns the decoded value of the leading topics, padded with nil for events with fewer topics.
+func decodedTopics(event contract.ContractEventOutput) []interface{} {
+	topics := make([]interface{}, indexedTopicCount)
+	for index, topic := range event.TopicsDecoded["topics_decoded"] {
+		if index >= indexedTopicCount {
+			break
+		}
+		topics[index] = topic["value"]
+	}
+	return topics
+}
+
+func (i *contractEventDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var transactionHash, transactionId, eventIndex, ledgerSequence, closedAt, successful, inSuccessfulContractCall []interface{}
+	var contractId, eventType, topics, topicsDecoded, eventData, eventDataDecoded, contractEventXDR []interface{}
+	topicColumns := make([][]interface{}, indexedTopicCount)
+
+	for _, rawRecord := range rawRecords {
+		event, ok := rawRecord.(contract.ContractEventOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractEventOutput")
+		}
+		topicsJson, err := json.Marshal(event.Topics["topics"])
+		if err != nil {
+			return fmt.Errorf("could not encode topics of transaction %s: %w", event.TransactionHash, err)
+		}
+		topicsDecodedJson, err := json.Marshal(event.TopicsDecoded["topics_decoded"])
+		if err != nil {
+			return fmt.Errorf("could not encode decoded topics of transaction %s: %w", event.TransactionHash, err)
+		}
+		dataJson, err := json.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("could not encode data of transaction %s: %w", event.TransactionHash, err)
+		}
+		dataDecodedJson, err := json.Marshal(event.DataDecoded)
+		if err != nil {
+			return fmt.Errorf("could not encode decoded data of transaction %s: %w", event.TransactionHash, err)
+		}
+
+		// System events are not emitted by a contract
+		var eventContractId interface{}
+		if event.ContractId != "" {
+			eventContractId = event.ContractId
+		}
+
+		transactionHash = append(transactionHash, event.TransactionHash)
+		transactionId = append(transactionId, event.TransactionID)
+		eventIndex = append(eventIndex, event.EventIndex)
+		ledgerSequence = append(ledgerSequence, event.LedgerSequence)
+		closedAt = append(closedAt, event.ClosedAt)
+		successful = append(successful, event.Successful)
+		inSuccessfulContractCall = append(inSuccessfulContractCall, event.InSuccessfulContractCall)
+		contractId = append(contractId, eventContractId)
+		eventType = append(eventType, EventTypeName(event.TypeString))
+		topics = append(topics, string(topicsJson))
+		topicsDecoded = append(topicsDecoded, string(topicsDecodedJson))
+		for index, topic := range decodedTopics(event) {
+			topicColumns[index] = append(topicColumns[index], topic)
+		}
+		eventData = append(eventData, string(dataJson))
+		eventDataDecoded = append(eventDataDecoded, string(dataDecodedJson))
+		contractEventXDR = append(contractEventXDR, event.ContractEventXDR)
+	}
+
+	upsertFields := []UpsertField{
+		{"transaction_hash", "text", transactionHash},
+		{"transaction_id", "bigint", transactionId},
+		{"event_index", "int", eventIndex},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"successful", "boolean", successful},
+		{"in_successful_contract_call", "boolean", inSuccessfulContractCall},
+		{"contract_id", "text", contractId},
+		{"type", "text", eventType},
+		{"topics", "jsonb", topics},
+		{"topics_decoded", "jsonb", topicsDecoded},
+	}
+	for index, topicColumn := range topicColumns {
+		upsertFields = append(upsertFields, UpsertField{fmt.Sprintf("topic_%d", index+1), "text", topicColumn})
+	}
+	upsertFields = append(upsertFields,
+		UpsertField{"data", "jsonb", eventData},
+		UpsertField{"data_decoded", "jsonb", eventDataDecoded},
+		UpsertField{"contract_event_xdr", "text", contractEventXDR},
+	)
+
+	// Events are immutable once emitted, replaying a ledger rewrites the same rows
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "transaction_id, event_index", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractEventDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractEventDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractEventDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractEventDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
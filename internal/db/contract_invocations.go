@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractInvocationDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractInvocationDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractInvocationDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractInvocationDBOperator {
+	return &contractInvocationDBOperator{session: dbSession, table: "contract_invocations", dataset: "contract_invocations", metricRecorder: metricRecorder}
+}
+
+func (i *contractInvocationDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var transactionHash, transactionId, operationId, operationIndex, ledgerSequence, closedAt, sourceAccount, successful, resultCode []interface{}
+	var hostFunctionType, contractId, functionName, args, argsDecoded, auth, returnValue, returnValueDecoded []interface{}
+	var declaredResourceFee, declaredInstructions, declaredDiskReadBytes, declaredWriteBytes, feeCharged, nonRefundableResourceFeeCharged, refundableResourceFeeCharged, rentFeeCharged []interface{}
+
+	for _, rawRecord := range rawRecords {
+		invocation, ok := rawRecord.(contract.ContractInvocationOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractInvocationOutput")
+		}
+		argsJson, err := json.Marshal(invocation.Args)
+		if err != nil {
+			return fmt.Errorf("could not encode args of transaction %s: %w", invocation.TransactionHash, err)
+		}
+		argsDecodedJson, err := json.Marshal(invocation.ArgsDecoded)
+		if err != nil {
+			return fmt.Errorf("could not encode decoded args of transaction %s: %w", invocation.TransactionHash, err)
+		}
+		authJson, err := json.Marshal(invocation.Auth)
+		if err != nil {
+			return fmt.Errorf("could not encode auth entries of transaction %s: %w", invocation.TransactionHash, err)
+		}
+
+		// Only invoke_contract calls a function of an existing contract, and failed calls return no value
+		var invocationContractId, invocationFunctionName, invocationReturnValue, invocationReturnValueDecoded interface{}
+		if invocation.ContractId != "" {
+			invocationContractId = invocation.ContractId
+			invocationFunctionName = invocation.FunctionName
+		}
+		if invocation.ReturnValue != nil {
+			returnValueJson, err := json.Marshal(invocation.ReturnValue)
+			if err != nil {
+				return fmt.Errorf("could not encode return value of transaction %s: %w", invocation.TransactionHash, err)
+			}
+			returnValueDecodedJson, err := json.Marshal(invocation.ReturnValueDecoded)
+			if err != nil {
+				return fmt.Errorf("could not encode decoded return value of transaction %s: %w", invocation.TransactionHash, err)
+			}
+			invocationReturnValue, invocationReturnValueDecoded = string(returnValueJson), string(returnValueDecodedJson)
+		}
+
+		transactionHash = append(transactionHash, invocation.TransactionHash)
+		transactionId = append(transactionId, invocation.TransactionID)
+		operationId = append(operationId, invocation.OperationID)
+		operationIndex = append(operationIndex, invocation.OperationIndex)
+		ledgerSequence = append(ledgerSequence, invocation.LedgerSequence)
+		closedAt = append(closedAt, invocation.ClosedAt)
+		sourceAccount = append(sourceAccount, invocation.SourceAccount)
+		successful = append(successful, invocation.Successful)
+		resultCode = append(resultCode, invocation.ResultCode)
+		hostFunctionType = append(hostFunctionType, invocation.HostFunctionType)
+		contractId = append(contractId, invocationContractId)
+		functionName = append(functionName, invocationFunctionName)
+		args = append(args, string(argsJson))
+		argsDecoded = append(argsDecoded, string(argsDecodedJson))
+		auth = append(auth, string(authJson))
+		returnValue = append(returnValue, invocationReturnValue)
+		returnValueDecoded = append(returnValueDecoded, invocationReturnValueDecoded)
+		declaredResourceFee = append(declaredResourceFee, invocation.DeclaredResourceFee)
+		declaredInstructions = append(declaredInstructions, invocation.DeclaredInstructions)
+		declaredDiskReadBytes = append(declaredDiskReadBytes, invocation.DeclaredDiskReadBytes)
+		declaredWriteBytes = append(declaredWriteBytes, invocation.DeclaredWriteBytes)
+		feeCharged = append(feeCharged, invocation.FeeCharged)
+		nonRefundableResourceFeeCharged = append(nonRefundableResourceFeeCharged, invocation.NonRefundableResourceFeeCharged)
+		refundableResourceFeeCharged = append(refundableResourceFeeCharged, invocation.RefundableResourceFeeCharged)
+		rentFeeCharged = append(rentFeeCharged, invocation.RentFeeCharged)
+	}
+
+	upsertFields := []UpsertField{
+		{"transaction_hash", "text", transactionHash},
+		{"transaction_id", "bigint", transactionId},
+		{"operation_id", "bigint", operationId},
+		{"operation_index", "int", operationIndex},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"source_account", "text", sourceAccount},
+		{"successful", "boolean", successful},
+		{"result_code", "text", resultCode},
+		{"host_function_type", "text", hostFunctionType},
+		{"contract_id", "text", contractId},
+		{"function_name", "text", functionName},
+		{"args", "jsonb", args},
+		{"args_decoded", "jsonb", argsDecoded},
+		{"auth", "jsonb", auth},
+		{"return_value", "jsonb", returnValue},
+		{"return_value_decoded", "jsonb", returnValueDecoded},
+		{"declared_resource_fee", "bigint", declaredResourceFee},
+		{"declared_instructions", "bigint", declaredInstructions},
+		{"declared_disk_read_bytes", "bigint", declaredDiskReadBytes},
+		{"declared_write_bytes", "bigint", declaredWriteBytes},
+		{"fee_charged", "bigint", feeCharged},
+		{"non_refundable_resource_fee_charged", "bigint", nonRefundableResourceFeeCharged},
+		{"refundable_resource_fee_charged", "bigint", refundableResourceFeeCharged},
+		{"rent_fee_charged", "bigint", rentFeeCharged},
+	}
+
+	// Operations are immutable once applied, replaying a ledger rewrites the same rows
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "operation_id", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractInvocationDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractInvocationDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractInvocationDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractInvocationDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
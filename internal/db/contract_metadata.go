@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractMetadataDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractMetadataDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractMetadataDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractMetadataDBOperator {
+	return &contractMetadataDBOperator{session: dbSession, table: "contract_metadata", dataset: "contract_metadata", metricRecorder: metricRecorder}
+}
+
+func (i *contractMetadataDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var codeHash, sdkVersion, envProtocolVersion, envPreReleaseVersion, meta, specEntryCount, parseError []interface{}
+
+	for _, rawRecord := range rawRecords {
+		contractSpec, ok := rawRecord.(contract.ContractSpecOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractSpecOutput")
+		}
+		metaJSON, err := json.Marshal(contractSpec.Meta)
+		if err != nil {
+			return fmt.Errorf("could not encode meta of contract code %s: %w", contractSpec.ContractCodeHash, err)
+		}
+		codeHash = append(codeHash, contractSpec.ContractCodeHash)
+		sdkVersion = append(sdkVersion, nullIfEmpty(contractSpec.SdkVersion))
+		envProtocolVersion = append(envProtocolVersion, contractSpec.EnvProtocolVersion)
+		envPreReleaseVersion = append(envPreReleaseVersion, contractSpec.EnvPreReleaseVersion)
+		meta = append(meta, string(metaJSON))
+		specEntryCount = append(specEntryCount, len(contractSpec.Entries))
+		parseError = append(parseError, nullIfEmpty(contractSpec.ParseError))
+	}
+
+	upsertFields := []UpsertField{
+		{"code_hash", "text", codeHash},
+		{"sdk_version", "text", sdkVersion},
+		{"env_protocol_version", "int", envProtocolVersion},
+		{"env_pre_release_version", "int", envPreReleaseVersion},
+		{"meta", "jsonb", meta},
+		{"spec_entry_count", "int", specEntryCount},
+		{"parse_error", "text", parseError},
+	}
+	// The code of a hash never changes, rewriting it only happens when it is restored or replayed
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "code_hash", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractMetadataDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractMetadataDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractMetadataDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractMetadataDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
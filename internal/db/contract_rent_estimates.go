@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+)
+
+const contractRentEstimatesTable = "contract_rent_estimates"
+
+// ContractDataEntrySize is the size of a live contract data entry, as of the last indexed ledger
+type ContractDataEntrySize struct {
+	KeyHash                 string `db:"key_hash"`
+	Durability              string `db:"durability"`
+	KeySymbol               string `db:"key_symbol"`
+	EntrySizeBytes          uint32 `db:"entry_size_bytes"`
+	LiveUntilLedgerSequence uint32 `db:"live_until_ledger_sequence"`
+}
+
+// IndexedRentFeeConfiguration reads the rent parameters in force as of the last indexed ledger. Parameters that
+// were not indexed, e.g. settings that were not upgraded since the first indexed ledger, are left as zero.
+func (q *DBSession) IndexedRentFeeConfiguration(ctx context.Context) (contract.RentFeeConfiguration, error) {
+	var row struct {
+		FeeRent1Kb                    sql.NullInt64 `db:"fee_rent_1kb"`
+		FeeWrite1Kb                   sql.NullInt64 `db:"fee_write_1kb"`
+		FeeWriteLedgerEntry           sql.NullInt64 `db:"fee_write_ledger_entry"`
+		PersistentRentRateDenominator sql.NullInt64 `db:"persistent_rent_rate_denominator"`
+		TemporaryRentRateDenominator  sql.NullInt64 `db:"temporary_rent_rate_denominator"`
+	}
+	query := `
+		SELECT
+			(SELECT soroban_fee_write_1kb FROM ledgers
+				WHERE soroban_fee_write_1kb IS NOT NULL
+				ORDER BY ledger_sequence DESC LIMIT 1) AS fee_rent_1kb,
+			(SELECT (value->>'fee_write1_kb')::bigint FROM current_config_settings
+				WHERE config_setting_id = 'contract_ledger_cost_ext_v0') AS fee_write_1kb,
+			(SELECT (value->>'fee_write_ledger_entry')::bigint FROM current_config_settings
+				WHERE config_setting_id = 'contract_ledger_cost_v0') AS fee_write_ledger_entry,
+			(SELECT (value->>'persistent_rent_rate_denominator')::bigint FROM current_config_settings
+				WHERE config_setting_id = 'state_archival') AS persistent_rent_rate_denominator,
+			(SELECT (value->>'temp_rent_rate_denominator')::bigint FROM current_config_settings
+				WHERE config_setting_id = 'state_archival') AS temporary_rent_rate_denominator`
+	if err := q.session.GetRaw(ctx, &row, query); err != nil {
+		return contract.RentFeeConfiguration{}, fmt.Errorf("failed to read rent parameters: %w", err)
+	}
+	return contract.RentFeeConfiguration{
+		FeeRent1Kb:                    row.FeeRent1Kb.Int64,
+		FeeWrite1Kb:                   row.FeeWrite1Kb.Int64,
+		FeeWriteLedgerEntry:           row.FeeWriteLedgerEntry.Int64,
+		PersistentRentRateDenominator: row.PersistentRentRateDenominator.Int64,
+		TemporaryRentRateDenominator:  row.TemporaryRentRateDenominator.Int64,
+	}, nil
+}
+
+// IndexedStorageLedger returns the last ledger both contract_data and ttl are indexed up to, which the live
+// storage is read at.
+func (q *DBSession) IndexedStorageLedger(ctx context.Context) (uint32, error) {
+	var ledgerSequence sql.NullInt64
+	query := `SELECT MIN(ledger_sequence) FROM ingestion_cursor WHERE dataset IN ('contract_data', 'ttl')`
+	if err := q.session.GetRaw(ctx, &ledgerSequence, query); err != nil {
+		return 0, fmt.Errorf("failed to read indexed storage ledger: %w", err)
+	}
+	return uint32(ledgerSequence.Int64), nil
+}
+
+// LiveContractDataSizes returns the size of the live entries of a contract, largest first.
+// Entries indexed before entry sizes were recorded and not updated since have no size and are skipped.
+func (q *DBSession) LiveContractDataSizes(ctx context.Context, contractId string) ([]ContractDataEntrySize, error) {
+	var entries []ContractDataEntrySize
+	query := `
+		SELECT key_hash, durability, COALESCE(key_symbol, '') AS key_symbol, entry_size_bytes, live_until_ledger_sequence
+		FROM current_contract_data
+		WHERE contract_id = ? AND liveness_state = 'live' AND entry_size_bytes IS NOT NULL
+		ORDER BY entry_size_bytes DESC, key_hash`
+	if err := q.session.SelectRaw(ctx, &entries, query, contractId); err != nil {
+		return nil, fmt.Errorf("failed to read live entries of %s: %w", contractId, err)
+	}
+	return entries, nil
+}
+
+// LiveContractStorageSizes returns the number and total size of the live entries of every contract, and the
+// extension of their TTL needed to keep them live until targetLedger.
+func (q *DBSession) LiveContractStorageSizes(ctx context.Context, targetLedger uint32) ([]contract.ContractStorageSize, error) {
+	var rows []struct {
+		ContractId                     string `db:"contract_id"`
+		PersistentEntries              uint64 `db:"persistent_entries"`
+		PersistentSizeBytes            uint64 `db:"persistent_size_bytes"`
+		TemporaryEntries               uint64 `db:"temporary_entries"`
+		TemporarySizeBytes             uint64 `db:"temporary_size_bytes"`
+		PersistentExtensionByteLedgers uint64 `db:"persistent_extension_byte_ledgers"`
+		TemporaryExtensionByteLedgers  uint64 `db:"temporary_extension_byte_ledgers"`
+		ExtendedEntries                uint64 `db:"extended_entries"`
+	}
+	query := `
+		SELECT contract_id,
+			COUNT(*) FILTER (WHERE durability = 'persistent') AS persistent_entries,
+			COALESCE(SUM(entry_size_bytes) FILTER (WHERE durability = 'persistent'), 0) AS persistent_size_bytes,
+			COUNT(*) FILTER (WHERE durability = 'temporary') AS temporary_entries,
+			COALESCE(SUM(entry_size_bytes) FILTER (WHERE durability = 'temporary'), 0) AS temporary_size_bytes,
+			COALESCE(SUM(entry_size_bytes::bigint * GREATEST(0, ?::bigint - live_until_ledger_sequence))
+				FILTER (WHERE durability = 'persistent'), 0) AS persistent_extension_byte_ledgers,
+			COALESCE(SUM(entry_size_bytes::bigint * GREATEST(0, ?::bigint - live_until_ledger_sequence))
+				FILTER (WHERE durability = 'temporary'), 0) AS temporary_extension_byte_ledgers,
+			COUNT(*) FILTER (WHERE live_until_ledger_sequence < ?) AS extended_entries
+		FROM current_contract_data
+		WHERE liveness_state = 'live' AND entry_size_bytes IS NOT NULL
+		GROUP BY contract_id`
+	if err := q.session.SelectRaw(ctx, &rows, query, targetLedger, targetLedger, targetLedger); err != nil {
+		return nil, fmt.Errorf("failed to read live contract storage: %w", err)
+	}
+	sizes := make([]contract.ContractStorageSize, 0, len(rows))
+	for _, row := range rows {
+		sizes = append(sizes, contract.ContractStorageSize{
+			ContractId:                     row.ContractId,
+			PersistentEntries:              row.PersistentEntries,
+			PersistentSizeBytes:            row.PersistentSizeBytes,
+			TemporaryEntries:               row.TemporaryEntries,
+			TemporarySizeBytes:             row.TemporarySizeBytes,
+			PersistentExtensionByteLedgers: row.PersistentExtensionByteLedgers,
+			TemporaryExtensionByteLedgers:  row.TemporaryExtensionByteLedgers,
+			ExtendedEntries:                row.ExtendedEntries,
+		})
+	}
+	return sizes, nil
+}
+
+// UnsizedContractDataAfter returns up to limit contract data rows without an entry size whose key hash follows
+// afterKeyHash, ordered by key hash, tombstones included.
+func (q *DBSession) UnsizedContractDataAfter(ctx context.Context, afterKeyHash string, limit int) ([]StoredContractData, error) {
+	var entries []StoredContractData
+	query := `
+		SELECT key_hash, contract_id, durability, ledger_sequence, closed_at, key, val
+		FROM contract_data
+		WHERE key_hash > ? AND entry_size_bytes IS NULL
+		ORDER BY key_hash
+		LIMIT ?`
+	if err := q.session.SelectRaw(ctx, &entries, query, afterKeyHash, limit); err != nil {
+		return nil, fmt.Errorf("failed to read unsized contract data after %s: %w", afterKeyHash, err)
+	}
+	return entries, nil
+}
+
+// UpdateEntrySizes sets the entry size of the given rows that have none. Rows written since they were read already
+// have the size of their new version and are left as is.
+func (q *DBSession) UpdateEntrySizes(ctx context.Context, keyHashes []string, sizes []int64) (int64, error) {
+	query := `
+		UPDATE contract_data
+		SET entry_size_bytes = sized.entry_size_bytes
+		FROM unnest(?::text[], ?::int[]) AS sized (key_hash, entry_size_bytes)
+		WHERE contract_data.key_hash = sized.key_hash AND contract_data.entry_size_bytes IS NULL`
+	result, err := q.session.ExecRaw(ctx, query, pq.Array(keyHashes), pq.Array(sizes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update entry sizes: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ReplaceContractRentEstimates replaces the content of contract_rent_estimates with the given estimates, made at
+// ledgerSequence. Contracts without live storage left have no row.
+func (q *DBSession) ReplaceContractRentEstimates(ctx context.Context, ledgerSequence uint32, estimates []contract.ContractRentEstimate) (err error) {
+	if err := q.session.Begin(ctx); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			q.session.Rollback()
+		}
+	}()
+
+	if _, err := q.session.ExecRaw(ctx, "DELETE FROM "+contractRentEstimatesTable); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", contractRentEstimatesTable, err)
+	}
+	if len(estimates) > 0 {
+		estimatedAt := time.Now().UTC()
+		var contractId, ledger, extendLedgers, persistentEntries, persistentSizeBytes, persistentRentFee []interface{}
+		var temporaryEntries, temporarySizeBytes, temporaryRentFee, ttlWriteFee, rentFee, estimatedAtValues []interface{}
+		for _, estimate := range estimates {
+			contractId = append(contractId, estimate.ContractId)
+			ledger = append(ledger, ledgerSequence)
+			extendLedgers = append(extendLedgers, estimate.ExtendLedgers)
+			persistentEntries = append(persistentEntries, estimate.PersistentEntries)
+			persistentSizeBytes = append(persistentSizeBytes, estimate.PersistentSizeBytes)
+			persistentRentFee = append(persistentRentFee, estimate.PersistentRentFee)
+			temporaryEntries = append(temporaryEntries, estimate.TemporaryEntries)
+			temporarySizeBytes = append(temporarySizeBytes, estimate.TemporarySizeBytes)
+			temporaryRentFee = append(temporaryRentFee, estimate.TemporaryRentFee)
+			ttlWriteFee = append(ttlWriteFee, estimate.TTLWriteFee)
+			rentFee = append(rentFee, estimate.RentFee)
+			estimatedAtValues = append(estimatedAtValues, estimatedAt)
+		}
+		upsertFields := []UpsertField{
+			{"contract_id", "text", contractId},
+			{"ledger_sequence", "int", ledger},
+			{"extend_ledgers", "int", extendLedgers},
+			{"persistent_entries", "int", persistentEntries},
+			{"persistent_size_bytes", "bigint", persistentSizeBytes},
+			{"persistent_rent_fee", "bigint", persistentRentFee},
+			{"temporary_entries", "int", temporaryEntries},
+			{"temporary_size_bytes", "bigint", temporarySizeBytes},
+			{"temporary_rent_fee", "bigint", temporaryRentFee},
+			{"ttl_write_fee", "bigint", ttlWriteFee},
+			{"rent_fee", "bigint", rentFee},
+			{"estimated_at", "timestamp", estimatedAtValues},
+		}
+		if _, err := q.UpsertRows(ctx, contractRentEstimatesTable, "contract_id", upsertFields, nil); err != nil {
+			return err
+		}
+	}
+	if err := q.session.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", contractRentEstimatesTable, err)
+	}
+	return nil
+}
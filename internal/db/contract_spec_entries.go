@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractSpecEntryDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractSpecEntryDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractSpecEntryDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractSpecEntryDBOperator {
+	return &contractSpecEntryDBOperator{session: dbSession, table: "contract_spec_entries", dataset: "contract_spec_entries", metricRecorder: metricRecorder}
+}
+
+func (i *contractSpecEntryDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var codeHash, entryIndex, entryType, name, lib, doc, definition, entryXDR []interface{}
+
+	for _, rawRecord := range rawRecords {
+		contractSpec, ok := rawRecord.(contract.ContractSpecOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractSpecOutput")
+		}
+		for _, entry := range contractSpec.Entries {
+			codeHash = append(codeHash, contractSpec.ContractCodeHash)
+			entryIndex = append(entryIndex, entry.EntryIndex)
+			entryType = append(entryType, entry.EntryType)
+			name = append(name, entry.Name)
+			lib = append(lib, nullIfEmpty(entry.Lib))
+			doc = append(doc, nullIfEmpty(entry.Doc))
+			definition = append(definition, entry.Definition)
+			entryXDR = append(entryXDR, entry.EntryXDR)
+		}
+	}
+	if len(codeHash) == 0 {
+		return nil
+	}
+
+	upsertFields := []UpsertField{
+		{"code_hash", "text", codeHash},
+		{"entry_index", "int", entryIndex},
+		{"entry_type", "text", entryType},
+		{"name", "text", name},
+		{"lib", "text", lib},
+		{"doc", "text", doc},
+		{"definition", "jsonb", definition},
+		{"entry_xdr", "text", entryXDR},
+	}
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "code_hash, entry_index", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractSpecEntryDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractSpecEntryDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractSpecEntryDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractSpecEntryDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
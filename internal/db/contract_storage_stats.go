@@ -0,0 +1,364 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const contractStorageStatsTable = "contract_storage_stats"
+
+// storageStatsEntry is the part of a contract_data row counted in contract_storage_stats
+type storageStatsEntry struct {
+	KeyHash        string `db:"key_hash"`
+	ContractId     string `db:"contract_id"`
+	Durability     string `db:"durability"`
+	LedgerSequence uint32 `db:"ledger_sequence"`
+	Deleted        bool   `db:"deleted"`
+	// SizeBytes is zero for rows indexed before entry sizes were recorded
+	SizeBytes               int64         `db:"entry_size_bytes"`
+	LiveUntilLedgerSequence sql.NullInt64 `db:"live_until_ledger_sequence"`
+}
+
+// contractStorageStatsDelta is the change of the stats of a contract made by a batch of writes to contract_data
+type contractStorageStatsDelta struct {
+	persistentEntries int64
+	temporaryEntries  int64
+	sizeBytes         int64
+	// addedLiveUntil is the lowest live_until_ledger_sequence written by the batch
+	addedLiveUntil sql.NullInt64
+	// removedLiveUntil is the lowest live_until_ledger_sequence replaced or removed by the batch. The earliest
+	// live_until_ledger_sequence of the contract is recomputed when it is not above it.
+	removedLiveUntil   sql.NullInt64
+	lastModifiedLedger uint32
+}
+
+// readStorageStatsEntries reads the existing rows of contract_data with the given key hashes, keyed by key hash.
+// The rows are locked until the end of the transaction, so that concurrent backfills writing the same entries
+// compute their deltas from the state they replace. Entries without a row are inserted with InsertNewRows first,
+// which reports each of them as new to a single writer.
+func readStorageStatsEntries(ctx context.Context, session DBSession, keyHashes []interface{}) (map[string]storageStatsEntry, error) {
+	entries := map[string]storageStatsEntry{}
+	if len(keyHashes) == 0 {
+		return entries, nil
+	}
+	query := `
+		SELECT key_hash, contract_id, durability, ledger_sequence, deleted,
+			COALESCE(entry_size_bytes, 0) AS entry_size_bytes, live_until_ledger_sequence
+		FROM contract_data
+		WHERE key_hash = ANY(?::text[])
+		FOR UPDATE`
+	var rows []storageStatsEntry
+	if err := session.session.SelectRaw(ctx, &rows, query, pq.Array(keyHashes)); err != nil {
+		return nil, fmt.Errorf("failed to read contract data for %s: %w", contractStorageStatsTable, err)
+	}
+	for _, row := range rows {
+		entries[row.KeyHash] = row
+	}
+	return entries, nil
+}
+
+// storageStatsChanges are the changes made to contract_data by a batch, by key hash
+type storageStatsChanges struct {
+	// written are the versions written with their value, removed and evicted the ledgers entries were removed or
+	// evicted at
+	written map[string]storageStatsEntry
+	removed map[string]uint32
+	evicted map[string]uint32
+	// writtenLedgers is the last ledger of the batch that changed each entry, see storageStatsDeltas
+	writtenLedgers map[string]uint32
+	// appliedTTLs are the live until ledgers of the buffered ttl entries applied to the written rows
+	appliedTTLs map[string]int64
+}
+
+func newStorageStatsChanges() storageStatsChanges {
+	return storageStatsChanges{
+		written:        map[string]storageStatsEntry{},
+		removed:        map[string]uint32{},
+		evicted:        map[string]uint32{},
+		writtenLedgers: map[string]uint32{},
+		appliedTTLs:    map[string]int64{},
+	}
+}
+
+// changed records that the batch changed the entry at ledgerSequence
+func (c storageStatsChanges) changed(keyHash string, ledgerSequence uint32) {
+	if ledgerSequence > c.writtenLedgers[keyHash] {
+		c.writtenLedgers[keyHash] = ledgerSequence
+	}
+}
+
+// after returns the state a batch of changes left the rows in from their state before it, without the rows it
+// removed. The locked rows are only changed by the batch, so their state follows from the conditions it was
+// written with: older versions never replace a row, and removals and evictions only apply to older rows.
+func (c storageStatsChanges) after(before map[string]storageStatsEntry) map[string]storageStatsEntry {
+	statsAfter := map[string]storageStatsEntry{}
+	for keyHash, entry := range c.written {
+		previous, existed := before[keyHash]
+		if existed && entry.LedgerSequence <= previous.LedgerSequence {
+			entry = previous
+		} else if existed {
+			entry.LiveUntilLedgerSequence = previous.LiveUntilLedgerSequence
+		}
+		if liveUntil, ok := c.appliedTTLs[keyHash]; ok {
+			entry.LiveUntilLedgerSequence = sql.NullInt64{Int64: liveUntil, Valid: true}
+		}
+		statsAfter[keyHash] = entry
+	}
+	for keyHash, ledger := range c.removed {
+		if previous, existed := before[keyHash]; existed && ledger < previous.LedgerSequence {
+			statsAfter[keyHash] = previous
+		}
+	}
+	for keyHash, ledger := range c.evicted {
+		if previous, existed := before[keyHash]; existed {
+			if ledger > previous.LedgerSequence {
+				previous.LedgerSequence = ledger
+				previous.Deleted = true
+			}
+			statsAfter[keyHash] = previous
+		}
+	}
+	return statsAfter
+}
+
+// storageStatsUpdate maintains contract_storage_stats across a batch of writes to contract_data. It is started
+// before the batch is written, reading and locking the rows the batch changes, and finished once the batch is
+// written, applying the difference with the state the batch left them in.
+type storageStatsUpdate struct {
+	before map[string]storageStatsEntry
+}
+
+// startStorageStatsUpdate reads and locks the existing rows with the given key hashes, see readStorageStatsEntries
+func startStorageStatsUpdate(ctx context.Context, session DBSession, keyHashes []interface{}) (*storageStatsUpdate, error) {
+	before, err := readStorageStatsEntries(ctx, session, keyHashes)
+	if err != nil {
+		return nil, err
+	}
+	return &storageStatsUpdate{before: before}, nil
+}
+
+// finishContractData applies the changes of a batch of contract data to contract_storage_stats
+func (u *storageStatsUpdate) finishContractData(ctx context.Context, session DBSession, changes storageStatsChanges) error {
+	return updateContractStorageStats(ctx, session, u.before, changes.after(u.before), changes.writtenLedgers)
+}
+
+// finishTTL applies the extensions of a batch of ttl entries to contract_storage_stats. Extensions only apply to
+// existing rows and only move their live until ledger forward.
+func (u *storageStatsUpdate) finishTTL(ctx context.Context, session DBSession, liveUntil map[string]int64) error {
+	statsAfter := map[string]storageStatsEntry{}
+	for keyHash, entry := range u.before {
+		if extended, ok := liveUntil[keyHash]; ok && (!entry.LiveUntilLedgerSequence.Valid || entry.LiveUntilLedgerSequence.Int64 < extended) {
+			entry.LiveUntilLedgerSequence = sql.NullInt64{Int64: extended, Valid: true}
+		}
+		statsAfter[keyHash] = entry
+	}
+	return updateContractStorageStats(ctx, session, u.before, statsAfter, nil)
+}
+
+func lowerLiveUntil(current sql.NullInt64, liveUntil sql.NullInt64) sql.NullInt64 {
+	if !liveUntil.Valid || (current.Valid && current.Int64 <= liveUntil.Int64) {
+		return current
+	}
+	return liveUntil
+}
+
+// storageStatsDeltas computes the change of the stats of every contract from the rows written by a batch, as they
+// were before the batch and as the batch left them. writtenLedgers maps the key hashes of the batch to the ledger
+// that changed them, entries that were not changed by the batch, e.g. older versions rejected during backfills, do
+// not move last_modified_ledger. Tombstones of removed entries are not counted.
+func storageStatsDeltas(before map[string]storageStatsEntry, after map[string]storageStatsEntry, writtenLedgers map[string]uint32) map[string]*contractStorageStatsDelta {
+	deltas := map[string]*contractStorageStatsDelta{}
+	delta := func(contractId string) *contractStorageStatsDelta {
+		if deltas[contractId] == nil {
+			deltas[contractId] = &contractStorageStatsDelta{}
+		}
+		return deltas[contractId]
+	}
+	count := func(entry storageStatsEntry, sign int64) {
+		d := delta(entry.ContractId)
+		if entry.Durability == "persistent" {
+			d.persistentEntries += sign
+		} else {
+			d.temporaryEntries += sign
+		}
+		d.sizeBytes += sign * entry.SizeBytes
+	}
+
+	for keyHash, previous := range before {
+		current, written := after[keyHash]
+		if written && current == previous {
+			continue
+		}
+		if !previous.Deleted {
+			count(previous, -1)
+			d := delta(previous.ContractId)
+			d.removedLiveUntil = lowerLiveUntil(d.removedLiveUntil, previous.LiveUntilLedgerSequence)
+		}
+		if ledger := writtenLedgers[keyHash]; ledger > delta(previous.ContractId).lastModifiedLedger {
+			delta(previous.ContractId).lastModifiedLedger = ledger
+		}
+	}
+	for keyHash, current := range after {
+		if previous, ok := before[keyHash]; ok && current == previous {
+			continue
+		}
+		if !current.Deleted {
+			count(current, 1)
+			d := delta(current.ContractId)
+			d.addedLiveUntil = lowerLiveUntil(d.addedLiveUntil, current.LiveUntilLedgerSequence)
+		}
+		if ledger := writtenLedgers[keyHash]; ledger > delta(current.ContractId).lastModifiedLedger {
+			delta(current.ContractId).lastModifiedLedger = ledger
+		}
+	}
+	return deltas
+}
+
+// updateContractStorageStats applies the changes made by a batch of writes to contract_data to contract_storage_stats.
+// before is the state of the written rows read with readStorageStatsEntries before the batch was written, after is
+// the state the batch left them in, without the rows it removed.
+func updateContractStorageStats(ctx context.Context, session DBSession, before map[string]storageStatsEntry, after map[string]storageStatsEntry, writtenLedgers map[string]uint32) error {
+	deltas := storageStatsDeltas(before, after, writtenLedgers)
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	updatedAt := time.Now().UTC()
+	var contractId, persistentEntries, temporaryEntries, sizeBytes, earliestLiveUntil, lastModifiedLedger, updatedAtValues []interface{}
+	var recomputedContractId, removedLiveUntil []interface{}
+	for id, delta := range deltas {
+		contractId = append(contractId, id)
+		persistentEntries = append(persistentEntries, delta.persistentEntries)
+		temporaryEntries = append(temporaryEntries, delta.temporaryEntries)
+		sizeBytes = append(sizeBytes, delta.sizeBytes)
+		var addedLiveUntil interface{}
+		if delta.addedLiveUntil.Valid {
+			addedLiveUntil = delta.addedLiveUntil.Int64
+		}
+		earliestLiveUntil = append(earliestLiveUntil, addedLiveUntil)
+		var ledger interface{}
+		if delta.lastModifiedLedger > 0 {
+			ledger = delta.lastModifiedLedger
+		}
+		lastModifiedLedger = append(lastModifiedLedger, ledger)
+		updatedAtValues = append(updatedAtValues, updatedAt)
+		if delta.removedLiveUntil.Valid {
+			recomputedContractId = append(recomputedContractId, id)
+			removedLiveUntil = append(removedLiveUntil, delta.removedLiveUntil.Int64)
+		}
+	}
+
+	// Counts and sizes are added to the existing stats, the earliest live until and last modified ledgers only
+	// move down and up respectively
+	upsertSql := fmt.Sprintf(`
+		INSERT INTO %s AS stats (contract_id, persistent_entries, temporary_entries, size_bytes,
+			earliest_live_until_ledger_sequence, last_modified_ledger, updated_at)
+		SELECT * FROM unnest(?::text[], ?::bigint[], ?::bigint[], ?::bigint[], ?::int[], ?::int[], ?::timestamp[])
+		ON CONFLICT (contract_id) DO UPDATE SET
+			persistent_entries = stats.persistent_entries + excluded.persistent_entries,
+			temporary_entries = stats.temporary_entries + excluded.temporary_entries,
+			size_bytes = stats.size_bytes + excluded.size_bytes,
+			earliest_live_until_ledger_sequence = LEAST(stats.earliest_live_until_ledger_sequence, excluded.earliest_live_until_ledger_sequence),
+			last_modified_ledger = GREATEST(stats.last_modified_ledger, excluded.last_modified_ledger),
+			updated_at = excluded.updated_at`,
+		contractStorageStatsTable,
+	)
+	if _, err := session.session.ExecRaw(ctx, upsertSql, pq.Array(contractId), pq.Array(persistentEntries), pq.Array(temporaryEntries),
+		pq.Array(sizeBytes), pq.Array(earliestLiveUntil), pq.Array(lastModifiedLedger), pq.Array(updatedAtValues)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", contractStorageStatsTable, err)
+	}
+
+	// The earliest live until ledger cannot be decremented, it is recomputed when the entry holding it changed
+	if len(recomputedContractId) > 0 {
+		recomputeSql := fmt.Sprintf(`
+			UPDATE %s AS stats
+			SET earliest_live_until_ledger_sequence = (
+				SELECT MIN(live_until_ledger_sequence) FROM contract_data
+				WHERE contract_data.contract_id = stats.contract_id AND NOT contract_data.deleted
+			)
+			FROM unnest(?::text[], ?::int[]) AS removed (contract_id, live_until_ledger_sequence)
+			WHERE stats.contract_id = removed.contract_id
+				AND (stats.earliest_live_until_ledger_sequence IS NULL
+					OR removed.live_until_ledger_sequence <= stats.earliest_live_until_ledger_sequence)`,
+			contractStorageStatsTable,
+		)
+		if _, err := session.session.ExecRaw(ctx, recomputeSql, pq.Array(recomputedContractId), pq.Array(removedLiveUntil)); err != nil {
+			return fmt.Errorf("failed to recompute earliest live until ledger of %s: %w", contractStorageStatsTable, err)
+		}
+	}
+
+	deleteSql := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE contract_id = ANY(?::text[]) AND persistent_entries = 0 AND temporary_entries = 0`,
+		contractStorageStatsTable,
+	)
+	if _, err := session.session.ExecRaw(ctx, deleteSql, pq.Array(contractId)); err != nil {
+		return fmt.Errorf("failed to remove empty rows of %s: %w", contractStorageStatsTable, err)
+	}
+	return nil
+}
+
+// StoredContractIdsAfter returns up to limit contract ids with indexed contract data following afterContractId,
+// ordered by contract id, so that every contract can be read in batches.
+func (q *DBSession) StoredContractIdsAfter(ctx context.Context, afterContractId string, limit int) ([]string, error) {
+	var contractIds []string
+	query := `
+		SELECT DISTINCT contract_id
+		FROM contract_data
+		WHERE contract_id > ?
+		ORDER BY contract_id
+		LIMIT ?`
+	if err := q.session.SelectRaw(ctx, &contractIds, query, afterContractId, limit); err != nil {
+		return nil, fmt.Errorf("failed to read contract ids after %s: %w", afterContractId, err)
+	}
+	return contractIds, nil
+}
+
+// RebuildContractStorageStats recomputes the stats of the given contracts from their contract_data rows in a single
+// transaction. The table lock makes ingestion wait for the rebuilt rows before applying its deltas on top of them,
+// while the rows are counted from the contract data committed before the lock.
+func (q *DBSession) RebuildContractStorageStats(ctx context.Context, contractIds []string) (err error) {
+	if err := q.session.Begin(ctx); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			q.session.Rollback()
+		}
+	}()
+
+	if _, err := q.session.ExecRaw(ctx, "LOCK TABLE "+contractStorageStatsTable+" IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", contractStorageStatsTable, err)
+	}
+	deleteSql := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = ANY(?::text[])`, contractStorageStatsTable)
+	if _, err := q.session.ExecRaw(ctx, deleteSql, pq.Array(contractIds)); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", contractStorageStatsTable, err)
+	}
+	insertSql := fmt.Sprintf(`
+		INSERT INTO %s (contract_id, persistent_entries, temporary_entries, size_bytes,
+			earliest_live_until_ledger_sequence, last_modified_ledger, updated_at)
+		SELECT contract_id,
+			COUNT(*) FILTER (WHERE NOT deleted AND durability = 'persistent'),
+			COUNT(*) FILTER (WHERE NOT deleted AND durability = 'temporary'),
+			COALESCE(SUM(entry_size_bytes) FILTER (WHERE NOT deleted), 0),
+			MIN(live_until_ledger_sequence) FILTER (WHERE NOT deleted),
+			MAX(ledger_sequence),
+			now()
+		FROM contract_data
+		WHERE contract_id = ANY(?::text[])
+		GROUP BY contract_id
+		HAVING COUNT(*) FILTER (WHERE NOT deleted) > 0`,
+		contractStorageStatsTable,
+	)
+	if _, err := q.session.ExecRaw(ctx, insertSql, pq.Array(contractIds)); err != nil {
+		return fmt.Errorf("failed to rebuild %s: %w", contractStorageStatsTable, err)
+	}
+	if err := q.session.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", contractStorageStatsTable, err)
+	}
+	return nil
+}
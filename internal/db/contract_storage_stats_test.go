@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageStatsDeltas(t *testing.T) {
+	liveUntil := func(ledger int64) sql.NullInt64 { return sql.NullInt64{Int64: ledger, Valid: true} }
+	updated := storageStatsEntry{KeyHash: "a", ContractId: "C1", Durability: "persistent", LedgerSequence: 40, SizeBytes: 86, LiveUntilLedgerSequence: liveUntil(100)}
+	removed := storageStatsEntry{KeyHash: "b", ContractId: "C1", Durability: "temporary", LedgerSequence: 40, SizeBytes: 66, LiveUntilLedgerSequence: liveUntil(90)}
+	created := storageStatsEntry{KeyHash: "c", ContractId: "C2", Durability: "persistent", LedgerSequence: 51, SizeBytes: 72, LiveUntilLedgerSequence: liveUntil(200)}
+	unchanged := storageStatsEntry{KeyHash: "d", ContractId: "C3", Durability: "persistent", LedgerSequence: 60, SizeBytes: 72}
+	deleted := storageStatsEntry{KeyHash: "e", ContractId: "C4", Durability: "persistent", LedgerSequence: 40, SizeBytes: 72, LiveUntilLedgerSequence: liveUntil(300)}
+
+	updatedAfter := updated
+	updatedAfter.LedgerSequence = 50
+	updatedAfter.SizeBytes = 96
+	tombstone := deleted
+	tombstone.LedgerSequence = 50
+	tombstone.Deleted = true
+
+	before := map[string]storageStatsEntry{"a": updated, "b": removed, "d": unchanged, "e": deleted}
+	after := map[string]storageStatsEntry{"a": updatedAfter, "c": created, "d": unchanged, "e": tombstone}
+	// d is an older version rejected during a backfill
+	writtenLedgers := map[string]uint32{"a": 50, "b": 50, "c": 51, "d": 30, "e": 50}
+
+	assert.Equal(t, map[string]*contractStorageStatsDelta{
+		"C1": {
+			temporaryEntries:   -1,
+			sizeBytes:          -56,
+			addedLiveUntil:     liveUntil(100),
+			removedLiveUntil:   liveUntil(90),
+			lastModifiedLedger: 50,
+		},
+		"C2": {
+			persistentEntries:  1,
+			sizeBytes:          72,
+			addedLiveUntil:     liveUntil(200),
+			lastModifiedLedger: 51,
+		},
+		"C4": {
+			persistentEntries:  -1,
+			sizeBytes:          -72,
+			removedLiveUntil:   liveUntil(300),
+			lastModifiedLedger: 50,
+		},
+	}, storageStatsDeltas(before, after, writtenLedgers))
+
+	// TTL extensions only move the live until ledgers
+	extended := updated
+	extended.LiveUntilLedgerSequence = liveUntil(500)
+	assert.Equal(t, map[string]*contractStorageStatsDelta{
+		"C1": {addedLiveUntil: liveUntil(500), removedLiveUntil: liveUntil(100)},
+	}, storageStatsDeltas(map[string]storageStatsEntry{"a": updated}, map[string]storageStatsEntry{"a": extended}, nil))
+}
+
+func TestStorageStatsChangesAfter(t *testing.T) {
+	liveUntil := func(ledger int64) sql.NullInt64 { return sql.NullInt64{Int64: ledger, Valid: true} }
+	existing := storageStatsEntry{KeyHash: "a", ContractId: "C1", Durability: "persistent", LedgerSequence: 40, SizeBytes: 86, LiveUntilLedgerSequence: liveUntil(100)}
+	newer := storageStatsEntry{KeyHash: "b", ContractId: "C1", Durability: "persistent", LedgerSequence: 60, SizeBytes: 86}
+	removedLater := storageStatsEntry{KeyHash: "c", ContractId: "C1", Durability: "temporary", LedgerSequence: 40, SizeBytes: 66}
+	evicted := storageStatsEntry{KeyHash: "d", ContractId: "C1", Durability: "persistent", LedgerSequence: 40, SizeBytes: 72, LiveUntilLedgerSequence: liveUntil(45)}
+	before := map[string]storageStatsEntry{"a": existing, "b": newer, "c": removedLater, "d": evicted}
+
+	changes := newStorageStatsChanges()
+	// a is updated and keeps its live until ledger, b is an older version rejected during a backfill
+	updated := existing
+	updated.LedgerSequence = 50
+	updated.SizeBytes = 96
+	updated.LiveUntilLedgerSequence = sql.NullInt64{}
+	changes.written["a"] = updated
+	older := newer
+	older.LedgerSequence = 30
+	changes.written["b"] = older
+	// e is created and gets a buffered ttl
+	created := storageStatsEntry{KeyHash: "e", ContractId: "C2", Durability: "persistent", LedgerSequence: 50, SizeBytes: 72}
+	changes.written["e"] = created
+	changes.appliedTTLs["e"] = 200
+	// c is removed at a later ledger, d is evicted
+	changes.removed["c"] = 50
+	changes.evicted["d"] = 50
+
+	updated.LiveUntilLedgerSequence = liveUntil(100)
+	created.LiveUntilLedgerSequence = liveUntil(200)
+	evictedAfter := evicted
+	evictedAfter.LedgerSequence = 50
+	evictedAfter.Deleted = true
+	assert.Equal(t, map[string]storageStatsEntry{"a": updated, "b": newer, "d": evictedAfter, "e": created}, changes.after(before))
+}
+
+func TestContractStorageStats(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	contractDataOperator := NewContractDataDBOperator(*session, metricRecorder, false)
+	ttlOperator := NewTTLDBOperator(*session, metricRecorder)
+
+	contractId := "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4"
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	entry := func(ledgerSequence uint32, entrySizeBytes uint32, deleted bool) []interface{} {
+		return []interface{}{contract.ContractDataOutput{
+			ContractId:         contractId,
+			ContractDurability: "ContractDataDurabilityPersistent",
+			LedgerSequence:     ledgerSequence,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           closedAt,
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": "AAAAAQ=="},
+			KeyJSON:            `{"symbol":"Admin"}`,
+			ValJSON:            `"void"`,
+			Deleted:            deleted,
+			EntrySizeBytes:     entrySizeBytes,
+		}}
+	}
+	type stats struct {
+		PersistentEntries int64         `db:"persistent_entries"`
+		SizeBytes         int64         `db:"size_bytes"`
+		EarliestLiveUntil sql.NullInt64 `db:"earliest_live_until_ledger_sequence"`
+		LastModified      int64         `db:"last_modified_ledger"`
+	}
+	readStats := func() []stats {
+		var rows []stats
+		require.NoError(t, session.session.SelectRaw(ctx, &rows,
+			`SELECT persistent_entries, size_bytes, earliest_live_until_ledger_sequence, last_modified_ledger
+			FROM contract_storage_stats WHERE contract_id = ?`, contractId))
+		return rows
+	}
+
+	// A new entry is counted with its size, writing the same ledger again does not count it twice
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(100, 76, false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(100, 76, false)))
+	assert.Equal(t, []stats{{PersistentEntries: 1, SizeBytes: 76, LastModified: 100}}, readStats())
+
+	// Extensions move the earliest live until ledger, a later version replaces the size and an older one is ignored
+	require.NoError(t, ttlOperator.Upsert(ctx, []interface{}{contract.TtlOutput{KeyHash: keyHash, LiveUntilLedgerSeq: 500, LedgerSequence: 100, ClosedAt: closedAt}}))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(110, 80, false)))
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(105, 90, false)))
+	assert.Equal(t, []stats{{PersistentEntries: 1, SizeBytes: 80, EarliestLiveUntil: sql.NullInt64{Int64: 500, Valid: true}, LastModified: 110}}, readStats())
+
+	// Removing the last entry removes the row, rebuilding it from the tombstone keeps it removed
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(120, 80, true)))
+	assert.Empty(t, readStats())
+	require.NoError(t, session.RebuildContractStorageStats(ctx, []string{contractId}))
+	assert.Empty(t, readStats())
+}
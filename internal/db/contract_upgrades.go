@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractUpgradeDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractUpgradeDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractUpgradeDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractUpgradeDBOperator {
+	return &contractUpgradeDBOperator{session: dbSession, table: "contract_upgrades", dataset: "contract_upgrades", metricRecorder: metricRecorder}
+}
+
+func (i *contractUpgradeDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var contractId, ledgerSequence, applicationOrder, operationIndex, transactionHash, closedAt []interface{}
+	var previousExecutableType, previousWasmHash, executableType, wasmHash []interface{}
+
+	for _, rawRecord := range rawRecords {
+		upgrade, ok := rawRecord.(contract.ContractInstanceOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractInstanceOutput")
+		}
+		var upgradeOperationIndex interface{}
+		if upgrade.OperationIndex != nil {
+			upgradeOperationIndex = *upgrade.OperationIndex
+		}
+		contractId = append(contractId, upgrade.ContractId)
+		ledgerSequence = append(ledgerSequence, upgrade.LedgerSequence)
+		applicationOrder = append(applicationOrder, upgrade.ApplicationOrder)
+		operationIndex = append(operationIndex, upgradeOperationIndex)
+		transactionHash = append(transactionHash, nullIfEmpty(upgrade.TransactionHash))
+		closedAt = append(closedAt, upgrade.ClosedAt)
+		previousExecutableType = append(previousExecutableType, upgrade.PreviousExecutableType)
+		previousWasmHash = append(previousWasmHash, nullIfEmpty(upgrade.PreviousWasmHash))
+		executableType = append(executableType, upgrade.ExecutableType)
+		wasmHash = append(wasmHash, nullIfEmpty(upgrade.WasmHash))
+	}
+
+	upsertFields := []UpsertField{
+		{"contract_id", "text", contractId},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"application_order", "int", applicationOrder},
+		{"operation_index", "int", operationIndex},
+		{"transaction_hash", "text", transactionHash},
+		{"closed_at", "timestamp", closedAt},
+		{"previous_executable_type", "text", previousExecutableType},
+		{"previous_wasm_hash", "text", previousWasmHash},
+		{"executable_type", "text", executableType},
+		{"wasm_hash", "text", wasmHash},
+	}
+	// Upgrades are append-only, replaying a ledger rewrites the same rows
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "contract_id, ledger_sequence, application_order", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *contractUpgradeDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractUpgradeDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractUpgradeDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractUpgradeDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type contractDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewContractDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) ContractDBOperator {
+	return &contractDBOperator{session: dbSession, table: "contracts", dataset: "contracts", metricRecorder: metricRecorder}
+}
+
+// nullIfEmpty writes empty strings as NULL.
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func (i *contractDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+
+	// A contract can be created and upgraded in the same ledger, the row holds its executable at the end of the ledger
+	latestByContractId := map[string]contract.ContractInstanceOutput{}
+	var contractIds []string
+	var createdContractId, createdLedger, createdAt, createdTransactionHash, deployer, salt []interface{}
+	for _, rawRecord := range rawRecords {
+		instance, ok := rawRecord.(contract.ContractInstanceOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractInstanceOutput")
+		}
+		if _, ok := latestByContractId[instance.ContractId]; !ok {
+			contractIds = append(contractIds, instance.ContractId)
+		}
+		latestByContractId[instance.ContractId] = instance
+
+		if instance.Created {
+			createdContractId = append(createdContractId, instance.ContractId)
+			createdLedger = append(createdLedger, instance.LedgerSequence)
+			createdAt = append(createdAt, instance.ClosedAt)
+			createdTransactionHash = append(createdTransactionHash, nullIfEmpty(instance.TransactionHash))
+			deployer = append(deployer, nullIfEmpty(instance.Deployer))
+			salt = append(salt, nullIfEmpty(instance.Salt))
+		}
+	}
+
+	var contractId, executableType, wasmHash, asset, ledgerSequence, closedAt []interface{}
+	for _, id := range contractIds {
+		instance := latestByContractId[id]
+		contractId = append(contractId, instance.ContractId)
+		executableType = append(executableType, instance.ExecutableType)
+		wasmHash = append(wasmHash, nullIfEmpty(instance.WasmHash))
+		asset = append(asset, nullIfEmpty(instance.Asset))
+		ledgerSequence = append(ledgerSequence, instance.LedgerSequence)
+		closedAt = append(closedAt, instance.ClosedAt)
+	}
+
+	upsertFields := []UpsertField{
+		{"contract_id", "text", contractId},
+		{"executable_type", "text", executableType},
+		{"wasm_hash", "text", wasmHash},
+		{"asset", "text", asset},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+	}
+	upsertConditions := []UpsertCondition{
+		{"ledger_sequence", OpGT},
+	}
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "contract_id", upsertFields, upsertConditions)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	if err != nil {
+		return err
+	}
+
+	// Creation fields are written on their own so that they are kept when a backfill
+	// reaches the creation of a contract whose row already holds a later executable.
+	// deployer and salt are NULL when the creating transaction does not hold the contract id preimage.
+	if len(createdContractId) > 0 {
+		createdFields := []UpsertField{
+			{"contract_id", "text", createdContractId},
+			{"created_ledger", "int", createdLedger},
+			{"created_at", "timestamp", createdAt},
+			{"created_transaction_hash", "text", createdTransactionHash},
+			{"deployer", "text", deployer},
+			{"salt", "text", salt},
+		}
+		if _, err := i.session.UpsertRows(ctx, i.table, "contract_id", createdFields, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StoredContractInstancesAfter returns up to limit stored contract instance entries whose key hash follows
+// afterKeyHash, ordered by key hash, so that every contract can be read in batches. Evicted instances are included,
+// as their contract can still be restored.
+func (q *DBSession) StoredContractInstancesAfter(ctx context.Context, afterKeyHash string, limit int) ([]StoredContractData, error) {
+	instanceKey, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode contract instance key: %w", err)
+	}
+	var entries []StoredContractData
+	query := `
+		SELECT key_hash, contract_id, durability, ledger_sequence, closed_at, key, val
+		FROM contract_data
+		WHERE key_hash > ? AND durability = 'persistent' AND key = ? AND (NOT deleted OR evicted)
+		ORDER BY key_hash
+		LIMIT ?`
+	if err := q.session.SelectRaw(ctx, &entries, query, afterKeyHash, []byte(instanceKey), limit); err != nil {
+		return nil, fmt.Errorf("failed to read contract instances after %s: %w", afterKeyHash, err)
+	}
+	return entries, nil
+}
+
+// InsertMissingContracts writes the executable of the given contracts that have no row yet, e.g. the ones created
+// before the first indexed ledger, and returns the number of contracts written. Their creation columns are NULL.
+// Contracts indexed in the meantime keep the executable and creation recorded by ingestion.
+func (q *DBSession) InsertMissingContracts(ctx context.Context, instances []contract.ContractInstanceOutput) (int, error) {
+	var contractId, executableType, wasmHash, asset, ledgerSequence, closedAt []interface{}
+	for _, instance := range instances {
+		contractId = append(contractId, instance.ContractId)
+		executableType = append(executableType, instance.ExecutableType)
+		wasmHash = append(wasmHash, nullIfEmpty(instance.WasmHash))
+		asset = append(asset, nullIfEmpty(instance.Asset))
+		ledgerSequence = append(ledgerSequence, instance.LedgerSequence)
+		closedAt = append(closedAt, instance.ClosedAt)
+	}
+	insertFields := []UpsertField{
+		{"contract_id", "text", contractId},
+		{"executable_type", "text", executableType},
+		{"wasm_hash", "text", wasmHash},
+		{"asset", "text", asset},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+	}
+	inserted, err := q.InsertNewRows(ctx, "contracts", "contract_id", insertFields)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert missing contracts: %w", err)
+	}
+	return len(inserted), nil
+}
+
+func (i *contractDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *contractDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *contractDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *contractDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// WatchedEntries returns the live until ledger of the indexed entries of the given contracts and of the contract
+// data and contract code entries with the given key hashes, along with the last event notified for that live until
+// ledger. Entries without a known live until ledger are skipped.
+func (q *DBSession) WatchedEntries(ctx context.Context, contractIds []string, keyHashes []string) ([]utils.WatchedEntry, error) {
+	query := `
+		SELECT watched.key_hash, watched.contract_id, watched.durability, watched.live_until_ledger_sequence,
+			COALESCE(n.event, '') AS notified_event
+		FROM (
+			SELECT key_hash, contract_id, durability, live_until_ledger_sequence
+			FROM contract_data
+			WHERE (contract_id = ANY(?::text[]) OR key_hash = ANY(?::text[]))
+				AND NOT deleted AND live_until_ledger_sequence IS NOT NULL
+			UNION ALL
+			SELECT key_hash, '' AS contract_id, 'persistent' AS durability, live_until_ledger_sequence
+			FROM contract_code
+			WHERE key_hash = ANY(?::text[]) AND NOT deleted AND live_until_ledger_sequence IS NOT NULL
+		) AS watched
+		LEFT JOIN expiry_notifications n
+			ON n.key_hash = watched.key_hash AND n.live_until_ledger_sequence = watched.live_until_ledger_sequence`
+	var entries []utils.WatchedEntry
+	if err := q.session.SelectRaw(ctx, &entries, query, pq.Array(contractIds), pq.Array(keyHashes), pq.Array(keyHashes)); err != nil {
+		return nil, fmt.Errorf("failed to read watched entries: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordExpiryNotifications keeps the last notification sent for each entry, read back by WatchedEntries
+func (q *DBSession) RecordExpiryNotifications(ctx context.Context, notifications []utils.ExpiryNotification) error {
+	var keyHash, liveUntilLedgerSequence, event, ledgerSequence, closedAt []interface{}
+	for _, notification := range notifications {
+		keyHash = append(keyHash, notification.KeyHash)
+		liveUntilLedgerSequence = append(liveUntilLedgerSequence, notification.LiveUntilLedgerSequence)
+		event = append(event, notification.Event)
+		ledgerSequence = append(ledgerSequence, notification.LedgerSequence)
+		closedAt = append(closedAt, notification.ClosedAt)
+	}
+	upsertFields := []UpsertField{
+		{"key_hash", "text", keyHash},
+		{"live_until_ledger_sequence", "int", liveUntilLedgerSequence},
+		{"event", "text", event},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+	}
+	upsertConditions := []UpsertCondition{
+		{"ledger_sequence", OpGE},
+	}
+	if _, err := q.UpsertRows(ctx, "expiry_notifications", "key_hash", upsertFields, upsertConditions); err != nil {
+		return fmt.Errorf("failed to record expiry notifications: %w", err)
+	}
+	return nil
+}
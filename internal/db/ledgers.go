@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type LedgerDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type ledgerDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewLedgerDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) LedgerDBOperator {
+	return &ledgerDBOperator{session: dbSession, table: "ledgers", dataset: "ledgers", metricRecorder: metricRecorder}
+}
+
+func (i *ledgerDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var ledgerSequence, ledgerHash, previousLedgerHash, closedAt, protocolVersion, baseFee, baseReserve []interface{}
+	var maxTxSetSize, transactionCount, sorobanFeeWrite1Kb, totalByteSizeOfLiveSorobanState []interface{}
+
+	for _, rawRecord := range rawRecords {
+		ledger, ok := rawRecord.(contract.LedgerOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected LedgerOutput")
+		}
+		ledgerSequence = append(ledgerSequence, ledger.Sequence)
+		ledgerHash = append(ledgerHash, ledger.LedgerHash)
+		previousLedgerHash = append(previousLedgerHash, ledger.PreviousLedgerHash)
+		closedAt = append(closedAt, ledger.ClosedAt)
+		protocolVersion = append(protocolVersion, ledger.ProtocolVersion)
+		baseFee = append(baseFee, ledger.BaseFee)
+		baseReserve = append(baseReserve, ledger.BaseReserve)
+		maxTxSetSize = append(maxTxSetSize, ledger.MaxTxSetSize)
+		transactionCount = append(transactionCount, ledger.TransactionCount)
+
+		// NULL for LedgerCloseMeta V0, which predates Soroban
+		var feeWrite1Kb, totalByteSize interface{}
+		if ledger.SorobanFeeWrite1Kb != nil {
+			feeWrite1Kb = *ledger.SorobanFeeWrite1Kb
+		}
+		if ledger.TotalByteSizeOfLiveSorobanState != nil {
+			totalByteSize = *ledger.TotalByteSizeOfLiveSorobanState
+		}
+		sorobanFeeWrite1Kb = append(sorobanFeeWrite1Kb, feeWrite1Kb)
+		totalByteSizeOfLiveSorobanState = append(totalByteSizeOfLiveSorobanState, totalByteSize)
+	}
+
+	upsertFields := []UpsertField{
+		{"ledger_sequence", "int", ledgerSequence},
+		{"ledger_hash", "text", ledgerHash},
+		{"previous_ledger_hash", "text", previousLedgerHash},
+		{"closed_at", "timestamp", closedAt},
+		{"protocol_version", "int", protocolVersion},
+		{"base_fee", "int", baseFee},
+		{"base_reserve", "int", baseReserve},
+		{"max_tx_set_size", "int", maxTxSetSize},
+		{"transaction_count", "int", transactionCount},
+		{"soroban_fee_write_1kb", "bigint", sorobanFeeWrite1Kb},
+		{"total_byte_size_of_live_soroban_state", "bigint", totalByteSizeOfLiveSorobanState},
+	}
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "ledger_sequence", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *ledgerDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *ledgerDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *ledgerDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *ledgerDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
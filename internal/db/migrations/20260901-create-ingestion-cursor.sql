@@ -0,0 +1,25 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Track the last fully committed ledger per dataset. The cursor is written
+-- in the same transaction as the dataset rows and is used to resume ingestion.
+CREATE TABLE IF NOT EXISTS ingestion_cursor (
+    dataset TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (dataset)
+);
+
+-- Seed cursors for existing deployments from the previous MAX(ledger_sequence) resume point.
+-- The max ledger itself may only be partially committed, so the cursor starts one ledger before it
+-- and that ledger is re-processed on the next run, as before.
+INSERT INTO ingestion_cursor (dataset, ledger_sequence)
+SELECT datasets.dataset, indexed.max_ledger_sequence - 1
+FROM (SELECT MAX(ledger_sequence) AS max_ledger_sequence FROM contract_data) AS indexed
+CROSS JOIN (VALUES ('contract_data'), ('ttl')) AS datasets (dataset)
+WHERE indexed.max_ledger_sequence IS NOT NULL
+ON CONFLICT (dataset) DO NOTHING;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS ingestion_cursor;
@@ -0,0 +1,29 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS contract_code (
+    code_hash TEXT,
+    key_hash TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    last_modified_ledger INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    n_instructions INTEGER,
+    n_functions INTEGER,
+    n_globals INTEGER,
+    n_table_entries INTEGER,
+    n_types INTEGER,
+    n_data_segments INTEGER,
+    n_elem_segments INTEGER,
+    n_imports INTEGER,
+    n_exports INTEGER,
+    n_data_segment_bytes INTEGER,
+    live_until_ledger_sequence INTEGER,
+    PRIMARY KEY (code_hash)
+);
+-- TTL entries reference contract code by the hash of its ledger key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contract_code_key_hash ON contract_code (key_hash);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_code_key_hash;
+DROP TABLE IF EXISTS contract_code;
@@ -0,0 +1,38 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS contract_events (
+    transaction_hash TEXT NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    event_index INTEGER NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    successful BOOLEAN NOT NULL,
+    in_successful_contract_call BOOLEAN NOT NULL,
+    contract_id TEXT,
+    type TEXT NOT NULL,
+    topics JSONB,
+    topics_decoded JSONB,
+    topic_1 TEXT,
+    topic_2 TEXT,
+    topic_3 TEXT,
+    topic_4 TEXT,
+    data JSONB,
+    data_decoded JSONB,
+    contract_event_xdr TEXT,
+    PRIMARY KEY (transaction_id, event_index)
+);
+-- "all events emitted by a contract since ledger N"
+CREATE INDEX IF NOT EXISTS idx_contract_events_contract_id_ledger_sequence ON contract_events (contract_id, ledger_sequence);
+-- events of a given name (first topic), optionally narrowed by the following topics
+CREATE INDEX IF NOT EXISTS idx_contract_events_topics ON contract_events (topic_1, topic_2, ledger_sequence);
+CREATE INDEX IF NOT EXISTS idx_contract_events_contract_id_topics ON contract_events (contract_id, topic_1, topic_2);
+CREATE INDEX IF NOT EXISTS idx_contract_events_transaction_hash ON contract_events (transaction_hash);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_events_transaction_hash;
+DROP INDEX IF EXISTS idx_contract_events_contract_id_topics;
+DROP INDEX IF EXISTS idx_contract_events_topics;
+DROP INDEX IF EXISTS idx_contract_events_contract_id_ledger_sequence;
+DROP TABLE IF EXISTS contract_events;
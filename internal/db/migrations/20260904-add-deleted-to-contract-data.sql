@@ -0,0 +1,22 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Keep removed contract data entries as tombstones, see indexer_config.deleted_entries.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS deleted BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS deleted_at_ledger INTEGER;
+
+-- Entries that exist on chain. Storage views must read from here instead of contract_data,
+-- which also holds tombstones of removed entries.
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence
+FROM contract_data
+WHERE NOT deleted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP VIEW IF EXISTS current_contract_data;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS deleted_at_ledger,
+DROP COLUMN IF EXISTS deleted;
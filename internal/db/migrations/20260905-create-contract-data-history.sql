@@ -0,0 +1,28 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Every version of every contract data entry, written when indexer_config.optional_datasets
+-- contains contract_data_history.
+CREATE TABLE IF NOT EXISTS contract_data_history (
+    key_hash TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    contract_id TEXT,
+    durability TEXT,
+    key_symbol TEXT,
+    change_type TEXT NOT NULL,
+    last_modified_ledger INTEGER,
+    key BYTEA,
+    val BYTEA,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (key_hash, ledger_sequence)
+);
+-- "value of key K as of ledger N" is served by the primary key:
+--   SELECT * FROM contract_data_history WHERE key_hash = K AND ledger_sequence <= N
+--   ORDER BY ledger_sequence DESC LIMIT 1
+CREATE INDEX IF NOT EXISTS idx_contract_data_history_contract_id_ledger_sequence
+ON contract_data_history (contract_id, ledger_sequence DESC);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_data_history_contract_id_ledger_sequence;
+DROP TABLE IF EXISTS contract_data_history;
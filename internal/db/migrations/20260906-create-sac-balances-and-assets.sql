@@ -0,0 +1,37 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Stellar Asset Contract (SAC) balances of contract addresses and the classic asset of every SAC.
+CREATE TABLE IF NOT EXISTS sac_balances (
+    contract_id TEXT NOT NULL,
+    holder TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    balance NUMERIC NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (contract_id, holder)
+);
+-- Balance entries are removed by the hash of their ledger key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sac_balances_key_hash ON sac_balances (key_hash);
+-- All balances of a holder
+CREATE INDEX IF NOT EXISTS idx_sac_balances_holder ON sac_balances (holder);
+
+-- Balances are matched on their storage layout, join with sac_assets to only keep balances of verified SACs
+CREATE TABLE IF NOT EXISTS sac_assets (
+    contract_id TEXT NOT NULL,
+    asset_type TEXT NOT NULL,
+    asset_code TEXT,
+    asset_issuer TEXT,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (contract_id)
+);
+CREATE INDEX IF NOT EXISTS idx_sac_assets_asset_code_asset_issuer ON sac_assets (asset_code, asset_issuer);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_sac_assets_asset_code_asset_issuer;
+DROP TABLE IF EXISTS sac_assets;
+DROP INDEX IF EXISTS idx_sac_balances_holder;
+DROP INDEX IF EXISTS idx_sac_balances_key_hash;
+DROP TABLE IF EXISTS sac_balances;
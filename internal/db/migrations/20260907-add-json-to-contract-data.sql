@@ -0,0 +1,37 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Decoded contract data keys and values, see contract.ScValToJSON for the format.
+-- Rows indexed before this migration have NULL key_json and val_json until they are updated or backfilled.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS key_json JSONB,
+ADD COLUMN IF NOT EXISTS val_json JSONB;
+
+-- NOTE: CONCURRENTLY not supported in migrations
+-- jsonb_path_ops indexes serve containment filters, e.g. key_json @> '{"vec":[{"symbol":"Balance"}]}'
+CREATE INDEX IF NOT EXISTS idx_contract_data_key_json
+ON public.contract_data USING GIN (key_json jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_contract_data_val_json
+ON public.contract_data USING GIN (val_json jsonb_path_ops);
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence, key_json, val_json
+FROM contract_data
+WHERE NOT deleted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP VIEW IF EXISTS current_contract_data;
+
+CREATE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence
+FROM contract_data
+WHERE NOT deleted;
+
+DROP INDEX IF EXISTS idx_contract_data_val_json;
+DROP INDEX IF EXISTS idx_contract_data_key_json;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS val_json,
+DROP COLUMN IF EXISTS key_json;
@@ -0,0 +1,35 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Arguments of enum variant keys, e.g. the address of Balance(address), see contract.DecomposeContractDataKey.
+-- key_args holds the normalized arguments and key_arg_types their ScVal types.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS key_args JSONB,
+ADD COLUMN IF NOT EXISTS key_arg_types JSONB;
+
+-- NOTE: CONCURRENTLY not supported in migrations
+-- "all Balance(G...) keys of contract C":
+--   WHERE contract_id = C AND key_symbol = 'Balance' AND key_args->>0 = 'G...'
+CREATE INDEX IF NOT EXISTS idx_contract_data_contract_id_key_symbol_first_arg
+ON public.contract_data (contract_id, key_symbol, (key_args->>0))
+WHERE key_args IS NOT NULL;
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence, key_json, val_json, key_args, key_arg_types
+FROM contract_data
+WHERE NOT deleted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP VIEW IF EXISTS current_contract_data;
+
+CREATE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence, key_json, val_json
+FROM contract_data
+WHERE NOT deleted;
+
+DROP INDEX IF EXISTS idx_contract_data_contract_id_key_symbol_first_arg;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS key_arg_types,
+DROP COLUMN IF EXISTS key_args;
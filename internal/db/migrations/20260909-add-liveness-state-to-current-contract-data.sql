@@ -0,0 +1,45 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Liveness of contract data entries as of the last indexed ledger, taken from the ingestion cursors.
+-- An entry is live while live_until_ledger_sequence >= current ledger. Past that ledger temporary entries
+-- are expired for good, while persistent entries are archived and can be restored, whether or not they
+-- were evicted yet. liveness_state is NULL for entries whose TTL is not indexed yet, or before the first ledger is committed.
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state
+FROM contract_data
+-- contract_data and ttl are committed together, the lowest cursor is the last ledger both are indexed up to
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+-- NOTE: CONCURRENTLY not supported in migrations
+-- Serves scans by state across contracts, e.g. every archived persistent entry:
+--   WHERE durability = 'persistent' AND live_until_ledger_sequence < current_ledger
+CREATE INDEX IF NOT EXISTS idx_contract_data_durability_live_until
+ON public.contract_data (durability, live_until_ledger_sequence)
+WHERE NOT deleted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_data_durability_live_until;
+
+DROP VIEW IF EXISTS current_contract_data;
+
+CREATE VIEW current_contract_data AS
+SELECT contract_id, ledger_sequence, key_hash, durability, key_symbol, key, val, closed_at, live_until_ledger_sequence, key_json, val_json, key_args, key_arg_types
+FROM contract_data
+WHERE NOT deleted;
@@ -0,0 +1,27 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS ledgers (
+    ledger_sequence INTEGER NOT NULL,
+    ledger_hash TEXT NOT NULL,
+    previous_ledger_hash TEXT NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    protocol_version INTEGER NOT NULL,
+    base_fee INTEGER NOT NULL,
+    base_reserve INTEGER NOT NULL,
+    max_tx_set_size INTEGER NOT NULL,
+    transaction_count INTEGER NOT NULL,
+    -- NULL for ledgers closed before LedgerCloseMeta V1
+    soroban_fee_write_1kb BIGINT,
+    total_byte_size_of_live_soroban_state BIGINT,
+    PRIMARY KEY (ledger_sequence)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledgers_ledger_hash ON ledgers (ledger_hash);
+-- Maps a point in time to the ledger closed at or before it
+CREATE INDEX IF NOT EXISTS idx_ledgers_closed_at ON ledgers (closed_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_ledgers_closed_at;
+DROP INDEX IF EXISTS idx_ledgers_ledger_hash;
+DROP TABLE IF EXISTS ledgers;
@@ -0,0 +1,83 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Transaction that wrote each contract data version, see utils.ChangeSource.
+-- application_order is the 1-based position of the transaction in the ledger and operation_index the 0-based index
+-- of the operation within it. All three are NULL for changes made by the ledger itself, e.g. evictions, and
+-- operation_index is NULL for changes made by the transaction outside of its operations.
+-- Rows indexed before this migration have NULL columns until they are updated or backfilled.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS transaction_hash TEXT,
+ADD COLUMN IF NOT EXISTS application_order INTEGER,
+ADD COLUMN IF NOT EXISTS operation_index INTEGER;
+
+ALTER TABLE contract_data_history
+ADD COLUMN IF NOT EXISTS transaction_hash TEXT,
+ADD COLUMN IF NOT EXISTS application_order INTEGER,
+ADD COLUMN IF NOT EXISTS operation_index INTEGER;
+
+-- NOTE: CONCURRENTLY not supported in migrations
+CREATE INDEX IF NOT EXISTS idx_contract_data_transaction_hash
+ON public.contract_data (transaction_hash);
+
+CREATE INDEX IF NOT EXISTS idx_contract_data_history_transaction_hash
+ON public.contract_data_history (transaction_hash);
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state,
+    contract_data.transaction_hash, contract_data.application_order, contract_data.operation_index
+FROM contract_data
+-- contract_data and ttl are committed together, the lowest cursor is the last ledger both are indexed up to
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP VIEW IF EXISTS current_contract_data;
+
+CREATE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state
+FROM contract_data
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+DROP INDEX IF EXISTS idx_contract_data_history_transaction_hash;
+DROP INDEX IF EXISTS idx_contract_data_transaction_hash;
+
+ALTER TABLE contract_data_history
+DROP COLUMN IF EXISTS operation_index,
+DROP COLUMN IF EXISTS application_order,
+DROP COLUMN IF EXISTS transaction_hash;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS operation_index,
+DROP COLUMN IF EXISTS application_order,
+DROP COLUMN IF EXISTS transaction_hash;
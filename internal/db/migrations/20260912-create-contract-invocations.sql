@@ -0,0 +1,43 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS contract_invocations (
+    operation_id BIGINT NOT NULL,
+    transaction_hash TEXT NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    operation_index INTEGER NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    source_account TEXT NOT NULL,
+    successful BOOLEAN NOT NULL,
+    result_code TEXT NOT NULL,
+    host_function_type TEXT NOT NULL,
+    -- NULL unless host_function_type is invoke_contract
+    contract_id TEXT,
+    function_name TEXT,
+    args JSONB,
+    args_decoded JSONB,
+    auth JSONB,
+    return_value JSONB,
+    return_value_decoded JSONB,
+    resource_fee BIGINT NOT NULL,
+    instructions BIGINT NOT NULL,
+    disk_read_bytes BIGINT NOT NULL,
+    write_bytes BIGINT NOT NULL,
+    fee_charged BIGINT NOT NULL,
+    non_refundable_resource_fee_charged BIGINT NOT NULL,
+    refundable_resource_fee_charged BIGINT NOT NULL,
+    rent_fee_charged BIGINT NOT NULL,
+    PRIMARY KEY (operation_id)
+);
+-- call volume and failure rate of the functions of a contract over time
+CREATE INDEX IF NOT EXISTS idx_contract_invocations_contract_id_function_name_closed_at ON contract_invocations (contract_id, function_name, closed_at);
+CREATE INDEX IF NOT EXISTS idx_contract_invocations_closed_at ON contract_invocations (closed_at);
+CREATE INDEX IF NOT EXISTS idx_contract_invocations_transaction_hash ON contract_invocations (transaction_hash);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_invocations_transaction_hash;
+DROP INDEX IF EXISTS idx_contract_invocations_closed_at;
+DROP INDEX IF EXISTS idx_contract_invocations_contract_id_function_name_closed_at;
+DROP TABLE IF EXISTS contract_invocations;
@@ -0,0 +1,46 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Lineage of contract instances. contracts holds the current executable of every contract and how it
+-- was created, contract_upgrades every later change of its executable.
+-- Creation columns are NULL for contracts created before the first indexed ledger. deployer and salt are also NULL
+-- for contracts created from an asset, or by a factory contract deploying from its own address.
+CREATE TABLE IF NOT EXISTS contracts (
+    contract_id TEXT NOT NULL,
+    executable_type TEXT,
+    wasm_hash TEXT,
+    asset TEXT,
+    ledger_sequence INTEGER,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_ledger INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE,
+    created_transaction_hash TEXT,
+    deployer TEXT,
+    salt TEXT,
+    PRIMARY KEY (contract_id)
+);
+CREATE INDEX IF NOT EXISTS idx_contracts_wasm_hash ON contracts (wasm_hash);
+CREATE INDEX IF NOT EXISTS idx_contracts_deployer ON contracts (deployer);
+
+CREATE TABLE IF NOT EXISTS contract_upgrades (
+    contract_id TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    application_order INTEGER NOT NULL,
+    operation_index INTEGER,
+    transaction_hash TEXT,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    previous_executable_type TEXT NOT NULL,
+    previous_wasm_hash TEXT,
+    executable_type TEXT NOT NULL,
+    wasm_hash TEXT,
+    PRIMARY KEY (contract_id, ledger_sequence, application_order)
+);
+CREATE INDEX IF NOT EXISTS idx_contract_upgrades_wasm_hash ON contract_upgrades (wasm_hash);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_upgrades_wasm_hash;
+DROP TABLE IF EXISTS contract_upgrades;
+DROP INDEX IF EXISTS idx_contracts_deployer;
+DROP INDEX IF EXISTS idx_contracts_wasm_hash;
+DROP TABLE IF EXISTS contracts;
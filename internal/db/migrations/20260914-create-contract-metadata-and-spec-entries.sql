@@ -0,0 +1,36 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Spec and metadata read from the contractspecv0, contractmetav0 and contractenvmetav0 custom sections
+-- of uploaded Wasm. parse_error is set when a section could not be decoded, in which case the spec may be partial.
+CREATE TABLE IF NOT EXISTS contract_metadata (
+    code_hash TEXT NOT NULL,
+    sdk_version TEXT,
+    env_protocol_version INTEGER NOT NULL,
+    env_pre_release_version INTEGER NOT NULL,
+    meta JSONB NOT NULL,
+    spec_entry_count INTEGER NOT NULL,
+    parse_error TEXT,
+    PRIMARY KEY (code_hash)
+);
+
+-- entry_type is one of function, struct, union, enum, error_enum or event
+CREATE TABLE IF NOT EXISTS contract_spec_entries (
+    code_hash TEXT NOT NULL,
+    entry_index INTEGER NOT NULL,
+    entry_type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    lib TEXT,
+    doc TEXT,
+    definition JSONB NOT NULL,
+    entry_xdr TEXT NOT NULL,
+    PRIMARY KEY (code_hash, entry_index)
+);
+-- NOTE: CONCURRENTLY not supported in migrations
+CREATE INDEX IF NOT EXISTS idx_contract_spec_entries_entry_type_name ON contract_spec_entries (entry_type, name);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_spec_entries_entry_type_name;
+DROP TABLE IF EXISTS contract_spec_entries;
+DROP TABLE IF EXISTS contract_metadata;
@@ -0,0 +1,38 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: SEP-41 token events (transfer, mint, burn, clawback, approve and set_admin), keyed like contract_events.
+-- from_address is NULL for mints, to_address for burns and clawbacks. For approvals, to_address is the spender,
+-- for set_admin, admin is the replaced admin and to_address the new one. asset is only set for Stellar Asset Contracts.
+CREATE TABLE IF NOT EXISTS token_transfers (
+    transaction_hash TEXT NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    event_index INTEGER NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    contract_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    from_address TEXT,
+    to_address TEXT,
+    admin TEXT,
+    amount NUMERIC,
+    to_muxed_id TEXT,
+    expiration_ledger INTEGER,
+    asset TEXT,
+    PRIMARY KEY (transaction_id, event_index)
+);
+-- NOTE: CONCURRENTLY not supported in migrations
+-- "activity of an account", on either side of the transfer
+CREATE INDEX IF NOT EXISTS idx_token_transfers_from_address_ledger_sequence ON token_transfers (from_address, ledger_sequence);
+CREATE INDEX IF NOT EXISTS idx_token_transfers_to_address_ledger_sequence ON token_transfers (to_address, ledger_sequence);
+-- "activity of a token since ledger N"
+CREATE INDEX IF NOT EXISTS idx_token_transfers_contract_id_ledger_sequence ON token_transfers (contract_id, ledger_sequence);
+CREATE INDEX IF NOT EXISTS idx_token_transfers_transaction_hash ON token_transfers (transaction_hash);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_token_transfers_transaction_hash;
+DROP INDEX IF EXISTS idx_token_transfers_contract_id_ledger_sequence;
+DROP INDEX IF EXISTS idx_token_transfers_to_address_ledger_sequence;
+DROP INDEX IF EXISTS idx_token_transfers_from_address_ledger_sequence;
+DROP TABLE IF EXISTS token_transfers;
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Every version of the network configuration settings, from the ledger at which an upgrade changed them.
+-- Settings that were not changed since the first indexed ledger have no row.
+CREATE TABLE IF NOT EXISTS config_settings (
+    config_setting_id TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    value JSONB NOT NULL,
+    value_xdr TEXT NOT NULL,
+    PRIMARY KEY (config_setting_id, ledger_sequence)
+);
+
+-- Settings in force as of the last indexed ledger
+CREATE OR REPLACE VIEW current_config_settings AS
+SELECT DISTINCT ON (config_setting_id) config_setting_id, ledger_sequence, closed_at, value, value_xdr
+FROM config_settings
+ORDER BY config_setting_id, ledger_sequence DESC;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP VIEW IF EXISTS current_config_settings;
+DROP TABLE IF EXISTS config_settings;
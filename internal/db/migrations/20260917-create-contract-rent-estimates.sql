@@ -0,0 +1,87 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Size of the XDR encoded ledger entry of each contract data row, which rent is charged on, and the
+-- rent needed to extend the TTL of the live storage of each contract, see the estimate-rent command. Rows indexed
+-- before this migration have no size until the backfill-entry-sizes command sizes them.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS entry_size_bytes INTEGER;
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state,
+    contract_data.transaction_hash, contract_data.application_order, contract_data.operation_index,
+    contract_data.entry_size_bytes
+FROM contract_data
+-- contract_data and ttl are committed together, the lowest cursor is the last ledger both are indexed up to
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+-- Rent estimates are a snapshot as of ledger_sequence, refreshed as a whole by the estimate-rent command and not
+-- updated by ingestion. Fees are in stroops
+CREATE TABLE IF NOT EXISTS contract_rent_estimates (
+    contract_id TEXT NOT NULL,
+    -- Ledger the live storage was read at and the estimate applies from
+    ledger_sequence INTEGER NOT NULL,
+    -- The TTL of the live entries is extended up to extend_ledgers past ledger_sequence
+    extend_ledgers INTEGER NOT NULL,
+    persistent_entries INTEGER NOT NULL,
+    persistent_size_bytes BIGINT NOT NULL,
+    persistent_rent_fee BIGINT NOT NULL,
+    temporary_entries INTEGER NOT NULL,
+    temporary_size_bytes BIGINT NOT NULL,
+    temporary_rent_fee BIGINT NOT NULL,
+    -- Fee to write the extended TTL entries
+    ttl_write_fee BIGINT NOT NULL,
+    rent_fee BIGINT NOT NULL,
+    estimated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (contract_id)
+);
+
+-- NOTE: CONCURRENTLY not supported in migrations
+CREATE INDEX IF NOT EXISTS idx_contract_rent_estimates_rent_fee
+ON public.contract_rent_estimates (rent_fee DESC);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_rent_estimates_rent_fee;
+DROP TABLE IF EXISTS contract_rent_estimates;
+
+DROP VIEW IF EXISTS current_contract_data;
+
+CREATE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state,
+    contract_data.transaction_hash, contract_data.application_order, contract_data.operation_index
+FROM contract_data
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS entry_size_bytes;
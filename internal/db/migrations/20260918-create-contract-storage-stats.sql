@@ -0,0 +1,26 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Storage footprint of every contract, maintained from the batches written to contract_data and the
+-- ttl extensions applied to it. Entries are counted until they are removed from the ledger, archived entries included.
+-- size_bytes is the total entry_size_bytes of the entries. Contracts indexed before this migration are counted by the
+-- rebuild-contract-storage-stats command.
+CREATE TABLE IF NOT EXISTS contract_storage_stats (
+    contract_id TEXT NOT NULL,
+    persistent_entries BIGINT NOT NULL,
+    temporary_entries BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    earliest_live_until_ledger_sequence INTEGER,
+    last_modified_ledger INTEGER,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (contract_id)
+);
+
+-- NOTE: CONCURRENTLY not supported in migrations
+CREATE INDEX IF NOT EXISTS idx_contract_storage_stats_earliest_live_until
+ON public.contract_storage_stats (earliest_live_until_ledger_sequence);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_storage_stats_earliest_live_until;
+DROP TABLE IF EXISTS contract_storage_stats;
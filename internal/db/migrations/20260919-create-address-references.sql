@@ -0,0 +1,29 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Reverse index of the addresses found anywhere inside the key or the value of the live contract data
+-- entries, replaced whenever an entry changes and removed with it. location is 'key' or 'val'. Entries indexed
+-- before this migration are referenced by the backfill-address-references command.
+CREATE TABLE IF NOT EXISTS address_references (
+    address TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    location TEXT NOT NULL,
+    contract_id TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (address, key_hash, location)
+);
+-- "entries referencing address A" is served by the primary key:
+--   SELECT * FROM address_references WHERE address = A
+-- NOTE: CONCURRENTLY not supported in migrations
+CREATE INDEX IF NOT EXISTS idx_address_references_key_hash
+ON address_references (key_hash);
+
+CREATE INDEX IF NOT EXISTS idx_address_references_contract_id
+ON address_references (contract_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_address_references_contract_id;
+DROP INDEX IF EXISTS idx_address_references_key_hash;
+DROP TABLE IF EXISTS address_references;
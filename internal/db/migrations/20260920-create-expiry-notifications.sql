@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Last expiry notification sent for each watched entry, for the live until ledger it was sent for.
+-- Loaded with the expiry watchlist at startup so that notifications are not sent again after a restart.
+CREATE TABLE IF NOT EXISTS expiry_notifications (
+    key_hash TEXT NOT NULL,
+    live_until_ledger_sequence INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (key_hash)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS expiry_notifications;
@@ -0,0 +1,72 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Flags persistent contract data entries evicted to the archive. Evicted entries are removed from the
+-- ledger, so they are kept as tombstones with their last value, but they can still be restored and stay in
+-- current_contract_data with the evicted liveness_state. Entries evicted before this migration are indexed as
+-- regular tombstones.
+ALTER TABLE contract_data
+ADD COLUMN IF NOT EXISTS evicted BOOLEAN NOT NULL DEFAULT false;
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.evicted THEN 'evicted'
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state,
+    contract_data.transaction_hash, contract_data.application_order, contract_data.operation_index,
+    contract_data.entry_size_bytes
+FROM contract_data
+-- contract_data and ttl are committed together, the lowest cursor is the last ledger both are indexed up to
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted OR contract_data.evicted;
+
+-- NOTE: CONCURRENTLY not supported in migrations
+-- The partial index follows the predicate of current_contract_data, so that evicted entries are served by it too
+DROP INDEX IF EXISTS idx_contract_data_durability_live_until;
+CREATE INDEX IF NOT EXISTS idx_contract_data_durability_live_until
+ON public.contract_data (durability, live_until_ledger_sequence)
+WHERE NOT deleted OR evicted;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_contract_data_durability_live_until;
+CREATE INDEX IF NOT EXISTS idx_contract_data_durability_live_until
+ON public.contract_data (durability, live_until_ledger_sequence)
+WHERE NOT deleted;
+
+CREATE OR REPLACE VIEW current_contract_data AS
+SELECT contract_data.contract_id, contract_data.ledger_sequence, contract_data.key_hash, contract_data.durability,
+    contract_data.key_symbol, contract_data.key, contract_data.val, contract_data.closed_at,
+    contract_data.live_until_ledger_sequence, contract_data.key_json, contract_data.val_json,
+    contract_data.key_args, contract_data.key_arg_types,
+    indexed.current_ledger,
+    CASE
+        WHEN contract_data.live_until_ledger_sequence IS NULL OR indexed.current_ledger IS NULL THEN NULL
+        WHEN contract_data.live_until_ledger_sequence >= indexed.current_ledger THEN 'live'
+        WHEN contract_data.durability = 'temporary' THEN 'expired'
+        ELSE 'archived'
+    END AS liveness_state,
+    contract_data.transaction_hash, contract_data.application_order, contract_data.operation_index,
+    contract_data.entry_size_bytes
+FROM contract_data
+CROSS JOIN (
+    SELECT MIN(ledger_sequence) AS current_ledger
+    FROM ingestion_cursor
+    WHERE dataset IN ('contract_data', 'ttl')
+) AS indexed
+WHERE NOT contract_data.deleted;
+
+ALTER TABLE contract_data
+DROP COLUMN IF EXISTS evicted;
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Keep removed contract code entries as tombstones holding their last cost inputs, see
+-- indexer_config.deleted_entries. Contract code is removed by evictions to the archive, evicted entries are kept
+-- with evicted set in both modes as they can still be restored.
+ALTER TABLE contract_code
+ADD COLUMN IF NOT EXISTS deleted BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS deleted_at_ledger INTEGER,
+ADD COLUMN IF NOT EXISTS evicted BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE contract_code
+DROP COLUMN IF EXISTS evicted,
+DROP COLUMN IF EXISTS deleted_at_ledger,
+DROP COLUMN IF EXISTS deleted;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Ledger at which the contract data entries whose rows were deleted were removed, when
+-- indexer_config.deleted_entries is remove. Backfills of earlier ledgers skip these entries instead of writing
+-- them again. Entries created again later on are removed from this table.
+CREATE TABLE IF NOT EXISTS removed_contract_data (
+    key_hash TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    PRIMARY KEY (key_hash)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS removed_contract_data;
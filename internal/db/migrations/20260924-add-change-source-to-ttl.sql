@@ -0,0 +1,16 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Transaction that wrote each buffered ttl entry, with the same meaning as the columns of contract_data.
+-- Rows buffered before this migration have NULL columns.
+ALTER TABLE ttl
+ADD COLUMN IF NOT EXISTS transaction_hash TEXT,
+ADD COLUMN IF NOT EXISTS application_order INTEGER,
+ADD COLUMN IF NOT EXISTS operation_index INTEGER;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE ttl
+DROP COLUMN IF EXISTS operation_index,
+DROP COLUMN IF EXISTS application_order,
+DROP COLUMN IF EXISTS transaction_hash;
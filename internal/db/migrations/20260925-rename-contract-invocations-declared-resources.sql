@@ -0,0 +1,16 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: The resources of contract_invocations are the limits declared by the transaction, the resources it
+-- actually used are not part of the ledger meta.
+ALTER TABLE contract_invocations RENAME COLUMN resource_fee TO declared_resource_fee;
+ALTER TABLE contract_invocations RENAME COLUMN instructions TO declared_instructions;
+ALTER TABLE contract_invocations RENAME COLUMN disk_read_bytes TO declared_disk_read_bytes;
+ALTER TABLE contract_invocations RENAME COLUMN write_bytes TO declared_write_bytes;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE contract_invocations RENAME COLUMN declared_write_bytes TO write_bytes;
+ALTER TABLE contract_invocations RENAME COLUMN declared_disk_read_bytes TO disk_read_bytes;
+ALTER TABLE contract_invocations RENAME COLUMN declared_instructions TO instructions;
+ALTER TABLE contract_invocations RENAME COLUMN declared_resource_fee TO resource_fee;
@@ -0,0 +1,37 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Records every change to an entry instead of its state at the end of the ledger, so that changes made
+-- by several transactions of a ledger are all kept. Changes made by the ledger itself have NULL application_order and
+-- operation_index, which are not distinct from each other in the key. Ledgers indexed before this migration keep a
+-- single version per entry.
+ALTER TABLE contract_data_history
+DROP CONSTRAINT IF EXISTS contract_data_history_pkey;
+
+-- The "as of" lookup of an entry is served by the key:
+--   SELECT * FROM contract_data_history WHERE key_hash = K AND ledger_sequence <= N
+--   ORDER BY ledger_sequence DESC, application_order DESC NULLS FIRST, operation_index DESC NULLS FIRST LIMIT 1
+ALTER TABLE contract_data_history
+ADD CONSTRAINT contract_data_history_change_key
+UNIQUE NULLS NOT DISTINCT (key_hash, ledger_sequence, application_order, operation_index);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE contract_data_history
+DROP CONSTRAINT IF EXISTS contract_data_history_change_key;
+
+-- Keep the last change to an entry in every ledger
+DELETE FROM contract_data_history
+WHERE ctid IN (
+    SELECT ctid FROM (
+        SELECT ctid, ROW_NUMBER() OVER (
+            PARTITION BY key_hash, ledger_sequence
+            ORDER BY application_order DESC NULLS FIRST, operation_index DESC NULLS FIRST
+        ) AS change_rank
+        FROM contract_data_history
+    ) AS ranked
+    WHERE change_rank > 1
+);
+
+ALTER TABLE contract_data_history
+ADD PRIMARY KEY (key_hash, ledger_sequence);
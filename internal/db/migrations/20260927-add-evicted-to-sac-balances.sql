@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: Balances evicted to the archive are kept and flagged, they can still be restored. The ledger at which
+-- deleted balances were removed is kept in removed_sac_balances, so that backfills of earlier ledgers skip these
+-- balances instead of writing them again. Balances created again later on are removed from this table.
+ALTER TABLE sac_balances ADD COLUMN IF NOT EXISTS evicted BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE IF NOT EXISTS removed_sac_balances (
+    key_hash TEXT NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    PRIMARY KEY (key_hash)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS removed_sac_balances;
+ALTER TABLE sac_balances DROP COLUMN IF EXISTS evicted;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- Description: References of persistent entries evicted to the archive are kept and flagged, the entries can still
+-- be restored. Restoring an entry replaces its references with live ones.
+ALTER TABLE address_references ADD COLUMN IF NOT EXISTS evicted BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE address_references DROP COLUMN IF EXISTS evicted;
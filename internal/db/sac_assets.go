@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// assetTypeNames maps the XDR asset type names to the names used by Horizon
+var assetTypeNames = map[string]string{
+	"AssetTypeAssetTypeNative":           "native",
+	"AssetTypeAssetTypeCreditAlphanum4":  "credit_alphanum4",
+	"AssetTypeAssetTypeCreditAlphanum12": "credit_alphanum12",
+}
+
+type SACAssetDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type sacAssetDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewSACAssetDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) SACAssetDBOperator {
+	return &sacAssetDBOperator{session: dbSession, table: "sac_assets", dataset: "sac_assets", metricRecorder: metricRecorder}
+}
+
+// Upsert writes the asset of every Stellar Asset Contract instance. The contract id of a Stellar Asset Contract
+// is derived from its asset, so the mapping is kept even if the instance entry is removed.
+func (i *sacAssetDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var contractId, assetType, assetCode, assetIssuer, ledgerSequence, closedAt []interface{}
+
+	for _, rawRecord := range rawRecords {
+		contractData, ok := rawRecord.(contract.ContractDataOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
+		}
+		// The native asset has no code nor issuer
+		var code, issuer interface{}
+		if contractData.ContractDataAssetCode != "" {
+			code = contractData.ContractDataAssetCode
+		}
+		if contractData.ContractDataAssetIssuer != "" {
+			issuer = contractData.ContractDataAssetIssuer
+		}
+		contractId = append(contractId, contractData.ContractId)
+		assetType = append(assetType, assetTypeNames[contractData.ContractDataAssetType])
+		assetCode = append(assetCode, code)
+		assetIssuer = append(assetIssuer, issuer)
+		ledgerSequence = append(ledgerSequence, contractData.LedgerSequence)
+		closedAt = append(closedAt, contractData.ClosedAt)
+	}
+
+	upsertFields := []UpsertField{
+		{"contract_id", "text", contractId},
+		{"asset_type", "text", assetType},
+		{"asset_code", "text", assetCode},
+		{"asset_issuer", "text", assetIssuer},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+	}
+	upsertConditions := []UpsertCondition{
+		{"ledger_sequence", OpGT},
+	}
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "contract_id", upsertFields, upsertConditions)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *sacAssetDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *sacAssetDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *sacAssetDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *sacAssetDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
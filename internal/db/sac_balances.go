@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type SACBalanceDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type sacBalanceDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewSACBalanceDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) SACBalanceDBOperator {
+	return &sacBalanceDBOperator{session: dbSession, table: "sac_balances", dataset: "sac_balances", metricRecorder: metricRecorder}
+}
+
+const removedSACBalancesTable = "removed_sac_balances"
+
+// removedLedgers returns the ledger each of the balances of keyHashes was last removed at, for the removed ones
+func (i *sacBalanceDBOperator) removedLedgers(ctx context.Context, keyHashes []string) (map[string]uint32, error) {
+	var rows []struct {
+		KeyHash        string `db:"key_hash"`
+		LedgerSequence uint32 `db:"ledger_sequence"`
+	}
+	query := "SELECT key_hash, ledger_sequence FROM " + removedSACBalancesTable + " WHERE key_hash = ANY(?::text[])"
+	if err := i.session.session.SelectRaw(ctx, &rows, query, pq.Array(keyHashes)); err != nil {
+		return nil, fmt.Errorf("failed to read removed sac balances: %w", err)
+	}
+	removedAt := make(map[string]uint32, len(rows))
+	for _, row := range rows {
+		removedAt[row.KeyHash] = row.LedgerSequence
+	}
+	return removedAt, nil
+}
+
+func (i *sacBalanceDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var contractId, holder, ledgerKeyHash, balance, ledgerSequence, closedAt, evicted []interface{}
+	var removedKeyHash, removedLedgerSequence []interface{}
+	var evictedKeyHash, evictedLedgerSequence, evictedClosedAt, evictedFlag []interface{}
+
+	var keyHashes []string
+	for _, rawRecord := range rawRecords {
+		if contractData, ok := rawRecord.(contract.ContractDataOutput); ok {
+			keyHashes = append(keyHashes, contractData.LedgerKeyHash)
+		}
+	}
+	// Removed balances leave no row to compare versions with, so backfills of ledgers before a balance was
+	// removed must not write it again
+	removedAt, err := i.removedLedgers(ctx, keyHashes)
+	if err != nil {
+		return err
+	}
+
+	for _, rawRecord := range rawRecords {
+		contractData, ok := rawRecord.(contract.ContractDataOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected ContractDataOutput")
+		}
+		if removedLedger, ok := removedAt[contractData.LedgerKeyHash]; ok && contractData.LedgerSequence <= removedLedger {
+			continue
+		}
+		// Balances are persistent entries, an evicted balance can be restored from the archive. Evictions only
+		// carry the key of the entry, so the row keeps its last balance and is flagged as evicted.
+		if contractData.Evicted {
+			evictedKeyHash = append(evictedKeyHash, contractData.LedgerKeyHash)
+			evictedLedgerSequence = append(evictedLedgerSequence, contractData.LedgerSequence)
+			evictedClosedAt = append(evictedClosedAt, contractData.ClosedAt)
+			evictedFlag = append(evictedFlag, true)
+			continue
+		}
+		// The table only holds current balances, a removed balance entry is deleted
+		if contractData.Deleted {
+			removedKeyHash = append(removedKeyHash, contractData.LedgerKeyHash)
+			removedLedgerSequence = append(removedLedgerSequence, contractData.LedgerSequence)
+			continue
+		}
+		contractId = append(contractId, contractData.ContractId)
+		holder = append(holder, contractData.ContractDataBalanceHolder)
+		ledgerKeyHash = append(ledgerKeyHash, contractData.LedgerKeyHash)
+		balance = append(balance, contractData.ContractDataBalance)
+		ledgerSequence = append(ledgerSequence, contractData.LedgerSequence)
+		closedAt = append(closedAt, contractData.ClosedAt)
+		evicted = append(evicted, false)
+	}
+
+	if len(contractId) > 0 {
+		upsertFields := []UpsertField{
+			{"contract_id", "text", contractId},
+			{"holder", "text", holder},
+			{"key_hash", "text", ledgerKeyHash},
+			{"balance", "numeric", balance},
+			{"ledger_sequence", "int", ledgerSequence},
+			{"closed_at", "timestamp", closedAt},
+			{"evicted", "boolean", evicted},
+		}
+		upsertConditions := []UpsertCondition{
+			{"ledger_sequence", OpGT},
+		}
+		rowsAffected, err := i.session.UpsertRows(ctx, i.table, "contract_id, holder", upsertFields, upsertConditions)
+		i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(removedKeyHash) > 0 {
+		deleteFields := []UpsertField{
+			{"key_hash", "text", removedKeyHash},
+			{"ledger_sequence", "int", removedLedgerSequence},
+		}
+		deleteConditions := []UpsertCondition{
+			{"ledger_sequence", OpGE},
+		}
+		if _, err := i.session.DeleteRows(ctx, i.table, "key_hash", deleteFields, deleteConditions); err != nil {
+			return err
+		}
+		removedConditions := []UpsertCondition{
+			{"ledger_sequence", OpGT},
+		}
+		if _, err := i.session.UpsertRows(ctx, removedSACBalancesTable, "key_hash", deleteFields, removedConditions); err != nil {
+			return err
+		}
+	}
+
+	// Balances created again forget their removal
+	if len(ledgerKeyHash) > 0 {
+		removalFields := []UpsertField{
+			{"key_hash", "text", ledgerKeyHash},
+			{"ledger_sequence", "int", ledgerSequence},
+		}
+		removalConditions := []UpsertCondition{
+			{"ledger_sequence", OpGT},
+		}
+		if _, err := i.session.DeleteRows(ctx, removedSACBalancesTable, "key_hash", removalFields, removalConditions); err != nil {
+			return err
+		}
+	}
+
+	if len(evictedKeyHash) > 0 {
+		evictFields := []UpsertField{
+			{"key_hash", "text", evictedKeyHash},
+			{"ledger_sequence", "int", evictedLedgerSequence},
+			{"closed_at", "timestamp", evictedClosedAt},
+			{"evicted", "boolean", evictedFlag},
+		}
+		// Only rows of earlier versions are flagged, a backfill must not flag a balance restored later on
+		condition := "data_source.ledger_sequence > " + i.table + ".ledger_sequence"
+		if _, err := i.session.EnrichExistingRows(ctx, i.table, "key_hash", evictFields, condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *sacBalanceDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *sacBalanceDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *sacBalanceDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *sacBalanceDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
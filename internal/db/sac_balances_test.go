@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSACBalanceRemovalAndEviction(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+	sacBalanceOperator := NewSACBalanceDBOperator(*session, metricRecorder)
+
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	balance := func(ledgerSequence uint32, amount string, deleted bool, evicted bool) []interface{} {
+		output := contract.ContractDataOutput{
+			ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			LedgerSequence: ledgerSequence,
+			LedgerKeyHash:  keyHash,
+			ClosedAt:       closedAt,
+			Deleted:        deleted || evicted,
+			Evicted:        evicted,
+		}
+		// Evictions only carry the key of the entry
+		if !evicted {
+			output.ContractDataBalanceHolder = "CAAQCAIBAEAQCAIBAEAQCAIBAEAQCAIBAEAQCAIBAEAQCAIBAEAQC526"
+			output.ContractDataBalance = amount
+		}
+		return []interface{}{output}
+	}
+	type row struct {
+		LedgerSequence int64  `db:"ledger_sequence"`
+		Balance        string `db:"balance"`
+		Evicted        bool   `db:"evicted"`
+	}
+	readRows := func() []row {
+		var rows []row
+		require.NoError(t, session.session.SelectRaw(ctx, &rows,
+			`SELECT ledger_sequence, balance::text AS balance, evicted FROM sac_balances WHERE key_hash = ?`, keyHash))
+		return rows
+	}
+
+	// An evicted balance keeps its last amount and is live again once restored
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(100, "10", false, false)))
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(200, "", false, true)))
+	assert.Equal(t, []row{{LedgerSequence: 200, Balance: "10", Evicted: true}}, readRows())
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(300, "10", false, false)))
+	assert.Equal(t, []row{{LedgerSequence: 300, Balance: "10", Evicted: false}}, readRows())
+
+	// A backfill of an eviction before the restore does not flag the balance
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(200, "", false, true)))
+	assert.Equal(t, []row{{LedgerSequence: 300, Balance: "10", Evicted: false}}, readRows())
+
+	// A backfill of a ledger before the removal does not write the balance again
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(400, "", true, false)))
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(350, "20", false, false)))
+	assert.Empty(t, readRows())
+
+	// The balance created again after the removal is written
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(500, "30", false, false)))
+	require.NoError(t, sacBalanceOperator.Upsert(ctx, balance(350, "20", false, false)))
+	assert.Equal(t, []row{{LedgerSequence: 500, Balance: "30", Evicted: false}}, readRows())
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/support/db"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type TokenTransferDBOperator interface {
+	Upsert(ctx context.Context, data any) error
+	TableName() string
+	Session() db.SessionInterface
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
+}
+
+type tokenTransferDBOperator struct {
+	session        DBSession
+	table          string
+	dataset        string
+	metricRecorder utils.MetricRecorder
+}
+
+func NewTokenTransferDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) TokenTransferDBOperator {
+	return &tokenTransferDBOperator{session: dbSession, table: "token_transfers", dataset: "token_transfers", metricRecorder: metricRecorder}
+}
+
+func (i *tokenTransferDBOperator) Upsert(ctx context.Context, data any) error {
+	rawRecords := data.([]interface{})
+	var transactionHash, transactionId, eventIndex, ledgerSequence, closedAt, contractId, eventType []interface{}
+	var fromAddress, toAddress, admin, amount, toMuxedId, expirationLedger, asset []interface{}
+
+	for _, rawRecord := range rawRecords {
+		transfer, ok := rawRecord.(contract.TokenTransferOutput)
+		if !ok {
+			return fmt.Errorf("InsertArgs: invalid type passed, expected TokenTransferOutput")
+		}
+		var transferExpirationLedger interface{}
+		if transfer.ExpirationLedger != nil {
+			transferExpirationLedger = *transfer.ExpirationLedger
+		}
+		transactionHash = append(transactionHash, transfer.TransactionHash)
+		transactionId = append(transactionId, transfer.TransactionID)
+		eventIndex = append(eventIndex, transfer.EventIndex)
+		ledgerSequence = append(ledgerSequence, transfer.LedgerSequence)
+		closedAt = append(closedAt, transfer.ClosedAt)
+		contractId = append(contractId, transfer.ContractId)
+		eventType = append(eventType, transfer.EventType)
+		fromAddress = append(fromAddress, nullIfEmpty(transfer.From))
+		toAddress = append(toAddress, nullIfEmpty(transfer.To))
+		admin = append(admin, nullIfEmpty(transfer.Admin))
+		amount = append(amount, nullIfEmpty(transfer.Amount))
+		toMuxedId = append(toMuxedId, nullIfEmpty(transfer.ToMuxedId))
+		expirationLedger = append(expirationLedger, transferExpirationLedger)
+		asset = append(asset, nullIfEmpty(transfer.Asset))
+	}
+
+	upsertFields := []UpsertField{
+		{"transaction_hash", "text", transactionHash},
+		{"transaction_id", "bigint", transactionId},
+		{"event_index", "int", eventIndex},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"contract_id", "text", contractId},
+		{"event_type", "text", eventType},
+		{"from_address", "text", fromAddress},
+		{"to_address", "text", toAddress},
+		{"admin", "text", admin},
+		{"amount", "numeric", amount},
+		{"to_muxed_id", "text", toMuxedId},
+		{"expiration_ledger", "int", expirationLedger},
+		{"asset", "text", asset},
+	}
+	// Events are immutable, replaying a ledger rewrites the same rows
+	rowsAffected, err := i.session.UpsertRows(ctx, i.table, "transaction_id, event_index", upsertFields, nil)
+	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+	return err
+}
+
+func (i *tokenTransferDBOperator) TableName() string {
+	return i.table
+}
+
+func (i *tokenTransferDBOperator) Session() db.SessionInterface {
+	return i.session.session
+}
+
+func (i *tokenTransferDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *tokenTransferDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
+}
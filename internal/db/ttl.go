@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/stellar/go-stellar-sdk/support/db"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
@@ -13,24 +14,93 @@ type TTLDBOperator interface {
 	Upsert(ctx context.Context, data any) error
 	TableName() string
 	Session() db.SessionInterface
-	GetMaxLedgerSequence(ctx context.Context) (uint32, error)
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
 }
 
+// ttlBufferTable holds the ttl entries whose contract data or contract code row is not indexed yet,
+// e.g. when backfills of different ranges run out of order. The buffered ttl is applied and removed
+// once the matching row is written.
+const ttlBufferTable = "ttl"
+
 type ttlDBOperator struct {
 	session        DBSession
 	table          string
 	dataset        string
 	metricRecorder utils.MetricRecorder
+	// enrichedTables are the tables whose rows get live_until_ledger_sequence from ttl entries, joined on key_hash
+	enrichedTables []string
 }
 
 func NewTTLDBOperator(dbSession DBSession, metricRecorder utils.MetricRecorder) TTLDBOperator {
-	return &ttlDBOperator{session: dbSession, table: "contract_data", dataset: "ttl", metricRecorder: metricRecorder}
+	return &ttlDBOperator{
+		session:        dbSession,
+		table:          "contract_data",
+		dataset:        "ttl",
+		metricRecorder: metricRecorder,
+		enrichedTables: []string{"contract_data", "contract_code"},
+	}
+}
+
+// liveUntilCondition only lets live_until_ledger_sequence of table move forward
+func liveUntilCondition(table string) string {
+	return fmt.Sprintf("(%s.live_until_ledger_sequence is null or %s.live_until_ledger_sequence < data_source.live_until_ledger_sequence)", table, table)
+}
+
+// resetRemovedTTLs clears live_until_ledger_sequence of the removed rows of table that are written again by a later
+// version, given by keys and ledgerSequences. liveUntilCondition only lets the live until ledger move forward, while
+// an entry created again starts over with the ttl of its new version, which is applied after the entry is written.
+func resetRemovedTTLs(ctx context.Context, session DBSession, table string, keyField string, keys []interface{}, ledgerSequences []interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	sql := fmt.Sprintf(`
+		WITH data_source AS (
+			SELECT unnest(?::text[]) AS %s, unnest(?::int[]) AS ledger_sequence
+		)
+		UPDATE %s
+		SET live_until_ledger_sequence = NULL
+		FROM data_source
+		WHERE %s.%s = data_source.%s AND %s.deleted AND data_source.ledger_sequence > %s.ledger_sequence`,
+		keyField,
+		table,
+		table, keyField, keyField, table, table,
+	)
+	if _, err := session.session.ExecRaw(ctx, sql, pq.Array(keys), pq.Array(ledgerSequences)); err != nil {
+		return fmt.Errorf("failed to reset the ttl of removed %s rows: %w", table, err)
+	}
+	return nil
+}
+
+// appliedTTL is a buffered ttl entry applied to its row
+type appliedTTL struct {
+	KeyHash                 string `db:"key_hash"`
+	LiveUntilLedgerSequence int64  `db:"live_until_ledger_sequence"`
+}
+
+// applyBufferedTTLs sets live_until_ledger_sequence of the given rows of table from the ttl entries buffered before
+// the rows were indexed, and returns the live until ledgers applied by key hash. It must be called after the rows
+// are written.
+func applyBufferedTTLs(ctx context.Context, session DBSession, table string, keyHashes []interface{}) (map[string]int64, error) {
+	liveUntil := map[string]int64{}
+	if len(keyHashes) == 0 {
+		return liveUntil, nil
+	}
+	var applied []appliedTTL
+	if err := session.ApplyBufferedRows(ctx, &applied, table, ttlBufferTable, "key_hash", keyHashes, []string{"live_until_ledger_sequence"}, liveUntilCondition(table)); err != nil {
+		return nil, err
+	}
+	for _, ttl := range applied {
+		liveUntil[ttl.KeyHash] = ttl.LiveUntilLedgerSequence
+	}
+	return liveUntil, nil
 }
 
 func (i *ttlDBOperator) Upsert(ctx context.Context, data any) error {
 	rawRecords := data.([]interface{})
 
-	var keyHash, liveUntilLedgerSequence []interface{}
+	var keyHash, liveUntilLedgerSequence, ledgerSequence, closedAt []interface{}
+	var transactionHash, applicationOrder, operationIndex []interface{}
 	for _, rawRecord := range rawRecords {
 		ttlData, ok := rawRecord.(contract.TtlOutput)
 		if !ok {
@@ -38,6 +108,12 @@ func (i *ttlDBOperator) Upsert(ctx context.Context, data any) error {
 		}
 		keyHash = append(keyHash, ttlData.KeyHash)
 		liveUntilLedgerSequence = append(liveUntilLedgerSequence, ttlData.LiveUntilLedgerSeq)
+		ledgerSequence = append(ledgerSequence, ttlData.LedgerSequence)
+		closedAt = append(closedAt, ttlData.ClosedAt)
+		entryTransactionHash, entryApplicationOrder, entryOperationIndex := changeSource(ttlData.TransactionHash, ttlData.ApplicationOrder, ttlData.OperationIndex)
+		transactionHash = append(transactionHash, entryTransactionHash)
+		applicationOrder = append(applicationOrder, entryApplicationOrder)
+		operationIndex = append(operationIndex, entryOperationIndex)
 	}
 
 	upsertFields := []UpsertField{
@@ -45,10 +121,46 @@ func (i *ttlDBOperator) Upsert(ctx context.Context, data any) error {
 		{"live_until_ledger_sequence", "int", liveUntilLedgerSequence},
 	}
 
-	upsertCondition := fmt.Sprintf("(%s.live_until_ledger_sequence is null or %s.live_until_ledger_sequence < data_source.live_until_ledger_sequence)", i.table, i.table)
-	rowsAffected, err := i.session.EnrichExistingRows(ctx, i.table, "key_hash", upsertFields, upsertCondition)
-	i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
-	return err
+	// Extensions move the earliest live until ledger of contract_storage_stats. They only apply to existing rows,
+	// which are locked so that the extended live until ledgers follow from the same condition as the update.
+	stats, err := startStorageStatsUpdate(ctx, i.session, keyHash)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range i.enrichedTables {
+		rowsAffected, err := i.session.EnrichExistingRows(ctx, table, "key_hash", upsertFields, liveUntilCondition(table))
+		i.metricRecorder.RecordUpsertCount(i.dataset, rowsAffected)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep the ttl entries that matched no row until their row is indexed
+	bufferFields := []UpsertField{
+		{"key_hash", "text", keyHash},
+		{"ledger_sequence", "int", ledgerSequence},
+		{"live_until_ledger_sequence", "int", liveUntilLedgerSequence},
+		{"closed_at", "timestamp", closedAt},
+		{"transaction_hash", "text", transactionHash},
+		{"application_order", "int", applicationOrder},
+		{"operation_index", "int", operationIndex},
+	}
+	bufferConditions := []UpsertCondition{
+		{"live_until_ledger_sequence", OpGT},
+	}
+	if _, err := i.session.UpsertUnmatchedRows(ctx, ttlBufferTable, "key_hash", bufferFields, bufferConditions, "key_hash", i.enrichedTables); err != nil {
+		return err
+	}
+
+	liveUntil := map[string]int64{}
+	for _, rawRecord := range rawRecords {
+		ttlData := rawRecord.(contract.TtlOutput)
+		if extended := int64(ttlData.LiveUntilLedgerSeq); extended > liveUntil[ttlData.KeyHash] {
+			liveUntil[ttlData.KeyHash] = extended
+		}
+	}
+	return stats.finishTTL(ctx, i.session, liveUntil)
 }
 
 func (i *ttlDBOperator) TableName() string {
@@ -59,6 +171,10 @@ func (i *ttlDBOperator) Session() db.SessionInterface {
 	return i.session.session
 }
 
-func (i *ttlDBOperator) GetMaxLedgerSequence(ctx context.Context) (uint32, error) {
-	return i.session.GetMaxLedgerSequence(ctx, i.table)
+func (i *ttlDBOperator) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return i.session.GetIngestionCursor(ctx, i.dataset)
+}
+
+func (i *ttlDBOperator) UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error {
+	return i.session.UpdateIngestionCursor(ctx, i.dataset, ledgerSequence)
 }
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stellar/go-stellar-sdk/support/db/dbtest"
+	"github.com/stellar/go-stellar-sdk/support/log"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDBSession opens a session on a new database with every migration applied
+func newTestDBSession(t *testing.T) (*DBSession, utils.MetricRecorder) {
+	testDB := dbtest.Postgres(t)
+	t.Cleanup(testDB.Close)
+	session, err := NewPostgresSession(context.Background(), testDB.DSN)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session, utils.GetNewMetricRecorder(context.Background(), log.New(), prometheus.NewRegistry(), "test")
+}
+
+func TestTTLBufferedUntilRowIsIndexed(t *testing.T) {
+	ctx := context.Background()
+	session, metricRecorder := newTestDBSession(t)
+
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	closedAt := time.Date(2025, time.October, 26, 17, 15, 2, 0, time.UTC)
+	liveUntil := func() []int64 {
+		var liveUntilLedgerSequence []int64
+		require.NoError(t, session.session.SelectRaw(ctx, &liveUntilLedgerSequence,
+			`SELECT live_until_ledger_sequence FROM contract_data WHERE key_hash = ? AND live_until_ledger_sequence IS NOT NULL
+			UNION ALL SELECT live_until_ledger_sequence FROM ttl WHERE key_hash = ?`, keyHash, keyHash))
+		return liveUntilLedgerSequence
+	}
+
+	// The ttl of an entry is written before the entry itself, e.g. by a backfill of a later range
+	ttlOperator := NewTTLDBOperator(*session, metricRecorder)
+	operationIndex := uint32(0)
+	require.NoError(t, ttlOperator.Upsert(ctx, []interface{}{
+		contract.TtlOutput{
+			KeyHash: keyHash, LiveUntilLedgerSeq: 200, LedgerSequence: 100, ClosedAt: closedAt,
+			TransactionHash: "0101010101010101010101010101010101010101010101010101010101010101", ApplicationOrder: 2, OperationIndex: &operationIndex,
+		},
+	}))
+	var buffered []struct {
+		LiveUntilLedgerSequence int64  `db:"live_until_ledger_sequence"`
+		TransactionHash         string `db:"transaction_hash"`
+		ApplicationOrder        int64  `db:"application_order"`
+		OperationIndex          int64  `db:"operation_index"`
+	}
+	require.NoError(t, session.session.SelectRaw(ctx, &buffered,
+		`SELECT live_until_ledger_sequence, transaction_hash, application_order, operation_index FROM ttl WHERE key_hash = ?`, keyHash))
+	require.Len(t, buffered, 1)
+	assert.Equal(t, int64(200), buffered[0].LiveUntilLedgerSequence)
+	assert.Equal(t, "0101010101010101010101010101010101010101010101010101010101010101", buffered[0].TransactionHash)
+	assert.Equal(t, int64(2), buffered[0].ApplicationOrder)
+	assert.Equal(t, int64(0), buffered[0].OperationIndex)
+
+	// Writing the entry applies the buffered ttl and removes it from the buffer
+	contractDataOperator := NewContractDataDBOperator(*session, metricRecorder, false)
+	require.NoError(t, contractDataOperator.Upsert(ctx, []interface{}{
+		contract.ContractDataOutput{
+			ContractId:         "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ContractDurability: "ContractDataDurabilityPersistent",
+			LedgerSequence:     90,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           closedAt,
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": "AAAAAQ=="},
+			KeyJSON:            `{"symbol":"Admin"}`,
+			ValJSON:            `"void"`,
+			EntrySizeBytes:     80,
+		},
+	}))
+	assert.Equal(t, []int64{200}, liveUntil())
+
+	// An older ttl does not move the live until ledger back, and is not buffered as the row exists
+	require.NoError(t, ttlOperator.Upsert(ctx, []interface{}{
+		contract.TtlOutput{KeyHash: keyHash, LiveUntilLedgerSeq: 150, LedgerSequence: 95, ClosedAt: closedAt},
+	}))
+	assert.Equal(t, []int64{200}, liveUntil())
+
+	// An entry created again after its removal starts over with the ttl of its new version, even an earlier one
+	entry := func(ledgerSequence uint32, deleted bool) []interface{} {
+		return []interface{}{contract.ContractDataOutput{
+			ContractId:         "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ContractDurability: "ContractDataDurabilityPersistent",
+			LedgerSequence:     ledgerSequence,
+			LedgerKeyHash:      keyHash,
+			ClosedAt:           closedAt,
+			Key:                map[string]string{"value": "AAAADwAAAAVBZG1pbgAAAA=="},
+			Val:                map[string]string{"value": "AAAAAQ=="},
+			Deleted:            deleted,
+		}}
+	}
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(110, true)))
+	assert.Equal(t, []int64{200}, liveUntil())
+	require.NoError(t, contractDataOperator.Upsert(ctx, entry(120, false)))
+	require.NoError(t, ttlOperator.Upsert(ctx, []interface{}{
+		contract.TtlOutput{KeyHash: keyHash, LiveUntilLedgerSeq: 180, LedgerSequence: 120, ClosedAt: closedAt},
+	}))
+	assert.Equal(t, []int64{180}, liveUntil())
+}
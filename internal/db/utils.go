@@ -24,6 +24,8 @@ const (
 	OpEQ Operator = "="
 )
 
+const ingestionCursorTable = "ingestion_cursor"
+
 func (o Operator) Valid() bool {
 	switch o {
 	case OpLT, OpGT, OpLE, OpGE, OpEQ:
@@ -56,22 +58,76 @@ func NewPostgresSession(ctx context.Context, connStr string) (*DBSession, error)
 	return &DBSession{session: session}, nil
 }
 
-// GetMaxLedgerSequence returns the maximum ledger_sequence from the specified table.
-// Returns 0 if the table is empty. Returns an error if the table name is invalid or if the query fails.
-func (q *DBSession) GetMaxLedgerSequence(ctx context.Context, tableName string) (uint32, error) {
+// Session returns the underlying session, e.g. to control the transaction shared by all operators.
+func (q *DBSession) Session() db.SessionInterface {
+	return q.session
+}
+
+// Clone returns a session on the same database that runs outside of the transaction of q, e.g. for writes made
+// in the background while a ledger is being committed.
+func (q *DBSession) Clone() *DBSession {
+	return &DBSession{session: q.session.Clone()}
+}
+
+// Close closes the underlying database connection.
+func (q *DBSession) Close() error {
+	return q.session.Close()
+}
+
+// GetIngestionCursor returns the last ledger sequence fully committed for the given dataset.
+// Returns 0 if the dataset has no cursor yet.
+func (q *DBSession) GetIngestionCursor(ctx context.Context, dataset string) (uint32, error) {
 	query := sq.
-		Select("MAX(ledger_sequence)").
-		From(tableName)
-	var maxLedger uint32
-	err := q.session.Get(ctx, &maxLedger, query)
+		Select("ledger_sequence").
+		From(ingestionCursorTable).
+		Where(sq.Eq{"dataset": dataset})
+	var ledgerSequence uint32
+	err := q.session.Get(ctx, &ledgerSequence, query)
+	if q.session.NoRows(err) {
+		return 0, nil
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to get max ledger sequence from %s: %w", tableName, err)
+		return 0, fmt.Errorf("failed to get ingestion cursor for %s: %w", dataset, err)
+	}
+	return ledgerSequence, nil
+}
+
+// UpdateIngestionCursor moves the cursor of the given dataset to ledgerSequence. It must be called
+// within the transaction writing the dataset rows for that ledger. Cursors only move forward, so
+// backfills of older ranges never rewind the resume point of a live indexer.
+func (q *DBSession) UpdateIngestionCursor(ctx context.Context, dataset string, ledgerSequence uint32) error {
+	upsertFields := []UpsertField{
+		{"dataset", "text", []interface{}{dataset}},
+		{"ledger_sequence", "int", []interface{}{ledgerSequence}},
+		{"updated_at", "timestamp", []interface{}{time.Now().UTC()}},
+	}
+	upsertConditions := []UpsertCondition{
+		{"ledger_sequence", OpGT},
+	}
+	if _, err := q.UpsertRows(ctx, ingestionCursorTable, "dataset", upsertFields, upsertConditions); err != nil {
+		return fmt.Errorf("failed to update ingestion cursor for %s: %w", dataset, err)
 	}
-	return maxLedger, nil
+	return nil
 }
 
 // Extended from https://github.com/stellar/stellar-horizon/blob/main/internal/db2/history/main.go
 func (q *DBSession) UpsertRows(ctx context.Context, table string, conflictField string, fields []UpsertField, conditions []UpsertCondition) (rowsAffected int64, err error) {
+	return q.upsertRows(ctx, table, conflictField, fields, conditions, "")
+}
+
+// UpsertUnmatchedRows works like UpsertRows but only writes the rows whose joinField matches no row of matchTables.
+func (q *DBSession) UpsertUnmatchedRows(ctx context.Context, table string, conflictField string, fields []UpsertField, conditions []UpsertCondition, joinField string, matchTables []string) (rowsAffected int64, err error) {
+	filterPart := make([]string, 0, len(matchTables))
+	for _, matchTable := range matchTables {
+		filterPart = append(
+			filterPart,
+			fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = r.%s)", matchTable, matchTable, joinField, joinField),
+		)
+	}
+	return q.upsertRows(ctx, table, conflictField, fields, conditions, strings.Join(filterPart, " AND "))
+}
+
+func (q *DBSession) upsertRows(ctx context.Context, table string, conflictField string, fields []UpsertField, conditions []UpsertCondition, filter string) (rowsAffected int64, err error) {
 	unnestPart := make([]string, 0, len(fields))
 	insertFieldsPart := make([]string, 0, len(fields))
 	onConflictPart := make([]string, 0, len(fields))
@@ -81,7 +137,7 @@ func (q *DBSession) UpsertRows(ctx context.Context, table string, conflictField
 	for _, field := range fields {
 		unnestPart = append(
 			unnestPart,
-			fmt.Sprintf("unnest(?::%s[]) /* %s */", field.dbType, field.name),
+			fmt.Sprintf("unnest(?::%s[]) AS %s", field.dbType, field.name),
 		)
 		insertFieldsPart = append(
 			insertFieldsPart,
@@ -111,7 +167,11 @@ func (q *DBSession) UpsertRows(ctx context.Context, table string, conflictField
 		(SELECT ` + strings.Join(unnestPart, ",") + `)
 	INSERT INTO ` + table + `
 		(` + strings.Join(insertFieldsPart, ",") + `)
-	SELECT * from r
+	SELECT * from r`
+	if filter != "" {
+		sql += " WHERE " + filter
+	}
+	sql += `
 	ON CONFLICT (` + conflictField + `) DO UPDATE SET
 		` + strings.Join(onConflictPart, ",")
 	if len(onConflictConditionPart) > 0 {
@@ -129,6 +189,126 @@ func (q *DBSession) UpsertRows(ctx context.Context, table string, conflictField
 	return sqlRes.RowsAffected()
 }
 
+// DeleteRows deletes the rows of table whose keyField matches the values in fields. Conditions compare the values
+// passed in fields with the existing row, e.g. so that a deletion never removes a row written at a later ledger.
+func (q *DBSession) DeleteRows(ctx context.Context, table string, keyField string, fields []UpsertField, conditions []UpsertCondition) (rowsAffected int64, err error) {
+	unnestPart := make([]string, 0, len(fields))
+	pqArrays := make([]interface{}, 0, len(fields))
+	wherePart := []string{fmt.Sprintf("%s.%s = data_source.%s", table, keyField, keyField)}
+
+	for _, field := range fields {
+		unnestPart = append(unnestPart, fmt.Sprintf("unnest(?::%s[]) AS %s", field.dbType, field.name))
+		pqArrays = append(pqArrays, pq.Array(field.objects))
+	}
+	for _, condition := range conditions {
+		if !condition.operator.Valid() {
+			return 0, fmt.Errorf("invalid operator for condition on field %s", condition.column)
+		}
+		wherePart = append(
+			wherePart,
+			fmt.Sprintf("data_source.%s %s %s.%s", condition.column, condition.operator, table, condition.column),
+		)
+	}
+
+	sql := fmt.Sprintf(`
+		WITH data_source AS (
+			SELECT %s
+		)
+		DELETE FROM %s
+		USING data_source
+		WHERE %s`,
+		strings.Join(unnestPart, ", "),
+		table,
+		strings.Join(wherePart, " AND "),
+	)
+
+	sqlRes, err := q.session.ExecRaw(ctx, sql, pqArrays...)
+	if err != nil {
+		return 0, fmt.Errorf("delete rows exec failed: %w", err)
+	}
+	return sqlRes.RowsAffected()
+}
+
+// ApplyBufferedRows copies fields from the rows of bufferTable to the rows of table with the same joinField, for the
+// given keys, and removes those rows from bufferTable. condition can refer to the buffered row as data_source.
+// joinField and fields of the updated rows are selected into dest.
+func (q *DBSession) ApplyBufferedRows(ctx context.Context, dest interface{}, table string, bufferTable string, joinField string, keys []interface{}, fields []string, condition string) error {
+	updateSetPart := make([]string, 0, len(fields))
+	returningPart := []string{fmt.Sprintf("%s.%s", table, joinField)}
+	for _, field := range fields {
+		updateSetPart = append(updateSetPart, fmt.Sprintf("%s = data_source.%s", field, field))
+		returningPart = append(returningPart, fmt.Sprintf("%s.%s", table, field))
+	}
+
+	updateSql := fmt.Sprintf(`
+			UPDATE %s
+			SET %s
+			FROM %s AS data_source
+			WHERE %s.%s = data_source.%s AND data_source.%s = ANY(?::text[])`,
+		table,
+		strings.Join(updateSetPart, ", "),
+		bufferTable,
+		table, joinField, joinField, joinField,
+	)
+	if condition != "" {
+		updateSql += " AND " + condition
+	}
+	updateSql += "\n\t\t\tRETURNING " + strings.Join(returningPart, ", ")
+
+	// Both statements see the buffer as it was before the update, so the rows are applied before being removed
+	sql := fmt.Sprintf(`
+		WITH applied AS (%s
+		), removed AS (
+			DELETE FROM %s
+			WHERE %s = ANY(?::text[])
+		)
+		SELECT * FROM applied`,
+		updateSql,
+		bufferTable,
+		joinField,
+	)
+
+	if err := q.session.SelectRaw(ctx, dest, sql, pq.Array(keys), pq.Array(keys)); err != nil {
+		return fmt.Errorf("apply buffered rows exec failed: %w", err)
+	}
+	return nil
+}
+
+// InsertNewRows inserts the rows whose conflictField matches no existing row and returns the conflictField values
+// of the rows it inserted. A row inserted by a concurrent transaction is waited for and skipped, so each row is
+// reported as new to a single writer.
+func (q *DBSession) InsertNewRows(ctx context.Context, table string, conflictField string, fields []UpsertField) ([]string, error) {
+	unnestPart := make([]string, 0, len(fields))
+	insertFieldsPart := make([]string, 0, len(fields))
+	pqArrays := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		unnestPart = append(unnestPart, fmt.Sprintf("unnest(?::%s[]) AS %s", field.dbType, field.name))
+		insertFieldsPart = append(insertFieldsPart, field.name)
+		pqArrays = append(pqArrays, pq.Array(field.objects))
+	}
+
+	sql := fmt.Sprintf(`
+		WITH r AS
+			(SELECT %s)
+		INSERT INTO %s
+			(%s)
+		SELECT * FROM r
+		ON CONFLICT (%s) DO NOTHING
+		RETURNING %s`,
+		strings.Join(unnestPart, ","),
+		table,
+		strings.Join(insertFieldsPart, ","),
+		conflictField,
+		conflictField,
+	)
+
+	var inserted []string
+	if err := q.session.SelectRaw(ctx, &inserted, sql, pqArrays...); err != nil {
+		return nil, fmt.Errorf("insert new rows exec failed: %w", err)
+	}
+	return inserted, nil
+}
+
 func (q *DBSession) EnrichExistingRows(ctx context.Context, table string, joinField string, fields []UpsertField, condition string) (rowsAffected int64, err error) {
 	unnestPart := make([]string, 0, len(fields))
 	updateSetPart := make([]string, 0, len(fields))
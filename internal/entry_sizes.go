@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+)
+
+// DefaultEntrySizeBatchSize is the number of contract data rows sized per statement
+const DefaultEntrySizeBatchSize = 1000
+
+// storedContractDataEntry rebuilds the ledger entry of a stored contract data row from its key and value
+func storedContractDataEntry(entry db.StoredContractData) (xdr.LedgerEntry, error) {
+	var key, val xdr.ScVal
+	if err := xdr.SafeUnmarshalBase64(string(entry.Key), &key); err != nil {
+		return xdr.LedgerEntry{}, fmt.Errorf("could not decode key of %s: %w", entry.KeyHash, err)
+	}
+	if err := xdr.SafeUnmarshalBase64(string(entry.Val), &val); err != nil {
+		return xdr.LedgerEntry{}, fmt.Errorf("could not decode val of %s: %w", entry.KeyHash, err)
+	}
+	contractIdBytes, err := strkey.Decode(strkey.VersionByteContract, entry.ContractId)
+	if err != nil {
+		return xdr.LedgerEntry{}, fmt.Errorf("could not decode contract id of %s: %w", entry.KeyHash, err)
+	}
+	var contractId xdr.ContractId
+	copy(contractId[:], contractIdBytes)
+	durability := xdr.ContractDataDurabilityTemporary
+	if entry.Durability == "persistent" {
+		durability = xdr.ContractDataDurabilityPersistent
+	}
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: xdr.Uint32(entry.LedgerSequence),
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+				Key:        key,
+				Durability: durability,
+				Val:        val,
+			},
+		},
+	}, nil
+}
+
+// contractDataEntrySize rebuilds the ledger entry of a stored contract data row and returns its XDR size, the same
+// size ingestion records.
+func contractDataEntrySize(entry db.StoredContractData) (uint32, error) {
+	ledgerEntry, err := storedContractDataEntry(entry)
+	if err != nil {
+		return 0, err
+	}
+	ledgerEntryBytes, err := ledgerEntry.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("could not encode ledger entry of %s: %w", entry.KeyHash, err)
+	}
+	return uint32(len(ledgerEntryBytes)), nil
+}
+
+// BackfillEntrySizes records the entry size of the contract data rows indexed before entry_size_bytes was added,
+// batchSize rows per statement. It can run alongside ingestion, rows written in the meantime keep the size of their
+// new version.
+func BackfillEntrySizes(config Config, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var afterKeyHash string
+	var total int64
+	for {
+		entries, err := session.UnsizedContractDataAfter(ctx, afterKeyHash, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+		keyHashes := make([]string, 0, len(entries))
+		sizes := make([]int64, 0, len(entries))
+		for _, entry := range entries {
+			size, err := contractDataEntrySize(entry)
+			if err != nil {
+				return err
+			}
+			keyHashes = append(keyHashes, entry.KeyHash)
+			sizes = append(sizes, int64(size))
+		}
+		sized, err := session.UpdateEntrySizes(ctx, keyHashes, sizes)
+		if err != nil {
+			return err
+		}
+		afterKeyHash = entries[len(entries)-1].KeyHash
+		total += sized
+		Logger.Infof("Sized %d contract data entries, up to key hash %s", total, afterKeyHash)
+	}
+	Logger.Infof("Sized %d contract data entries", total)
+	return nil
+}
@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractDataEntrySize(t *testing.T) {
+	// Admin => void: the 16 bytes of the key and the 4 bytes of the value, plus the 56 bytes of the rest of the entry
+	size, err := contractDataEntrySize(db.StoredContractData{
+		KeyHash:        "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca",
+		ContractId:     "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		Durability:     "persistent",
+		LedgerSequence: 100,
+		Key:            []byte("AAAADwAAAAVBZG1pbgAAAA=="),
+		Val:            []byte("AAAAAQ=="),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(76), size)
+
+	_, err = contractDataEntrySize(db.StoredContractData{KeyHash: "01", ContractId: "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF", Key: []byte("AAAAAQ=="), Val: []byte("AAAAAQ==")})
+	assert.Error(t, err)
+}
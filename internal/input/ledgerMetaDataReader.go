@@ -18,6 +18,8 @@ const (
 
 type LedgerMetadataReader struct {
 	processors         []utils.Processor
+	committer          utils.LedgerCommitter
+	passphrase         string
 	dataStoreConfig    datastore.DataStoreConfig
 	startLedger        uint32
 	endLedger          uint32
@@ -25,21 +27,27 @@ type LedgerMetadataReader struct {
 	maxLedgerInDB      uint32
 	maxLedgerInGalexie uint32
 	metricRecorder     utils.MetricRecorder
+	replayDir          string
 }
 
 func NewLedgerMetadataReader(config *datastore.DataStoreConfig,
 	processors []utils.Processor,
+	committer utils.LedgerCommitter,
+	passphrase string,
 	startLedger uint32,
 	endLedger uint32,
 	backfill bool,
 	maxLedgerInDB uint32,
 	maxLedgerInGalexie uint32,
-	metricRecorder utils.MetricRecorder) (*LedgerMetadataReader, error) {
+	metricRecorder utils.MetricRecorder,
+	replayDir string) (*LedgerMetadataReader, error) {
 	if config == nil {
 		return nil, errors.New("missing configuration")
 	}
 	return &LedgerMetadataReader{
 		processors:         processors,
+		committer:          committer,
+		passphrase:         passphrase,
 		dataStoreConfig:    *config,
 		startLedger:        startLedger,
 		endLedger:          endLedger,
@@ -47,6 +55,7 @@ func NewLedgerMetadataReader(config *datastore.DataStoreConfig,
 		maxLedgerInDB:      maxLedgerInDB,
 		maxLedgerInGalexie: maxLedgerInGalexie,
 		metricRecorder:     metricRecorder,
+		replayDir:          replayDir,
 	}, nil
 }
 
@@ -72,9 +81,11 @@ func GetLedgerBound(startLedger uint32, endLedger uint32, latestNetworkLedger ui
 		return ledgerbackend.Range{}, false
 	}
 
+	// maxLedgerInDB is the ingestion cursor, i.e. the last fully committed ledger,
+	// so ingestion resumes exactly one ledger after it
 	if !backfill && maxLedgerInDB > 0 && startLedger <= maxLedgerInDB {
-		startLedger = maxLedgerInDB
-		logger.Infof("Using max ledger sequence as start")
+		startLedger = maxLedgerInDB + 1
+		logger.Infof("Resuming from ledger %d after last committed ledger %d", startLedger, maxLedgerInDB)
 	}
 
 	isUnbounded := endLedger <= UnboundedSentinel || startLedger <= UnboundedSentinel || startLedger == latestNetworkLedger
@@ -105,6 +116,37 @@ func (a *LedgerMetadataReader) Run(ctx context.Context, Logger *log.Entry) error
 		return nil
 	}
 
+	processLedger := func(lcm xdr.LedgerCloseMeta) error {
+		// Changes are decoded once per ledger and fanned out to every processor
+		ledgerChangeSet, err := utils.NewLedgerChangeSet(a.passphrase, lcm)
+		if err != nil {
+			return err
+		}
+		// Every processor writes a ledger within the same transaction, so a ledger is never half-applied
+		err = a.committer.CommitLedger(ctx, ledgerChangeSet.LedgerSequence(), func() error {
+			for _, processor := range a.processors {
+				if err := processor.Process(ctx, utils.Message{Payload: ledgerChangeSet, LedgerSequence: ledgerChangeSet.LedgerSequence()}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, processor := range a.processors {
+			if listener, ok := processor.(utils.LedgerCommitListener); ok {
+				listener.LedgerCommitted(ctx, ledgerChangeSet.LedgerSequence())
+			}
+		}
+		return nil
+	}
+
+	if a.replayDir != "" {
+		Logger.Infof("Replaying ledgers from local directory %s", a.replayDir)
+		return ApplyLedgerMetadataFromDirectory(ctx, a.replayDir, ledgerRange, processLedger)
+	}
+
 	pubConfig := ingest.PublisherConfig{
 		DataStoreConfig:       a.dataStoreConfig,
 		BufferedStorageConfig: ingest.DefaultBufferedStorageBackendConfig(a.dataStoreConfig.Schema.LedgersPerFile),
@@ -112,13 +154,5 @@ func (a *LedgerMetadataReader) Run(ctx context.Context, Logger *log.Entry) error
 	pubConfig.BufferedStorageConfig.RetryLimit = 20
 	pubConfig.BufferedStorageConfig.RetryWait = 3
 
-	return ingest.ApplyLedgerMetadata(ledgerRange, pubConfig, ctx,
-		func(lcm xdr.LedgerCloseMeta) error {
-			for _, processor := range a.processors {
-				if err := processor.Process(ctx, utils.Message{Payload: lcm}); err != nil {
-					return err
-				}
-			}
-			return nil
-		})
+	return ingest.ApplyLedgerMetadata(ledgerRange, pubConfig, ctx, processLedger)
 }
@@ -76,13 +76,13 @@ func TestGetLedgerBound(t *testing.T) {
 
 		// Database has data, non-backfill mode tests
 		{
-			name:                "DB has data, start < max DB, unbounded - use max DB",
+			name:                "DB has data, start < max DB, unbounded - resume after max DB",
 			startLedger:         2,
 			endLedger:           1, // unbounded
 			latestNetworkLedger: 200,
 			backfill:            false,
 			maxLedgerInDB:       100,
-			expectedRange:       ledgerbackend.UnboundedRange(100),
+			expectedRange:       ledgerbackend.UnboundedRange(101),
 			shouldProceed:       true,
 		},
 		{
@@ -116,13 +116,13 @@ func TestGetLedgerBound(t *testing.T) {
 			shouldProceed:       false,
 		},
 		{
-			name:                "DB has data, start < max DB, bounded, max < end - resume from max",
+			name:                "DB has data, start < max DB, bounded, max < end - resume after max",
 			startLedger:         2,
 			endLedger:           100,
 			latestNetworkLedger: 200,
 			backfill:            false,
 			maxLedgerInDB:       50,
-			expectedRange:       ledgerbackend.BoundedRange(50, 100),
+			expectedRange:       ledgerbackend.BoundedRange(51, 100),
 			shouldProceed:       true,
 		},
 		{
@@ -136,13 +136,13 @@ func TestGetLedgerBound(t *testing.T) {
 			shouldProceed:       true,
 		},
 		{
-			name:                "DB has data, start == max DB, bounded - use requested start",
+			name:                "DB has data, start == max DB, bounded - resume after max",
 			startLedger:         50,
 			endLedger:           100,
 			latestNetworkLedger: 200,
 			backfill:            false,
 			maxLedgerInDB:       50,
-			expectedRange:       ledgerbackend.BoundedRange(50, 100),
+			expectedRange:       ledgerbackend.BoundedRange(51, 100),
 			shouldProceed:       true,
 		},
 
@@ -0,0 +1,172 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// zstdMagic is the frame header every zstd compressed file starts with.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// galexieFileNamePattern matches Galexie object names, e.g. FC6DEEDF--59912480.xdr.zst
+// or FC6DEEDF--59912480-59912543.xdr for files holding more than one ledger.
+var galexieFileNamePattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}--(\d+)(?:-(\d+))?\.xdr(?:\.zst|\.zstd)?$`)
+
+type ledgerFile struct {
+	path  string
+	start uint32
+	end   uint32
+}
+
+// ParseGalexieFileName returns the ledger range covered by a file named with the Galexie naming scheme.
+func ParseGalexieFileName(name string) (uint32, uint32, bool) {
+	matches := galexieFileNamePattern.FindStringSubmatch(name)
+	if matches == nil {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseUint(matches[1], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	end := start
+	if matches[2] != "" {
+		end, err = strconv.ParseUint(matches[2], 10, 32)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+	return uint32(start), uint32(end), true
+}
+
+// listLedgerFiles walks dir, including Galexie partition directories, and returns all
+// ledger files sorted by their first ledger.
+func listLedgerFiles(dir string) ([]ledgerFile, error) {
+	var files []ledgerFile
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		start, end, ok := ParseGalexieFileName(entry.Name())
+		if !ok {
+			return nil
+		}
+		files = append(files, ledgerFile{path: path, start: start, end: end})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list ledger files in %s: %w", dir, err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].start < files[j].start })
+	return files, nil
+}
+
+// ReadLedgerCloseMetaBatch decodes a LedgerCloseMetaBatch file, which may be raw XDR
+// or zstd compressed XDR as written by Galexie.
+func ReadLedgerCloseMetaBatch(path string) (xdr.LedgerCloseMetaBatch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return xdr.LedgerCloseMetaBatch{}, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	if bytes.HasPrefix(data, zstdMagic) {
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return xdr.LedgerCloseMetaBatch{}, fmt.Errorf("could not create zstd decoder: %w", err)
+		}
+		defer decoder.Close()
+		data, err = decoder.DecodeAll(data, nil)
+		if err != nil {
+			return xdr.LedgerCloseMetaBatch{}, fmt.Errorf("could not decompress %s: %w", path, err)
+		}
+	}
+
+	var batch xdr.LedgerCloseMetaBatch
+	if err := batch.UnmarshalBinary(data); err != nil {
+		return xdr.LedgerCloseMetaBatch{}, fmt.Errorf("could not decode LedgerCloseMetaBatch from %s: %w", path, err)
+	}
+	return batch, nil
+}
+
+// LatestLedgerInDirectory returns the highest ledger sequence available in the replay directory.
+func LatestLedgerInDirectory(dir string) (uint32, error) {
+	files, err := listLedgerFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	var latest uint32
+	for _, file := range files {
+		if file.end > latest {
+			latest = file.end
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no ledger files found in %s", dir)
+	}
+	return latest, nil
+}
+
+// ApplyLedgerMetadataFromDirectory replays ledgers stored in dir through callback in ledger order.
+// Unbounded ranges are replayed up to the last ledger available in the directory. Every ledger in
+// the range must be present, so a gap in the replay files results in an error instead of a silent skip.
+func ApplyLedgerMetadataFromDirectory(ctx context.Context, dir string, ledgerRange ledgerbackend.Range, callback func(xdr.LedgerCloseMeta) error) error {
+	files, err := listLedgerFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	next := ledgerRange.From()
+	for _, file := range files {
+		if ledgerRange.Bounded() && next > ledgerRange.To() {
+			break
+		}
+		if file.end < next {
+			continue
+		}
+		if file.start > next {
+			return fmt.Errorf("ledger %d not found in replay directory %s", next, dir)
+		}
+
+		batch, err := ReadLedgerCloseMetaBatch(file.path)
+		if err != nil {
+			return err
+		}
+		for _, lcm := range batch.LedgerCloseMetas {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			sequence := lcm.LedgerSequence()
+			if sequence < next {
+				continue
+			}
+			if ledgerRange.Bounded() && sequence > ledgerRange.To() {
+				break
+			}
+			if sequence != next {
+				return fmt.Errorf("ledger %d not found in replay directory %s", next, dir)
+			}
+			if err := callback(lcm); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+
+	if ledgerRange.Bounded() && next <= ledgerRange.To() {
+		return fmt.Errorf("ledger %d not found in replay directory %s", next, dir)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package input
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGalexieFileName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantStart uint32
+		wantEnd   uint32
+		wantOk    bool
+	}{
+		{"FC6DEEDF--59912480.xdr.zst", 59912480, 59912480, true},
+		{"FC6DEEDF--59912480.xdr.zstd", 59912480, 59912480, true},
+		{"FC6DEEDF--59912480.xdr", 59912480, 59912480, true},
+		{"FC6DEEBF--59912512-59912543.xdr.zst", 59912512, 59912543, true},
+		{"FC6DFFFF--59899904-59963903", 0, 0, false},
+		{".config.json", 0, 0, false},
+		{"FC6DEEBF--59912543-59912512.xdr", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, end, ok := ParseGalexieFileName(tt.name)
+		assert.Equal(t, tt.wantOk, ok, tt.name)
+		assert.Equal(t, tt.wantStart, start, tt.name)
+		assert.Equal(t, tt.wantEnd, end, tt.name)
+	}
+}
+
+func writeLedgerBatch(t *testing.T, path string, start, end uint32, compress bool) {
+	batch := xdr.LedgerCloseMetaBatch{
+		StartSequence: xdr.Uint32(start),
+		EndSequence:   xdr.Uint32(end),
+	}
+	for sequence := start; sequence <= end; sequence++ {
+		batch.LedgerCloseMetas = append(batch.LedgerCloseMetas, xdr.LedgerCloseMeta{
+			V: 0,
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)},
+				},
+			},
+		})
+	}
+	raw, err := batch.MarshalBinary()
+	require.NoError(t, err)
+	if compress {
+		encoder, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		raw = encoder.EncodeAll(raw, nil)
+		require.NoError(t, encoder.Close())
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, raw, 0o644))
+}
+
+func TestApplyLedgerMetadataFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	partition := filepath.Join(dir, "FFFFFFFF--0-63999")
+	writeLedgerBatch(t, filepath.Join(partition, "FFFFFFF5--10.xdr.zst"), 10, 10, true)
+	writeLedgerBatch(t, filepath.Join(partition, "FFFFFFF4--11.xdr"), 11, 11, false)
+	writeLedgerBatch(t, filepath.Join(partition, "FFFFFFF3--12-13.xdr.zst"), 12, 13, true)
+
+	latest, err := LatestLedgerInDirectory(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(13), latest)
+
+	collect := func(ledgerRange ledgerbackend.Range) ([]uint32, error) {
+		var sequences []uint32
+		err := ApplyLedgerMetadataFromDirectory(context.Background(), dir, ledgerRange, func(lcm xdr.LedgerCloseMeta) error {
+			sequences = append(sequences, lcm.LedgerSequence())
+			return nil
+		})
+		return sequences, err
+	}
+
+	sequences, err := collect(ledgerbackend.BoundedRange(11, 12))
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{11, 12}, sequences)
+
+	sequences, err = collect(ledgerbackend.UnboundedRange(10))
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{10, 11, 12, 13}, sequences)
+
+	// Ledgers missing from the directory are reported instead of skipped
+	_, err = collect(ledgerbackend.BoundedRange(9, 11))
+	assert.ErrorContains(t, err, "ledger 9 not found")
+
+	_, err = collect(ledgerbackend.BoundedRange(12, 14))
+	assert.ErrorContains(t, err, "ledger 14 not found")
+}
@@ -0,0 +1,97 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const rpcTimeout = 30 * time.Second
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type getLedgerEntriesResponse struct {
+	Result struct {
+		Entries []struct {
+			XDR                   string `json:"xdr"`
+			LastModifiedLedgerSeq uint32 `json:"lastModifiedLedgerSeq"`
+		} `json:"entries"`
+	} `json:"result"`
+	Error *rpcError `json:"error"`
+}
+
+// FetchConfigSettings reads the current value of every network configuration setting, along with the ledger that
+// last modified it, from the getLedgerEntries method of a Stellar RPC server.
+func FetchConfigSettings(ctx context.Context, rpcURL string) ([]xdr.LedgerEntry, error) {
+	var keys []string
+	for id := int32(0); xdr.ConfigSettingId(id).ValidEnum(id); id++ {
+		key, err := xdr.MarshalBase64(xdr.LedgerKey{
+			Type:          xdr.LedgerEntryTypeConfigSetting,
+			ConfigSetting: &xdr.LedgerKeyConfigSetting{ConfigSettingId: xdr.ConfigSettingId(id)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not encode ledger key of config setting %d: %w", id, err)
+		}
+		keys = append(keys, key)
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "getLedgerEntries",
+		Params:  map[string]interface{}{"keys": keys},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode getLedgerEntries request: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create getLedgerEntries request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", UserAgent)
+	response, err := (&http.Client{Timeout: rpcTimeout}).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("getLedgerEntries request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("getLedgerEntries returned status %d", response.StatusCode)
+	}
+
+	var decoded getLedgerEntriesResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("could not decode getLedgerEntries response: %w", err)
+	}
+	if decoded.Error != nil {
+		return nil, fmt.Errorf("getLedgerEntries failed with code %d: %s", decoded.Error.Code, decoded.Error.Message)
+	}
+
+	entries := make([]xdr.LedgerEntry, 0, len(decoded.Result.Entries))
+	for _, entry := range decoded.Result.Entries {
+		var data xdr.LedgerEntryData
+		if err := xdr.SafeUnmarshalBase64(entry.XDR, &data); err != nil {
+			return nil, fmt.Errorf("could not decode config setting entry: %w", err)
+		}
+		if data.Type != xdr.LedgerEntryTypeConfigSetting {
+			return nil, fmt.Errorf("getLedgerEntries returned a %s entry instead of a config setting", data.Type)
+		}
+		entries = append(entries, xdr.LedgerEntry{LastModifiedLedgerSeq: xdr.Uint32(entry.LastModifiedLedgerSeq), Data: data})
+	}
+	return entries, nil
+}
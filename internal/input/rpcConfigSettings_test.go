@@ -0,0 +1,60 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchConfigSettings(t *testing.T) {
+	maxSize := xdr.Uint32(131072)
+	data := xdr.LedgerEntryData{
+		Type: xdr.LedgerEntryTypeConfigSetting,
+		ConfigSetting: &xdr.ConfigSettingEntry{
+			ConfigSettingId:      xdr.ConfigSettingIdConfigSettingContractMaxSizeBytes,
+			ContractMaxSizeBytes: &maxSize,
+		},
+	}
+	dataXDR, err := xdr.MarshalBase64(data)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			Params struct {
+				Keys []string `json:"keys"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "getLedgerEntries", request.Method)
+		// Every config setting is requested
+		var firstKey xdr.LedgerKey
+		require.NoError(t, xdr.SafeUnmarshalBase64(request.Params.Keys[0], &firstKey))
+		assert.Equal(t, xdr.ConfigSettingIdConfigSettingContractMaxSizeBytes, firstKey.MustConfigSetting().ConfigSettingId)
+		assert.Greater(t, len(request.Params.Keys), int(xdr.ConfigSettingIdConfigSettingStateArchival))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"entries":[{"key":"","xdr":"` + dataXDR + `","lastModifiedLedgerSeq":50}],"latestLedger":100}}`))
+	}))
+	defer server.Close()
+
+	entries, err := FetchConfigSettings(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []xdr.LedgerEntry{{LastModifiedLedgerSeq: 50, Data: data}}, entries)
+}
+
+func TestFetchConfigSettingsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid keys"}}`))
+	}))
+	defer server.Close()
+
+	_, err := FetchConfigSettings(context.Background(), server.URL)
+	assert.EqualError(t, err, "getLedgerEntries failed with code -32602: invalid keys")
+}
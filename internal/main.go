@@ -6,12 +6,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 
 	"github.com/go-errors/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stellar/go-stellar-sdk/support/datastore"
 	supporthttp "github.com/stellar/go-stellar-sdk/support/http"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/db"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/input"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/transform"
@@ -26,27 +29,45 @@ func PostgresConnString(cfg PostgresConfig) string {
 }
 
 func getProcessor(dataset string, outboundAdapters []utils.OutboundAdapter, passPhrase string, metricRecorder utils.MetricRecorder) (processor utils.Processor, err error) {
+	base := utils.BaseProcessor{
+		OutboundAdapters: outboundAdapters,
+		Logger:           Logger,
+		Passphrase:       passPhrase,
+		MetricRecorder:   metricRecorder,
+	}
 	switch dataset {
 	case "contract_data":
-		processor := &transform.ContractDataProcessor{
-			BaseProcessor: utils.BaseProcessor{
-				OutboundAdapters: outboundAdapters,
-				Logger:           Logger,
-				Passphrase:       passPhrase,
-				MetricRecorder:   metricRecorder,
-			},
-		}
-		return processor, nil
+		return &transform.ContractDataProcessor{BaseProcessor: base}, nil
+	case "address_references":
+		return &transform.AddressReferenceProcessor{BaseProcessor: base}, nil
+	case "contract_data_history":
+		return &transform.ContractDataHistoryProcessor{BaseProcessor: base}, nil
+	case "contract_code":
+		return &transform.ContractCodeProcessor{BaseProcessor: base}, nil
+	case "contract_events":
+		return &transform.ContractEventProcessor{BaseProcessor: base}, nil
+	case "contract_invocations":
+		return &transform.ContractInvocationProcessor{BaseProcessor: base}, nil
+	case "contracts":
+		return &transform.ContractProcessor{BaseProcessor: base}, nil
+	case "contract_upgrades":
+		return &transform.ContractUpgradeProcessor{BaseProcessor: base}, nil
+	case "contract_metadata":
+		return &transform.ContractMetadataProcessor{BaseProcessor: base}, nil
+	case "contract_spec_entries":
+		return &transform.ContractSpecEntryProcessor{BaseProcessor: base}, nil
+	case "token_transfers":
+		return &transform.TokenTransferProcessor{BaseProcessor: base}, nil
+	case "config_settings":
+		return &transform.ConfigSettingProcessor{BaseProcessor: base}, nil
+	case "sac_balances":
+		return &transform.SACBalanceProcessor{BaseProcessor: base}, nil
+	case "sac_assets":
+		return &transform.SACAssetProcessor{BaseProcessor: base}, nil
+	case "ledgers":
+		return &transform.LedgerProcessor{BaseProcessor: base}, nil
 	case "ttl":
-		processor := &transform.TTLDataProcessor{
-			BaseProcessor: utils.BaseProcessor{
-				OutboundAdapters: outboundAdapters,
-				Logger:           Logger,
-				Passphrase:       passPhrase,
-				MetricRecorder:   metricRecorder,
-			},
-		}
-		return processor, nil
+		return &transform.TTLDataProcessor{BaseProcessor: base}, nil
 	default:
 		return nil, fmt.Errorf("unsupported dataset: %s", dataset)
 	}
@@ -71,17 +92,81 @@ func getPostgresSession(ctx context.Context, postgresConfig PostgresConfig) (*db
 	return session, nil
 }
 
-func getPostgresOutputAdapter(ctx context.Context, config Config, dataset string, metricRecorder utils.MetricRecorder) (*utils.PostgresAdapter, error) {
-	session, err := getPostgresSession(ctx, config.PostgresConfig)
+// getExpiryWatchlist loads the watched entries as of the last indexed ledger and opens the configured notifiers
+func getExpiryWatchlist(ctx context.Context, session *db.DBSession, watchlistConfig ExpiryWatchlistConfig) (*transform.ExpiryWatchlist, []utils.ExpiryNotifier, error) {
+	entries, err := session.WatchedEntries(ctx, watchlistConfig.ContractIds, watchlistConfig.KeyHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	var notifiers []utils.ExpiryNotifier
+	if watchlistConfig.WebhookURL != "" {
+		notifiers = append(notifiers, utils.NewWebhookExpiryNotifier(watchlistConfig.WebhookURL))
+	}
+	if watchlistConfig.NDJSONPath != "" {
+		notifier, err := utils.NewNDJSONExpiryNotifier(watchlistConfig.NDJSONPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	Logger.Infof("Watching %d entries for expiry, notifying %d ledgers before their live until ledger", len(entries), watchlistConfig.ThresholdLedgers)
+	watchlist := transform.NewExpiryWatchlist(watchlistConfig.ContractIds, watchlistConfig.KeyHashes, watchlistConfig.ThresholdLedgers, entries)
+	return watchlist, notifiers, nil
+}
+
+// getInitialConfigSettings reads the current network configuration settings that have no indexed version yet
+func getInitialConfigSettings(ctx context.Context, session *db.DBSession, rpcURL string) ([]xdr.LedgerEntry, error) {
+	entries, err := input.FetchConfigSettings(ctx, rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	indexedIds, err := session.IndexedConfigSettingIds(ctx)
 	if err != nil {
-		Logger.Fatal(err)
 		return nil, err
 	}
+	var initialSettings []xdr.LedgerEntry
+	for _, entry := range entries {
+		if !slices.Contains(indexedIds, contract.ConfigSettingIdName(entry.Data.MustConfigSetting().ConfigSettingId)) {
+			initialSettings = append(initialSettings, entry)
+		}
+	}
+	Logger.Infof("Read %d config settings from %s, %d of them have no indexed version", len(entries), rpcURL, len(initialSettings))
+	return initialSettings, nil
+}
 
+func getPostgresOutputAdapter(session *db.DBSession, dataset string, indexerConfig IndexerConfig, metricRecorder utils.MetricRecorder) (*utils.PostgresAdapter, error) {
 	var dbOperator utils.DBOperator
 	switch dataset {
 	case "contract_data":
-		dbOperator = db.NewContractDataDBOperator(*session, metricRecorder)
+		dbOperator = db.NewContractDataDBOperator(*session, metricRecorder, indexerConfig.DeletedEntries == DeletedEntriesRemove)
+	case "address_references":
+		dbOperator = db.NewAddressReferenceDBOperator(*session, metricRecorder)
+	case "contract_data_history":
+		dbOperator = db.NewContractDataHistoryDBOperator(*session, metricRecorder)
+	case "contract_code":
+		dbOperator = db.NewContractCodeDBOperator(*session, metricRecorder, indexerConfig.DeletedEntries == DeletedEntriesRemove)
+	case "contract_events":
+		dbOperator = db.NewContractEventDBOperator(*session, metricRecorder)
+	case "contract_invocations":
+		dbOperator = db.NewContractInvocationDBOperator(*session, metricRecorder)
+	case "contracts":
+		dbOperator = db.NewContractDBOperator(*session, metricRecorder)
+	case "contract_upgrades":
+		dbOperator = db.NewContractUpgradeDBOperator(*session, metricRecorder)
+	case "contract_metadata":
+		dbOperator = db.NewContractMetadataDBOperator(*session, metricRecorder)
+	case "contract_spec_entries":
+		dbOperator = db.NewContractSpecEntryDBOperator(*session, metricRecorder)
+	case "token_transfers":
+		dbOperator = db.NewTokenTransferDBOperator(*session, metricRecorder)
+	case "config_settings":
+		dbOperator = db.NewConfigSettingDBOperator(*session, metricRecorder)
+	case "sac_balances":
+		dbOperator = db.NewSACBalanceDBOperator(*session, metricRecorder)
+	case "sac_assets":
+		dbOperator = db.NewSACAssetDBOperator(*session, metricRecorder)
+	case "ledgers":
+		dbOperator = db.NewLedgerDBOperator(*session, metricRecorder)
 	case "ttl":
 		dbOperator = db.NewTTLDBOperator(*session, metricRecorder)
 	default:
@@ -91,6 +176,28 @@ func getPostgresOutputAdapter(ctx context.Context, config Config, dataset string
 	return postgresAdapter, nil
 }
 
+// getLastCommittedLedger returns the lowest ingestion cursor across datasets, so that
+// resuming never skips a ledger for a dataset that is behind the others.
+// Datasets without a cursor yet, e.g. ones added after the indexer was deployed, start from the
+// resume point of the others; their history has to be filled with a backfill.
+func getLastCommittedLedger(ctx context.Context, dbOperators []utils.DBOperator) (uint32, error) {
+	var lastCommittedLedger uint32
+	for _, dbOperator := range dbOperators {
+		cursor, err := dbOperator.GetIngestionCursor(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if cursor == 0 {
+			Logger.Infof("No ingestion cursor found for %s", dbOperator.TableName())
+			continue
+		}
+		if lastCommittedLedger == 0 || cursor < lastCommittedLedger {
+			lastCommittedLedger = cursor
+		}
+	}
+	return lastCommittedLedger, nil
+}
+
 func newAdminServer(adminPort int, prometheusRegistry *prometheus.Registry) *http.Server {
 	mux := supporthttp.NewMux(Logger)
 	mux.Handle("/metrics", promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{}))
@@ -115,20 +222,26 @@ func IndexData(config Config) {
 		}
 	}()
 
-	dataStore, err := datastore.NewGCSDataStore(ctx, config.DataStoreConfig)
+	metricRecorder := utils.GetNewMetricRecorder(ctx, Logger, registry, nameSpace)
+
+	// All datasets share a single session so that every processor's writes for a ledger,
+	// together with the ingestion cursors, are committed in one transaction
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
 	if err != nil {
-		Logger.Fatal("failed to create GCS data store:", err)
+		Logger.Fatal(err)
 		return
 	}
-	metricRecorder := utils.GetNewMetricRecorder(ctx, Logger, registry, nameSpace)
+	defer session.Close()
 
-	var outboundAdapters []utils.PostgresAdapter
+	var dbOperators []utils.DBOperator
 	var processors []utils.Processor
-	// Order is important here, as contract data entries needs to be processed before ttl entries
-	// ttl entries are enrichment to base contract data
-	datasets := []string{"contract_data", "ttl"}
+	// Order is important here, as contract data and contract code entries needs to be processed before ttl entries
+	// ttl entries are enrichment to base contract data and contract code
+	datasets := []string{"contract_data", "address_references", "contract_code", "contract_events", "contract_invocations", "contracts", "contract_upgrades", "contract_metadata", "contract_spec_entries", "token_transfers", "config_settings", "sac_balances", "sac_assets", "ledgers"}
+	datasets = append(datasets, config.IndexerConfig.OptionalDatasets...)
+	datasets = append(datasets, "ttl")
 	for _, dataset := range datasets {
-		postgresAdapter, err := getPostgresOutputAdapter(ctx, config, dataset, metricRecorder)
+		postgresAdapter, err := getPostgresOutputAdapter(session, dataset, config.IndexerConfig, metricRecorder)
 		if err != nil {
 			Logger.Fatal(err)
 			return
@@ -138,58 +251,108 @@ func IndexData(config Config) {
 			Logger.Fatal(err)
 			return
 		}
+		if ttlProcessor, ok := processor.(*transform.TTLDataProcessor); ok && config.ExpiryWatchlist.Enabled() {
+			if config.Backfill {
+				Logger.Infof("Expiry watchlist is disabled in backfill mode")
+			} else {
+				var notifiers []utils.ExpiryNotifier
+				ttlProcessor.Watchlist, notifiers, err = getExpiryWatchlist(ctx, session, config.ExpiryWatchlist)
+				if err != nil {
+					Logger.Fatal(err)
+					return
+				}
+				// Notifications are recorded once delivered, in the background, outside of the ledger transactions
+				ttlProcessor.NotificationSender = utils.NewExpiryNotificationSender(ctx, notifiers, session.Clone(), Logger)
+				defer ttlProcessor.NotificationSender.Close()
+			}
+		}
 
-		outboundAdapters = append(outboundAdapters, *postgresAdapter)
+		if configSettingProcessor, ok := processor.(*transform.ConfigSettingProcessor); ok && config.IndexerConfig.RPCURL != "" {
+			configSettingProcessor.InitialSettings, err = getInitialConfigSettings(ctx, session, config.IndexerConfig.RPCURL)
+			if err != nil {
+				Logger.Fatal(err)
+				return
+			}
+		}
+
+		dbOperators = append(dbOperators, postgresAdapter.DBOperator)
 		processors = append(processors, processor)
 	}
+	ledgerCommitter := &utils.PostgresLedgerCommitter{
+		Session:     session.Session(),
+		DBOperators: dbOperators,
+		Logger:      Logger,
+		Backfill:    config.Backfill,
+	}
 
-	metricsAdapter, err := getPostgresOutputAdapter(ctx, config, "contract_data", metricRecorder)
+	// Metrics are scraped concurrently with ingestion, so they use their own session
+	// instead of the one holding the open ledger transaction
+	metricsSession, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		Logger.Fatal(err)
+		return
+	}
+	defer metricsSession.Close()
+	metricsAdapter, err := getPostgresOutputAdapter(metricsSession, "contract_data", config.IndexerConfig, metricRecorder)
 	if err != nil {
 		Logger.Fatal(err)
 		return
 	}
-	outboundAdapters = append(outboundAdapters, *metricsAdapter)
 
-	metricRecorder.RegisterMaxLedgerSequenceInGalexieMetric(ctx, registry, nameSpace, dataStore)
 	metricRecorder.RegisterMaxLedgerSequenceIndexedMetric(ctx, registry, nameSpace, metricsAdapter.DBOperator)
 
-	// Ensure adapters are closed on all exit paths
-	defer func() {
-		for _, adapter := range outboundAdapters {
-			adapter.Close()
-		}
-	}()
-
 	// Query max ledger sequence from database if not in backfill mode
 	var maxLedgerInDB uint32
 	if config.Backfill {
 		maxLedgerInDB = 0
 		Logger.Infof("Backfill mode enabled: Using exact start=%d and end=%d ledgers as provided", config.StartLedger, config.EndLedger)
 	} else {
-		// Both outbound adapters write to the same database, so querying from the first is sufficient
-		maxLedgerInDB, err = metricsAdapter.GetMaxLedgerSequence(ctx)
+		maxLedgerInDB, err = getLastCommittedLedger(ctx, dbOperators)
 		if err != nil {
-			Logger.Errorf("Failed to get max ledger sequence from database: %v. Proceeding with requested start ledger.", err)
+			Logger.Errorf("Failed to get ingestion cursor from database: %v. Proceeding with requested start ledger.", err)
 			maxLedgerInDB = 0
 		} else {
-			Logger.Infof("Max ledger sequence in database: %d", maxLedgerInDB)
+			Logger.Infof("Last committed ledger sequence in database: %d", maxLedgerInDB)
 		}
 	}
-	maxLedgerInGalexie, err := datastore.FindLatestLedgerSequence(ctx, dataStore)
-	if err != nil {
-		Logger.Fatal("failed to fetch latest ledger sequence from Galexie:", err)
-		return
+	var maxLedgerInGalexie uint32
+	if config.ReplayDir != "" {
+		// Replay mode reads LedgerCloseMetaBatch files from a local directory and needs no datastore
+		maxLedgerInGalexie, err = input.LatestLedgerInDirectory(config.ReplayDir)
+		if err != nil {
+			Logger.Fatal("failed to find latest ledger sequence in replay directory:", err)
+			return
+		}
+	} else {
+		// The datastore is built from datastore_config.type so that the indexer can read
+		// Galexie files from GCS, S3 (or S3-compatible endpoints) and local filesystem mirrors.
+		dataStore, err := datastore.NewDataStore(ctx, config.DataStoreConfig)
+		if err != nil {
+			Logger.Fatalf("failed to create %s data store: %v", config.DataStoreConfig.Type, err)
+			return
+		}
+		defer dataStore.Close()
+		metricRecorder.RegisterMaxLedgerSequenceInGalexieMetric(ctx, registry, nameSpace, dataStore)
+
+		maxLedgerInGalexie, err = datastore.FindLatestLedgerSequence(ctx, dataStore)
+		if err != nil {
+			Logger.Fatal("failed to fetch latest ledger sequence from Galexie:", err)
+			return
+		}
 	}
 
 	reader, err := input.NewLedgerMetadataReader(
 		&config.DataStoreConfig,
 		processors,
+		ledgerCommitter,
+		config.StellarCoreConfig.NetworkPassphrase,
 		config.StartLedger,
 		config.EndLedger,
 		config.Backfill,
 		maxLedgerInDB,
 		maxLedgerInGalexie,
 		metricRecorder,
+		config.ReplayDir,
 	)
 	if err != nil {
 		Logger.Fatal(err)
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+)
+
+// DefaultRentExtendLedgers is about 31 days of ledgers closing every 5 seconds
+const DefaultRentExtendLedgers = 535680
+
+// RentSettings are the options of the estimate-rent command
+type RentSettings struct {
+	// ExtendLedgers is the number of ledgers past the last indexed ledger to extend the TTL of the live entries to
+	ExtendLedgers uint32
+	// ContractId restricts the estimate to a single contract, printed per entry. When empty, the estimate of every
+	// contract is written to contract_rent_estimates.
+	ContractId string
+	// FeeOverrides replaces the indexed rent parameters that are set, e.g. when the settings were last upgraded
+	// before the first indexed ledger
+	FeeOverrides contract.RentFeeConfiguration
+}
+
+// rentFeeConfiguration applies the overrides to the indexed rent parameters. Before protocol 23 the write fee per
+// 1KB was the per ledger fee rent is computed from, it is used when no flat write fee was indexed.
+func rentFeeConfiguration(indexed contract.RentFeeConfiguration, overrides contract.RentFeeConfiguration) (contract.RentFeeConfiguration, error) {
+	configuration := indexed
+	if overrides.FeeRent1Kb != 0 {
+		configuration.FeeRent1Kb = overrides.FeeRent1Kb
+	}
+	if overrides.FeeWrite1Kb != 0 {
+		configuration.FeeWrite1Kb = overrides.FeeWrite1Kb
+	}
+	if overrides.FeeWriteLedgerEntry != 0 {
+		configuration.FeeWriteLedgerEntry = overrides.FeeWriteLedgerEntry
+	}
+	if overrides.PersistentRentRateDenominator != 0 {
+		configuration.PersistentRentRateDenominator = overrides.PersistentRentRateDenominator
+	}
+	if overrides.TemporaryRentRateDenominator != 0 {
+		configuration.TemporaryRentRateDenominator = overrides.TemporaryRentRateDenominator
+	}
+	if configuration.FeeWrite1Kb == 0 {
+		configuration.FeeWrite1Kb = configuration.FeeRent1Kb
+	}
+	if err := configuration.Validate(); err != nil {
+		return contract.RentFeeConfiguration{}, fmt.Errorf("rent parameters are not indexed, index the ledgers and "+
+			"config_settings datasets from the last upgrade of the settings or pass them as flags: %w", err)
+	}
+	return configuration, nil
+}
+
+// EstimateRent estimates the rent to extend the TTL of the live contract storage up to settings.ExtendLedgers past
+// the last indexed ledger, using the rent parameters in force as of that ledger. Entries are only charged for the
+// ledgers their TTL falls short of that target.
+func EstimateRent(config Config, settings RentSettings, out io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+
+	session, err := getPostgresSession(ctx, config.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	indexed, err := session.IndexedRentFeeConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	fees, err := rentFeeConfiguration(indexed, settings.FeeOverrides)
+	if err != nil {
+		return err
+	}
+	ledgerSequence, err := session.IndexedStorageLedger(ctx)
+	if err != nil {
+		return err
+	}
+	if ledgerSequence == 0 {
+		return fmt.Errorf("contract_data and ttl have not been indexed yet")
+	}
+
+	if settings.ContractId != "" {
+		entries, err := session.LiveContractDataSizes(ctx, settings.ContractId)
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "KEY_HASH\tDURABILITY\tKEY_SYMBOL\tSIZE_BYTES\tLIVE_UNTIL\tRENT_FEE")
+		var total int64
+		for _, entry := range entries {
+			var rentFee int64
+			if extension := contract.ExtensionLedgers(entry.LiveUntilLedgerSequence, ledgerSequence, settings.ExtendLedgers); extension > 0 {
+				rentFee = fees.EntryRentFee(entry.Durability == "persistent", entry.EntrySizeBytes, extension)
+			}
+			total += rentFee
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%d\n", entry.KeyHash, entry.Durability, entry.KeySymbol,
+				entry.EntrySizeBytes, entry.LiveUntilLedgerSequence, rentFee)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%d live entries of %s, extending to %d ledgers past ledger %d costs %d stroops\n",
+			len(entries), settings.ContractId, settings.ExtendLedgers, ledgerSequence, total)
+		return nil
+	}
+
+	sizes, err := session.LiveContractStorageSizes(ctx, ledgerSequence+settings.ExtendLedgers)
+	if err != nil {
+		return err
+	}
+	estimates := make([]contract.ContractRentEstimate, 0, len(sizes))
+	for _, size := range sizes {
+		estimates = append(estimates, fees.EstimateContractRent(size, settings.ExtendLedgers))
+	}
+	if err := session.ReplaceContractRentEstimates(ctx, ledgerSequence, estimates); err != nil {
+		return err
+	}
+	var total int64
+	for _, estimate := range estimates {
+		total += estimate.RentFee
+	}
+	Logger.Infof("Estimated rent of %d contracts at ledger %d, extending to %d ledgers past it costs %d stroops in total",
+		len(estimates), ledgerSequence, settings.ExtendLedgers, total)
+	return nil
+}
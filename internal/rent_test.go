@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRentFeeConfiguration(t *testing.T) {
+	indexed := contract.RentFeeConfiguration{
+		FeeRent1Kb:                    10000,
+		FeeWriteLedgerEntry:           1000,
+		PersistentRentRateDenominator: 2103,
+		TemporaryRentRateDenominator:  4206,
+	}
+
+	// Without an indexed flat write fee, the per ledger fee is used
+	configuration, err := rentFeeConfiguration(indexed, contract.RentFeeConfiguration{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), configuration.FeeWrite1Kb)
+
+	configuration, err = rentFeeConfiguration(indexed, contract.RentFeeConfiguration{FeeWrite1Kb: 3500, PersistentRentRateDenominator: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, contract.RentFeeConfiguration{
+		FeeRent1Kb:                    10000,
+		FeeWrite1Kb:                   3500,
+		FeeWriteLedgerEntry:           1000,
+		PersistentRentRateDenominator: 1000,
+		TemporaryRentRateDenominator:  4206,
+	}, configuration)
+
+	indexed.TemporaryRentRateDenominator = 0
+	_, err = rentFeeConfiguration(indexed, contract.RentFeeConfiguration{})
+	assert.ErrorContains(t, err, "temporary_rent_rate_denominator")
+}
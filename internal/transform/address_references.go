@@ -0,0 +1,34 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// AddressReferenceProcessor sends the contract data entries changed in a ledger, including removed ones, so that
+// the addresses found in their keys and values replace the ones previously recorded for them.
+type AddressReferenceProcessor struct {
+	utils.BaseProcessor
+}
+
+func (p *AddressReferenceProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contracts, err := ledgerContractData(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("address_references", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract data entries for address references in ledger sequence %d", len(contracts), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, tx := range contracts {
+		data = append(data, tx)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
@@ -0,0 +1,92 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// ConfigSettingProcessor sends the network configuration settings changed in a ledger, which only happens on upgrades
+type ConfigSettingProcessor struct {
+	utils.BaseProcessor
+	// InitialSettings are the current settings without an indexed version, along with the ledger that last
+	// modified them. Each is recorded as of the first processed ledger it was already in force at.
+	InitialSettings []xdr.LedgerEntry
+}
+
+func GetConfigSettingDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.ConfigSettingOutput, error) {
+	configSettingOutputs := []contract.ConfigSettingOutput{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeConfigSetting {
+			continue
+		}
+
+		configSettingOutput, ok, err := contract.TransformConfigSetting(change, lhe)
+		if err != nil {
+			return configSettingOutputs, fmt.Errorf("could not transform config setting %w", err)
+		}
+		if !ok {
+			continue
+		}
+		configSettingOutputs = append(configSettingOutputs, configSettingOutput)
+	}
+
+	// A ledger can hold several upgrades of the same setting, only its value at the end of the ledger is kept
+	configSettingOutputs = utils.RemoveDuplicatesByFields(configSettingOutputs, []string{"ConfigSettingId"})
+	return configSettingOutputs, nil
+}
+
+func (p *ConfigSettingProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+	// Upgrades in the ledger come last, so they take precedence over the initial value of the same setting
+	changes := append(p.initialSettingChanges(uint32(lhe.Header.LedgerSeq)), ledgerChangeSet.Changes(xdr.LedgerEntryTypeConfigSetting)...)
+
+	configSettings, err := GetConfigSettingDetails(changes, lhe)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("config_settings", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d config settings in ledger sequence %d", len(configSettings), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, setting := range configSettings {
+		data = append(data, setting)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
+
+// initialSettingChanges returns the initial settings in force at the ledger as changes of that ledger
+func (p *ConfigSettingProcessor) initialSettingChanges(ledgerSequence uint32) []ingest.Change {
+	var changes []ingest.Change
+	for index := range p.InitialSettings {
+		entry := &p.InitialSettings[index]
+		if uint32(entry.LastModifiedLedgerSeq) > ledgerSequence {
+			continue
+		}
+		changes = append(changes, ingest.Change{
+			Type:       xdr.LedgerEntryTypeConfigSetting,
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryState,
+			Post:       entry,
+		})
+	}
+	return changes
+}
+
+// LedgerCommitted drops the initial settings recorded with the committed ledger
+func (p *ConfigSettingProcessor) LedgerCommitted(ctx context.Context, ledgerSequence uint32) {
+	var pending []xdr.LedgerEntry
+	for _, entry := range p.InitialSettings {
+		if uint32(entry.LastModifiedLedgerSeq) > ledgerSequence {
+			pending = append(pending, entry)
+		}
+	}
+	p.InitialSettings = pending
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfigSettingDetails(t *testing.T) {
+	makeChange := func(maxSize xdr.Uint32) ingest.Change {
+		return ingest.Change{
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+			Type:       xdr.LedgerEntryTypeConfigSetting,
+			Post: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeConfigSetting,
+					ConfigSetting: &xdr.ConfigSettingEntry{
+						ConfigSettingId:      xdr.ConfigSettingIdConfigSettingContractMaxSizeBytes,
+						ContractMaxSizeBytes: &maxSize,
+					},
+				},
+			},
+		}
+	}
+	// The same setting upgraded twice in a ledger only keeps its final value
+	changes := []ingest.Change{makeChange(65536), makeChange(131072)}
+	valueXDR, err := xdr.MarshalBase64(changes[1].Post.Data.MustConfigSetting())
+	require.NoError(t, err)
+
+	actualOutput, err := GetConfigSettingDetails(changes, makeContractEventTestHeader())
+	require.NoError(t, err)
+	assert.Equal(t, []contract.ConfigSettingOutput{
+		{
+			ConfigSettingId: "contract_max_size_bytes",
+			LedgerSequence:  10,
+			ClosedAt:        time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+			Value:           "131072",
+			ValueXDR:        valueXDR,
+		},
+	}, actualOutput)
+}
+
+func TestConfigSettingProcessorInitialSettings(t *testing.T) {
+	makeEntry := func(lastModified xdr.Uint32, maxSize xdr.Uint32) xdr.LedgerEntry {
+		return xdr.LedgerEntry{
+			LastModifiedLedgerSeq: lastModified,
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeConfigSetting,
+				ConfigSetting: &xdr.ConfigSettingEntry{
+					ConfigSettingId:      xdr.ConfigSettingIdConfigSettingContractMaxSizeBytes,
+					ContractMaxSizeBytes: &maxSize,
+				},
+			},
+		}
+	}
+	inForce := makeEntry(5, 65536)
+	notYetInForce := makeEntry(20, 131072)
+	processor := &ConfigSettingProcessor{InitialSettings: []xdr.LedgerEntry{inForce, notYetInForce}}
+
+	// Only the setting already in force at the ledger is recorded, until the ledger is committed
+	changes := processor.initialSettingChanges(10)
+	require.Len(t, changes, 1)
+	assert.Equal(t, inForce, *changes[0].Post)
+	actualOutput, err := GetConfigSettingDetails(changes, makeContractEventTestHeader())
+	require.NoError(t, err)
+	require.Len(t, actualOutput, 1)
+	assert.Equal(t, "65536", actualOutput[0].Value)
+	assert.Len(t, processor.initialSettingChanges(10), 1)
+
+	processor.LedgerCommitted(context.Background(), 10)
+	assert.Equal(t, []xdr.LedgerEntry{notYetInForce}, processor.InitialSettings)
+	assert.Empty(t, processor.initialSettingChanges(11))
+}
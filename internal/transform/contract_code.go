@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractCodeProcessor struct {
+	utils.BaseProcessor
+}
+
+func GetContractCodeDetails(changes []utils.SourcedChange, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.ContractCodeOutput, error) {
+	contractCodeOutputs := []contract.ContractCodeOutput{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeContractCode {
+			continue
+		}
+
+		contractCodeOutput, err := contract.TransformContractCode(change.Change, lhe)
+		if err != nil {
+			return contractCodeOutputs, fmt.Errorf("could not transform contract code %w", err)
+		}
+		contractCodeOutput.Evicted = change.Source.Evicted
+
+		contractCodeOutputs = append(contractCodeOutputs, contractCodeOutput)
+	}
+
+	// The same Wasm can be uploaded or have its entry restored more than once in a single ledger
+	contractCodeOutputs = utils.RemoveDuplicatesByFields(contractCodeOutputs, []string{"ContractCodeHash", "LedgerSequence"})
+	return contractCodeOutputs, nil
+}
+
+func (p *ContractCodeProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+	changes := ledgerChangeSet.SourcedChanges(xdr.LedgerEntryTypeContractCode)
+
+	contractCodes, err := GetContractCodeDetails(changes, lhe)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_code", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract codes in ledger sequence %d", len(contractCodes), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, code := range contractCodes {
+		data = append(data, code)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+
+}
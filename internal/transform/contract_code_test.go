@@ -0,0 +1,120 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContractCodeDetails(t *testing.T) {
+	type transformTest struct {
+		input      []utils.SourcedChange
+		wantOutput []contract.ContractCodeOutput
+		wantErr    error
+	}
+
+	tests := []transformTest{
+		{
+			[]utils.SourcedChange{
+				{Change: ingest.Change{
+					ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+					Type:       xdr.LedgerEntryTypeOffer,
+					Pre:        nil,
+					Post: &xdr.LedgerEntry{
+						Data: xdr.LedgerEntryData{
+							Type: xdr.LedgerEntryTypeOffer,
+						},
+					},
+				}},
+			},
+			// Any non contract code (eg: LedgerEntryTypeOffer) is skipped
+			[]contract.ContractCodeOutput{}, nil,
+		},
+		{
+			makeContractCodeTestInput(),
+			makeContractCodeTestOutput(),
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		header := xdr.LedgerHeaderHistoryEntry{
+			Header: xdr.LedgerHeader{
+				ScpValue: xdr.StellarValue{
+					CloseTime: 1000,
+				},
+				LedgerSeq: 10,
+			},
+		}
+		actualOutput, actualError := GetContractCodeDetails(test.input, header)
+		assert.Equal(t, test.wantErr, actualError)
+		assert.Equal(t, test.wantOutput, actualOutput)
+	}
+}
+
+func makeContractCodeTestInput() []utils.SourcedChange {
+	var hash xdr.Hash
+	for i := range hash {
+		hash[i] = 1
+	}
+
+	contractCodeLedgerEntry := xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 9,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractCode,
+			ContractCode: &xdr.ContractCodeEntry{
+				Ext: xdr.ContractCodeEntryExt{
+					V: 1,
+					V1: &xdr.ContractCodeEntryV1{
+						CostInputs: xdr.ContractCodeCostInputs{
+							NInstructions: 100,
+							NFunctions:    5,
+							NExports:      3,
+						},
+					},
+				},
+				Hash: hash,
+				Code: []byte{0x00, 0x61, 0x73, 0x6d},
+			},
+		},
+	}
+
+	// The same code uploaded twice in a ledger is only recorded once
+	return []utils.SourcedChange{
+		{Change: ingest.Change{
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+			Type:       xdr.LedgerEntryTypeContractCode,
+			Pre:        nil,
+			Post:       &contractCodeLedgerEntry,
+		}},
+		{Change: ingest.Change{
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+			Type:       xdr.LedgerEntryTypeContractCode,
+			Pre:        &contractCodeLedgerEntry,
+			Post:       &contractCodeLedgerEntry,
+		}},
+	}
+}
+
+func makeContractCodeTestOutput() []contract.ContractCodeOutput {
+	return []contract.ContractCodeOutput{
+		{
+			ContractCodeHash:   "0101010101010101010101010101010101010101010101010101010101010101",
+			ContractCodeExtV:   1,
+			LastModifiedLedger: 9,
+			LedgerEntryChange:  1,
+			Deleted:            false,
+			ClosedAt:           time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+			LedgerSequence:     10,
+			LedgerKeyHash:      "b23a7523df5007be33f86b6eeba74debadbb3743806af27f5c1cdf9d3b6e6cfe",
+			NInstructions:      100,
+			NFunctions:         5,
+			NExports:           3,
+		},
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/stellar/go-stellar-sdk/ingest"
 	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
@@ -14,7 +13,8 @@ type ContractDataProcessor struct {
 	utils.BaseProcessor
 }
 
-func GetContractDataDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistoryEntry, passPhrase string) ([]contract.ContractDataOutput, error) {
+// GetContractDataVersions returns every change to a contract data entry in a ledger, in the order they were applied.
+func GetContractDataVersions(changes []utils.SourcedChange, lhe xdr.LedgerHeaderHistoryEntry, passPhrase string) ([]contract.ContractDataOutput, error) {
 	contractDataOutputs := []contract.ContractDataOutput{}
 	for _, change := range changes {
 		if change.Type != xdr.LedgerEntryTypeContractData {
@@ -22,7 +22,7 @@ func GetContractDataDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistory
 		}
 
 		TransformContractData := contract.NewTransformContractDataStruct(contract.AssetFromContractData, contract.ContractBalanceFromContractData)
-		contractDataOutput, err, _ := TransformContractData.TransformContractData(change, passPhrase, lhe)
+		contractDataOutput, err, _ := TransformContractData.TransformContractData(change.Change, passPhrase, lhe)
 
 		if err != nil {
 			return contractDataOutputs, fmt.Errorf("could not transform contract data %w", err)
@@ -33,27 +33,66 @@ func GetContractDataDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistory
 			continue
 		}
 
+		contractDataOutput.TransactionHash = change.Source.TransactionHash
+		contractDataOutput.ApplicationOrder = change.Source.ApplicationOrder
+		contractDataOutput.OperationIndex = change.Source.OperationIndex
+		contractDataOutput.Evicted = change.Source.Evicted
 		contractDataOutputs = append(contractDataOutputs, contractDataOutput)
 
 	}
+	return contractDataOutputs, nil
+}
+
+func GetContractDataDetails(changes []utils.SourcedChange, lhe xdr.LedgerHeaderHistoryEntry, passPhrase string) ([]contract.ContractDataOutput, error) {
+	contractDataOutputs, err := GetContractDataVersions(changes, lhe, passPhrase)
+	if err != nil {
+		return contractDataOutputs, err
+	}
+	return lastContractDataVersions(contractDataOutputs), nil
+}
+
+// lastContractDataVersions keeps the last change to every contract data entry of a ledger.
+func lastContractDataVersions(contractDataOutputs []contract.ContractDataOutput) []contract.ContractDataOutput {
 	// It is possible to have multiple changes to the same contract data entry in a single ledger
 	// example: CAJJZSGMMM3PD7N33TAPHGBUGTB43OC73HVIK2L2G6BNGGGYOSSYBXBD, ad520948ba9b01c4e202b5f784de5ed57bd56d18a5de485a54db4b752c0cf61d, 59561994
-	contractDataOutputs = utils.RemoveDuplicatesByFields(contractDataOutputs, []string{"ContractId", "LedgerKeyHash", "LedgerSequence", "Key"})
-	return contractDataOutputs, nil
+	return utils.RemoveDuplicatesByFields(contractDataOutputs, []string{"ContractId", "LedgerKeyHash", "LedgerSequence", "Key"})
 }
 
-func (p *ContractDataProcessor) Process(ctx context.Context, msg utils.Message) error {
-	ledgerCloseMeta, err := p.ExtractLedgerCloseMeta(msg)
+// ledgerContractDataVersions returns every contract data change of a ledger, which is shared by the processors of contract data.
+func ledgerContractDataVersions(ledgerChangeSet *utils.LedgerChangeSet, passPhrase string) ([]contract.ContractDataOutput, error) {
+	details, err := ledgerChangeSet.Details("contract_data_versions", func() (interface{}, error) {
+		lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+		return GetContractDataVersions(ledgerChangeSet.SourcedChanges(xdr.LedgerEntryTypeContractData), lhe, passPhrase)
+	})
 	if err != nil {
-		return err
+		return []contract.ContractDataOutput{}, err
 	}
-	lhe := ledgerCloseMeta.LedgerHeaderHistoryEntry()
-	changes, err := p.ReadIngestChanges(ctx, msg)
+	return details.([]contract.ContractDataOutput), nil
+}
+
+// ledgerContractData returns the contract data details of a ledger, which are shared by the processors of contract data.
+func ledgerContractData(ledgerChangeSet *utils.LedgerChangeSet, passPhrase string) ([]contract.ContractDataOutput, error) {
+	details, err := ledgerChangeSet.Details("contract_data", func() (interface{}, error) {
+		versions, err := ledgerContractDataVersions(ledgerChangeSet, passPhrase)
+		if err != nil {
+			return nil, err
+		}
+		return lastContractDataVersions(versions), nil
+	})
+	if err != nil {
+		return []contract.ContractDataOutput{}, err
+	}
+	return details.([]contract.ContractDataOutput), nil
+}
+
+func (p *ContractDataProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
 	if err != nil {
 		return err
 	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
 
-	contracts, err := GetContractDataDetails(changes, lhe, p.Passphrase)
+	contracts, err := ledgerContractData(ledgerChangeSet, p.Passphrase)
 	if err != nil {
 		return err
 	}
@@ -62,8 +101,11 @@ func (p *ContractDataProcessor) Process(ctx context.Context, msg utils.Message)
 	p.Logger.Infof("Processed %d contracts in ledger sequence %d", len(contracts), lhe.Header.LedgerSeq)
 	var data []interface{}
 	for _, tx := range contracts {
+		if tx.JSONError != "" {
+			p.Logger.Warnf("Could not decode contract data entry %s as json: %s", tx.LedgerKeyHash, tx.JSONError)
+		}
 		data = append(data, tx)
 	}
-	return p.SendInfo(ctx, data)
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
 
 }
@@ -0,0 +1,35 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// ContractDataHistoryProcessor sends every change to the contract data entries of a ledger,
+// including removals, to be appended to the contract data history.
+type ContractDataHistoryProcessor struct {
+	utils.BaseProcessor
+}
+
+func (p *ContractDataHistoryProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	// An entry changed more than once in a ledger is recorded once per change
+	contracts, err := ledgerContractDataVersions(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_data_history", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract data versions in ledger sequence %d", len(contracts), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, tx := range contracts {
+		data = append(data, tx)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
@@ -7,29 +7,41 @@ import (
 	"github.com/stellar/go-stellar-sdk/ingest"
 	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetContractDataDetails(t *testing.T) {
 	type transformTest struct {
-		input      []ingest.Change
+		input      []utils.SourcedChange
 		passphrase string
 		wantOutput []contract.ContractDataOutput
 		wantErr    error
 	}
 
-	hardCodedInput := makeContractDataTestInput()
+	operationIndex := uint32(1)
+	source := utils.ChangeSource{
+		TransactionHash:  "0101010101010101010101010101010101010101010101010101010101010101",
+		ApplicationOrder: 2,
+		OperationIndex:   &operationIndex,
+	}
+	hardCodedInput := []utils.SourcedChange{{Change: makeContractDataTestInput()[0], Source: source}}
 	hardCodedOutput := makeContractDataTestOutput()
+	hardCodedOutput[0].TransactionHash = source.TransactionHash
+	hardCodedOutput[0].ApplicationOrder = source.ApplicationOrder
+	hardCodedOutput[0].OperationIndex = &operationIndex
 	tests := []transformTest{
 		{
-			[]ingest.Change{
+			[]utils.SourcedChange{
 				{
-					ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
-					Type:       xdr.LedgerEntryTypeOffer,
-					Pre:        nil,
-					Post: &xdr.LedgerEntry{
-						Data: xdr.LedgerEntryData{
-							Type: xdr.LedgerEntryTypeOffer,
+					Change: ingest.Change{
+						ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+						Type:       xdr.LedgerEntryTypeOffer,
+						Pre:        nil,
+						Post: &xdr.LedgerEntry{
+							Data: xdr.LedgerEntryData{
+								Type: xdr.LedgerEntryTypeOffer,
+							},
 						},
 					},
 				},
@@ -157,7 +169,37 @@ func makeContractDataTestOutput() []contract.ContractDataOutput {
 			KeyDecoded:                keyDecoded,
 			Val:                       val,
 			ValDecoded:                valDecoded,
+			KeyJSON:                   `{"contract_instance":{"executable":{"wasm":"0000000000000000000000000000000000000000000000000000000000000000"},"storage":[{"key":{"string":"a"},"val":{"string":"a"}}]}}`,
+			ValJSON:                   `{"bool":true}`,
 			ContractDataXDR:           "AAAAAAAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABMAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABAAAAAQAAAA4AAAABYQAAAAAAAA4AAAABYQAAAAAAAAEAAAAAAAAAAQ==",
+			EntrySizeBytes:            136,
 		},
 	}
 }
+
+func TestGetContractDataVersions(t *testing.T) {
+	header := xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			ScpValue:  xdr.StellarValue{CloseTime: 1000},
+			LedgerSeq: 10,
+		},
+	}
+	firstIndex, secondIndex := uint32(0), uint32(1)
+	change := makeContractDataTestInput()[0]
+	changes := []utils.SourcedChange{
+		{Change: change, Source: utils.ChangeSource{TransactionHash: "01", ApplicationOrder: 1, OperationIndex: &firstIndex}},
+		{Change: change, Source: utils.ChangeSource{TransactionHash: "02", ApplicationOrder: 2, OperationIndex: &secondIndex}},
+	}
+
+	// Every change to the entry is a version, the details only keep the last one
+	versions, err := GetContractDataVersions(changes, header, "unit test")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, "01", versions[0].TransactionHash)
+	assert.Equal(t, "02", versions[1].TransactionHash)
+
+	details, err := GetContractDataDetails(changes, header, "unit test")
+	assert.NoError(t, err)
+	assert.Len(t, details, 1)
+	assert.Equal(t, versions[1], details[0])
+}
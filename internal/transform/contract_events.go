@@ -0,0 +1,56 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractEventProcessor struct {
+	utils.BaseProcessor
+}
+
+func GetContractEventDetails(transactions []ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.ContractEventOutput, error) {
+	contractEventOutputs := []contract.ContractEventOutput{}
+	for _, transaction := range transactions {
+		transformedEvents, err := contract.TransformContractEvent(transaction, lhe)
+		if err != nil {
+			return contractEventOutputs, fmt.Errorf("could not transform contract events %w", err)
+		}
+
+		for _, event := range transformedEvents {
+			// Diagnostic events are debug output that is not part of consensus and only
+			// present when enabled on the captive core that produced the ledger
+			if xdr.ContractEventType(event.Type) == xdr.ContractEventTypeDiagnostic {
+				continue
+			}
+			contractEventOutputs = append(contractEventOutputs, event)
+		}
+	}
+	return contractEventOutputs, nil
+}
+
+func (p *ContractEventProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractEvents, err := GetContractEventDetails(ledgerChangeSet.Transactions, lhe)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_events", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract events in ledger sequence %d", len(contractEvents), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, event := range contractEvents {
+		data = append(data, event)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
@@ -0,0 +1,149 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContractEventDetails(t *testing.T) {
+	type transformTest struct {
+		input      []ingest.LedgerTransaction
+		wantOutput []contract.ContractEventOutput
+		wantErr    error
+	}
+
+	tests := []transformTest{
+		{
+			// Transactions without Soroban meta have no events
+			[]ingest.LedgerTransaction{makeContractEventTestTransaction(xdr.TransactionMeta{V: 2, V2: &xdr.TransactionMetaV2{}})},
+			[]contract.ContractEventOutput{}, nil,
+		},
+		{
+			[]ingest.LedgerTransaction{makeContractEventTestTransaction(xdr.TransactionMeta{
+				V: 3,
+				V3: &xdr.TransactionMetaV3{
+					SorobanMeta: &xdr.SorobanTransactionMeta{
+						Events: []xdr.ContractEvent{makeContractEvent(xdr.ContractEventTypeContract, "transfer")},
+						DiagnosticEvents: []xdr.DiagnosticEvent{
+							{InSuccessfulContractCall: true, Event: makeContractEvent(xdr.ContractEventTypeDiagnostic, "fn_call")},
+							{InSuccessfulContractCall: true, Event: makeContractEvent(xdr.ContractEventTypeContract, "transfer")},
+							{InSuccessfulContractCall: false, Event: makeContractEvent(xdr.ContractEventTypeContract, "burn")},
+						},
+					},
+				},
+			})},
+			// Contract events come before the diagnostic events, which repeat them when diagnostic events are enabled.
+			// Diagnostic events are skipped, events of calls that were rolled back are only found among them.
+			[]contract.ContractEventOutput{
+				makeContractEventTestOutput(t, 0, true, xdr.ContractEventTypeContract, "transfer"),
+				makeContractEventTestOutput(t, 2, false, xdr.ContractEventTypeContract, "burn"),
+			},
+			nil,
+		},
+		{
+			[]ingest.LedgerTransaction{makeContractEventTestTransaction(xdr.TransactionMeta{
+				V: 4,
+				V4: &xdr.TransactionMetaV4{
+					Events: []xdr.TransactionEvent{
+						{Stage: xdr.TransactionEventStageBeforeAllTxs, Event: makeContractEvent(xdr.ContractEventTypeContract, "fee")},
+					},
+					Operations: []xdr.OperationMetaV2{
+						{Events: []xdr.ContractEvent{makeContractEvent(xdr.ContractEventTypeContract, "mint")}},
+					},
+					DiagnosticEvents: []xdr.DiagnosticEvent{
+						{InSuccessfulContractCall: true, Event: makeContractEvent(xdr.ContractEventTypeDiagnostic, "fn_return")},
+					},
+				},
+			})},
+			// From TransactionMetaV4 transaction level events come first, followed by operation events
+			[]contract.ContractEventOutput{
+				makeContractEventTestOutput(t, 0, true, xdr.ContractEventTypeContract, "fee"),
+				makeContractEventTestOutput(t, 1, true, xdr.ContractEventTypeContract, "mint"),
+			},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		actualOutput, actualError := GetContractEventDetails(test.input, makeContractEventTestHeader())
+		assert.Equal(t, test.wantErr, actualError)
+		assert.Equal(t, test.wantOutput, actualOutput)
+	}
+}
+
+func makeContractEventTestHeader() xdr.LedgerHeaderHistoryEntry {
+	return xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			ScpValue: xdr.StellarValue{
+				CloseTime: 1000,
+			},
+			LedgerSeq: 10,
+		},
+	}
+}
+
+func makeContractEventTestTransaction(meta xdr.TransactionMeta) ingest.LedgerTransaction {
+	var hash xdr.Hash
+	return ingest.LedgerTransaction{
+		Index: 1,
+		Result: xdr.TransactionResultPair{
+			TransactionHash: hash,
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code:    xdr.TransactionResultCodeTxSuccess,
+					Results: &[]xdr.OperationResult{},
+				},
+			},
+		},
+		UnsafeMeta: meta,
+	}
+}
+
+func makeContractEvent(eventType xdr.ContractEventType, name string) xdr.ContractEvent {
+	var contractID xdr.ContractId
+	symbol := xdr.ScSymbol(name)
+	amount := xdr.Uint32(100)
+	return xdr.ContractEvent{
+		ContractId: &contractID,
+		Type:       eventType,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &symbol}},
+				Data:   xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount},
+			},
+		},
+	}
+}
+
+func makeContractEventTestOutput(t *testing.T, eventIndex uint32, inSuccessfulContractCall bool, eventType xdr.ContractEventType, name string) contract.ContractEventOutput {
+	event := makeContractEvent(eventType, name)
+	topics, topicsDecoded := contract.SerializeScValArray(event.Body.V0.Topics)
+	data, dataDecoded := contract.SerializeScVal(event.Body.V0.Data)
+	eventXDR, err := xdr.MarshalBase64(xdr.DiagnosticEvent{InSuccessfulContractCall: inSuccessfulContractCall, Event: event})
+	assert.NoError(t, err)
+
+	return contract.ContractEventOutput{
+		TransactionHash:          "0000000000000000000000000000000000000000000000000000000000000000",
+		TransactionID:            toid.New(10, 1, 0).ToInt64(),
+		EventIndex:               eventIndex,
+		Successful:               true,
+		LedgerSequence:           10,
+		ClosedAt:                 time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+		InSuccessfulContractCall: inSuccessfulContractCall,
+		ContractId:               "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+		Type:                     int32(eventType),
+		TypeString:               eventType.String(),
+		Topics:                   map[string][]map[string]string{"topics": topics},
+		TopicsDecoded:            map[string][]map[string]string{"topics_decoded": topicsDecoded},
+		Data:                     data,
+		DataDecoded:              dataDecoded,
+		ContractEventXDR:         eventXDR,
+	}
+}
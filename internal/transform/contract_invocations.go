@@ -0,0 +1,48 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type ContractInvocationProcessor struct {
+	utils.BaseProcessor
+}
+
+func GetContractInvocationDetails(transactions []ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.ContractInvocationOutput, error) {
+	contractInvocationOutputs := []contract.ContractInvocationOutput{}
+	for _, transaction := range transactions {
+		invocations, err := contract.TransformContractInvocation(transaction, lhe)
+		if err != nil {
+			return contractInvocationOutputs, fmt.Errorf("could not transform contract invocations %w", err)
+		}
+		contractInvocationOutputs = append(contractInvocationOutputs, invocations...)
+	}
+	return contractInvocationOutputs, nil
+}
+
+func (p *ContractInvocationProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractInvocations, err := GetContractInvocationDetails(ledgerChangeSet.Transactions, lhe)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_invocations", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract invocations in ledger sequence %d", len(contractInvocations), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, invocation := range contractInvocations {
+		data = append(data, invocation)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
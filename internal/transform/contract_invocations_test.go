@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invocationTestSourceAccount = "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+
+func TestGetContractInvocationDetails(t *testing.T) {
+	var contractID xdr.ContractId
+	contractAddress := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID}
+	amount := xdr.Uint32(100)
+	invokeContract := xdr.InvokeContractArgs{
+		ContractAddress: contractAddress,
+		FunctionName:    "transfer",
+		Args:            []xdr.ScVal{{Type: xdr.ScValTypeScvU32, U32: &amount}},
+	}
+	authEntry := xdr.SorobanAuthorizationEntry{
+		Credentials: xdr.SorobanCredentials{
+			Type: xdr.SorobanCredentialsTypeSorobanCredentialsAddress,
+			Address: &xdr.SorobanAddressCredentials{
+				Address:   contractAddress,
+				Signature: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+			},
+		},
+		RootInvocation: xdr.SorobanAuthorizedInvocation{
+			Function: xdr.SorobanAuthorizedFunction{
+				Type:       xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn,
+				ContractFn: &invokeContract,
+			},
+		},
+	}
+	authEntryXDR, err := xdr.MarshalBase64(authEntry)
+	require.NoError(t, err)
+
+	invokeOperation := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type: xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+				HostFunction: xdr.HostFunction{
+					Type:           xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+					InvokeContract: &invokeContract,
+				},
+				Auth: []xdr.SorobanAuthorizationEntry{authEntry},
+			},
+		},
+	}
+	sorobanData := &xdr.SorobanTransactionData{
+		Resources: xdr.SorobanResources{
+			Instructions:  1000,
+			DiskReadBytes: 200,
+			WriteBytes:    300,
+		},
+		ResourceFee: 500,
+	}
+	returnValue := xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+	meta := xdr.TransactionMeta{
+		V: 4,
+		V4: &xdr.TransactionMetaV4{
+			SorobanMeta: &xdr.SorobanTransactionMetaV2{
+				Ext: xdr.SorobanTransactionMetaExt{
+					V: 1,
+					V1: &xdr.SorobanTransactionMetaExtV1{
+						TotalNonRefundableResourceFeeCharged: 100,
+						TotalRefundableResourceFeeCharged:    50,
+						RentFeeCharged:                       20,
+					},
+				},
+				ReturnValue: &returnValue,
+			},
+		},
+	}
+	operationResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{
+				Code:    xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess,
+				Success: &xdr.Hash{},
+			},
+		},
+	}
+	paymentOperation := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:      xdr.OperationTypePayment,
+			PaymentOp: &xdr.PaymentOp{Destination: xdr.MustMuxedAddress(invocationTestSourceAccount), Asset: xdr.MustNewNativeAsset(), Amount: 1},
+		},
+	}
+
+	transactions := []ingest.LedgerTransaction{
+		// Transactions without InvokeHostFunction operations are skipped
+		makeContractInvocationTestTransaction(1, paymentOperation, nil, xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}, xdr.OperationResult{}),
+		makeContractInvocationTestTransaction(2, invokeOperation, sorobanData, meta, operationResult),
+	}
+	args, argsDecoded := contract.SerializeScValArray(invokeContract.Args)
+	returnValueSerialized, returnValueDecoded := contract.SerializeScVal(returnValue)
+	want := []contract.ContractInvocationOutput{
+		{
+			TransactionHash:                 "0000000000000000000000000000000000000000000000000000000000000000",
+			TransactionID:                   toid.New(10, 2, 0).ToInt64(),
+			OperationID:                     toid.New(10, 2, 1).ToInt64(),
+			OperationIndex:                  0,
+			LedgerSequence:                  10,
+			ClosedAt:                        time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+			SourceAccount:                   invocationTestSourceAccount,
+			Successful:                      true,
+			ResultCode:                      "InvokeHostFunctionResultCodeInvokeHostFunctionSuccess",
+			HostFunctionType:                "invoke_contract",
+			ContractId:                      "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			FunctionName:                    "transfer",
+			Args:                            args,
+			ArgsDecoded:                     argsDecoded,
+			Auth:                            []map[string]string{{"credentials": "address", "address": "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4", "xdr": authEntryXDR}},
+			ReturnValue:                     returnValueSerialized,
+			ReturnValueDecoded:              returnValueDecoded,
+			DeclaredResourceFee:             500,
+			DeclaredInstructions:            1000,
+			DeclaredDiskReadBytes:           200,
+			DeclaredWriteBytes:              300,
+			FeeCharged:                      170,
+			NonRefundableResourceFeeCharged: 100,
+			RefundableResourceFeeCharged:    50,
+			RentFeeCharged:                  20,
+		},
+	}
+
+	actualOutput, err := GetContractInvocationDetails(transactions, makeContractEventTestHeader())
+	require.NoError(t, err)
+	assert.Equal(t, want, actualOutput)
+}
+
+func makeContractInvocationTestTransaction(index uint32, operation xdr.Operation, sorobanData *xdr.SorobanTransactionData, meta xdr.TransactionMeta, operationResult xdr.OperationResult) ingest.LedgerTransaction {
+	ext := xdr.TransactionExt{V: 0}
+	if sorobanData != nil {
+		ext = xdr.TransactionExt{V: 1, SorobanData: sorobanData}
+	}
+	return ingest.LedgerTransaction{
+		Index: index,
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: xdr.MustMuxedAddress(invocationTestSourceAccount),
+					Operations:    []xdr.Operation{operation},
+					Ext:           ext,
+				},
+			},
+		},
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				FeeCharged: 170,
+				Result: xdr.TransactionResultResult{
+					Code:    xdr.TransactionResultCodeTxSuccess,
+					Results: &[]xdr.OperationResult{operationResult},
+				},
+			},
+		},
+		UnsafeMeta: meta,
+	}
+}
@@ -0,0 +1,103 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// ContractMetadataProcessor sends the SDK version, meta and env meta of uploaded Wasm.
+type ContractMetadataProcessor struct {
+	utils.BaseProcessor
+}
+
+// ContractSpecEntryProcessor sends the spec of uploaded Wasm, one entry per function, type and event.
+type ContractSpecEntryProcessor struct {
+	utils.BaseProcessor
+}
+
+// GetContractSpecDetails reads the spec and metadata of the Wasm uploaded or restored by changes.
+func GetContractSpecDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.ContractSpecOutput, error) {
+	contractSpecOutputs := []contract.ContractSpecOutput{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeContractCode {
+			continue
+		}
+
+		contractSpecOutput, ok, err := contract.TransformContractSpec(change, lhe)
+		if err != nil {
+			return contractSpecOutputs, fmt.Errorf("could not transform contract spec %w", err)
+		}
+		if !ok {
+			continue
+		}
+		contractSpecOutputs = append(contractSpecOutputs, contractSpecOutput)
+	}
+
+	// The code of a hash never changes, so it only needs to be read once per ledger
+	contractSpecOutputs = utils.RemoveDuplicatesByFields(contractSpecOutputs, []string{"ContractCodeHash"})
+	return contractSpecOutputs, nil
+}
+
+// ledgerContractSpecs returns the contract spec details of a ledger, which are shared by the metadata and spec entry processors.
+func ledgerContractSpecs(ledgerChangeSet *utils.LedgerChangeSet) ([]contract.ContractSpecOutput, error) {
+	details, err := ledgerChangeSet.Details("contract_specs", func() (interface{}, error) {
+		lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+		return GetContractSpecDetails(ledgerChangeSet.Changes(xdr.LedgerEntryTypeContractCode), lhe)
+	})
+	if err != nil {
+		return []contract.ContractSpecOutput{}, err
+	}
+	return details.([]contract.ContractSpecOutput), nil
+}
+
+func (p *ContractMetadataProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractSpecs, err := ledgerContractSpecs(ledgerChangeSet)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_metadata", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract metadata in ledger sequence %d", len(contractSpecs), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, spec := range contractSpecs {
+		if spec.ParseError != "" {
+			p.Logger.Warnf("Could not read spec of contract code %s: %s", spec.ContractCodeHash, spec.ParseError)
+		}
+		data = append(data, spec)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
+
+func (p *ContractSpecEntryProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractSpecs, err := ledgerContractSpecs(ledgerChangeSet)
+	if err != nil {
+		return err
+	}
+
+	var data []interface{}
+	entryCount := 0
+	for _, spec := range contractSpecs {
+		entryCount += len(spec.Entries)
+		data = append(data, spec)
+	}
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_spec_entries", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract spec entries in ledger sequence %d", entryCount, lhe.Header.LedgerSeq)
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
@@ -0,0 +1,40 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContractSpecDetails(t *testing.T) {
+	// An empty module, without custom sections
+	codeEntry := &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type:         xdr.LedgerEntryTypeContractCode,
+			ContractCode: &xdr.ContractCodeEntry{Hash: xdr.Hash{1}, Code: []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}},
+		},
+	}
+	changes := []ingest.Change{
+		{ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Type: xdr.LedgerEntryTypeContractCode, Post: codeEntry},
+		// The same Wasm restored in the same ledger is only read once
+		{ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryRestored, Type: xdr.LedgerEntryTypeContractCode, Post: codeEntry},
+		{ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Type: xdr.LedgerEntryTypeContractCode, Pre: codeEntry},
+	}
+
+	actualOutput, err := GetContractSpecDetails(changes, makeContractEventTestHeader())
+	require.NoError(t, err)
+	assert.Equal(t, []contract.ContractSpecOutput{
+		{
+			ContractCodeHash: "0100000000000000000000000000000000000000000000000000000000000000",
+			LedgerSequence:   10,
+			ClosedAt:         time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+			Entries:          []contract.ContractSpecEntryOutput{},
+			Meta:             map[string]string{},
+		},
+	}, actualOutput)
+}
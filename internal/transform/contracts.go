@@ -0,0 +1,126 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// ContractProcessor sends the contract instances created or upgraded in a ledger, to keep track of the
+// current executable of every contract and of how it was deployed.
+type ContractProcessor struct {
+	utils.BaseProcessor
+}
+
+// ContractUpgradeProcessor sends every change of the executable of a contract instance.
+type ContractUpgradeProcessor struct {
+	utils.BaseProcessor
+}
+
+// GetContractInstanceDetails returns the contract instances created or upgraded by changes, in the order they were applied.
+// Created instances are attributed to their deployer when the creating transaction holds the contract id preimage.
+func GetContractInstanceDetails(changes []utils.SourcedChange, transactions []ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passPhrase string) ([]contract.ContractInstanceOutput, error) {
+	transactionsByOrder := map[uint32]ingest.LedgerTransaction{}
+	for _, transaction := range transactions {
+		transactionsByOrder[transaction.Index] = transaction
+	}
+	preimagesByOrder := map[uint32]map[string]xdr.ContractIdPreimage{}
+
+	contractInstanceOutputs := []contract.ContractInstanceOutput{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeContractData {
+			continue
+		}
+
+		contractInstanceOutput, ok, err := contract.TransformContractInstance(change.Change, passPhrase, lhe)
+		if err != nil {
+			return contractInstanceOutputs, fmt.Errorf("could not transform contract instance %w", err)
+		}
+		if !ok {
+			continue
+		}
+		contractInstanceOutput.TransactionHash = change.Source.TransactionHash
+		contractInstanceOutput.ApplicationOrder = change.Source.ApplicationOrder
+		contractInstanceOutput.OperationIndex = change.Source.OperationIndex
+
+		transaction, found := transactionsByOrder[change.Source.ApplicationOrder]
+		if contractInstanceOutput.Created && found {
+			preimages, cached := preimagesByOrder[transaction.Index]
+			if !cached {
+				if preimages, err = contract.ContractIdPreimages(transaction, passPhrase); err != nil {
+					return contractInstanceOutputs, err
+				}
+				preimagesByOrder[transaction.Index] = preimages
+			}
+			if preimage, ok := preimages[contractInstanceOutput.ContractId]; ok {
+				if contractInstanceOutput.Deployer, contractInstanceOutput.Salt, err = contract.ContractDeployer(preimage); err != nil {
+					return contractInstanceOutputs, err
+				}
+			}
+		}
+
+		contractInstanceOutputs = append(contractInstanceOutputs, contractInstanceOutput)
+	}
+	return contractInstanceOutputs, nil
+}
+
+// ledgerContractInstances returns the contract instance details of a ledger, which are shared by the contract and upgrade processors.
+func ledgerContractInstances(ledgerChangeSet *utils.LedgerChangeSet, passPhrase string) ([]contract.ContractInstanceOutput, error) {
+	details, err := ledgerChangeSet.Details("contract_instances", func() (interface{}, error) {
+		lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+		changes := ledgerChangeSet.SourcedChanges(xdr.LedgerEntryTypeContractData)
+		return GetContractInstanceDetails(changes, ledgerChangeSet.Transactions, lhe, passPhrase)
+	})
+	if err != nil {
+		return []contract.ContractInstanceOutput{}, err
+	}
+	return details.([]contract.ContractInstanceOutput), nil
+}
+
+func (p *ContractProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contracts, err := ledgerContractInstances(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("contracts", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract instances in ledger sequence %d", len(contracts), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, instance := range contracts {
+		data = append(data, instance)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
+
+func (p *ContractUpgradeProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contracts, err := ledgerContractInstances(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	var data []interface{}
+	for _, instance := range contracts {
+		if instance.Upgraded {
+			data = append(data, instance)
+		}
+	}
+	p.MetricRecorder.RecordProcessingLedgerSequence("contract_upgrades", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d contract upgrades in ledger sequence %d", len(data), lhe.Header.LedgerSeq)
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
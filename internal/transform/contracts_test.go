@@ -0,0 +1,145 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContractInstanceDetails(t *testing.T) {
+	const passphrase = "unit test"
+	deployerAccount := xdr.MustAddress(invocationTestSourceAccount)
+	preimage := xdr.ContractIdPreimage{
+		Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+		FromAddress: &xdr.ContractIdPreimageFromAddress{
+			Address: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &deployerAccount},
+			Salt:    xdr.Uint256{1},
+		},
+	}
+	oldWasmHash := xdr.Hash{1}
+	newWasmHash := xdr.Hash{2}
+	createOperation := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type: xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+				HostFunction: xdr.HostFunction{
+					Type: xdr.HostFunctionTypeHostFunctionTypeCreateContract,
+					CreateContract: &xdr.CreateContractArgs{
+						ContractIdPreimage: preimage,
+						Executable:         xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &oldWasmHash},
+					},
+				},
+			},
+		},
+	}
+	transactions := []ingest.LedgerTransaction{
+		makeContractInvocationTestTransaction(1, createOperation, nil, xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}, xdr.OperationResult{}),
+	}
+
+	preimages, err := contract.ContractIdPreimages(transactions[0], passphrase)
+	require.NoError(t, err)
+	require.Len(t, preimages, 1)
+	var createdContractId string
+	for contractId := range preimages {
+		createdContractId = contractId
+	}
+	createdContractIdBytes, err := strkey.Decode(strkey.VersionByteContract, createdContractId)
+	require.NoError(t, err)
+	var createdContract xdr.ContractId
+	copy(createdContract[:], createdContractIdBytes)
+	var upgradedContract xdr.ContractId
+
+	operationIndex := uint32(0)
+	createSource := utils.ChangeSource{TransactionHash: "0000000000000000000000000000000000000000000000000000000000000000", ApplicationOrder: 1, OperationIndex: &operationIndex}
+	upgradeSource := utils.ChangeSource{TransactionHash: "0202020202020202020202020202020202020202020202020202020202020202", ApplicationOrder: 2, OperationIndex: &operationIndex}
+	changes := []utils.SourcedChange{
+		{
+			Change: ingest.Change{
+				ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+				Type:       xdr.LedgerEntryTypeContractData,
+				Post:       makeContractInstanceTestEntry(createdContract, oldWasmHash, nil),
+			},
+			Source: createSource,
+		},
+		{
+			Change: ingest.Change{
+				ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+				Type:       xdr.LedgerEntryTypeContractData,
+				Pre:        makeContractInstanceTestEntry(upgradedContract, oldWasmHash, nil),
+				Post:       makeContractInstanceTestEntry(upgradedContract, newWasmHash, nil),
+			},
+			Source: upgradeSource,
+		},
+		// Changes of the instance storage keep the executable and are skipped
+		{
+			Change: ingest.Change{
+				ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+				Type:       xdr.LedgerEntryTypeContractData,
+				Pre:        makeContractInstanceTestEntry(upgradedContract, newWasmHash, nil),
+				Post:       makeContractInstanceTestEntry(upgradedContract, newWasmHash, &xdr.ScMap{}),
+			},
+			Source: upgradeSource,
+		},
+	}
+
+	closedAt := time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC)
+	want := []contract.ContractInstanceOutput{
+		{
+			ContractId:       createdContractId,
+			ExecutableType:   "wasm",
+			WasmHash:         oldWasmHash.HexString(),
+			Created:          true,
+			LedgerSequence:   10,
+			ClosedAt:         closedAt,
+			Deployer:         invocationTestSourceAccount,
+			Salt:             "0100000000000000000000000000000000000000000000000000000000000000",
+			TransactionHash:  createSource.TransactionHash,
+			ApplicationOrder: 1,
+			OperationIndex:   &operationIndex,
+		},
+		{
+			ContractId:             "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4",
+			ExecutableType:         "wasm",
+			WasmHash:               newWasmHash.HexString(),
+			Upgraded:               true,
+			LedgerSequence:         10,
+			ClosedAt:               closedAt,
+			PreviousExecutableType: "wasm",
+			PreviousWasmHash:       oldWasmHash.HexString(),
+			TransactionHash:        upgradeSource.TransactionHash,
+			ApplicationOrder:       2,
+			OperationIndex:         &operationIndex,
+		},
+	}
+
+	actualOutput, err := GetContractInstanceDetails(changes, transactions, makeContractEventTestHeader(), passphrase)
+	require.NoError(t, err)
+	assert.Equal(t, want, actualOutput)
+}
+
+func makeContractInstanceTestEntry(contractID xdr.ContractId, wasmHash xdr.Hash, storage *xdr.ScMap) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+				Durability: xdr.ContractDataDurabilityPersistent,
+				Val: xdr.ScVal{
+					Type: xdr.ScValTypeScvContractInstance,
+					Instance: &xdr.ScContractInstance{
+						Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash},
+						Storage:    storage,
+					},
+				},
+			},
+		},
+	}
+}
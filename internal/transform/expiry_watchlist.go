@@ -0,0 +1,225 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// ExpiryWatchlist tracks the live until ledger of the watched entries, ledger by ledger, and reports the entries
+// coming within thresholdLedgers of it. Each event is reported once per live until ledger, extending or restoring
+// an entry reports it again the next time it runs out. An event is only done with once Delivered, a Failed one is
+// reported again at the next ledger.
+type ExpiryWatchlist struct {
+	contractIds      map[string]bool
+	keyHashes        map[string]bool
+	thresholdLedgers uint32
+	entries          map[string]utils.WatchedEntry
+	// pending holds the entries changed by the ledger being processed, a nil entry is no longer watched.
+	// They replace entries once the ledger is committed, so a ledger processed again starts from the same state.
+	pending map[string]*utils.WatchedEntry
+	// due are the notifications of the ledger being processed. Once it is committed they are kept in sending,
+	// by key hash, until they are delivered or failed, and are not reported again in the meantime.
+	due     []utils.ExpiryNotification
+	sending map[string]utils.ExpiryNotification
+}
+
+// NewExpiryWatchlist watches the entries of the given contracts and the entries with the given key hashes,
+// starting from their state as of the last indexed ledger.
+func NewExpiryWatchlist(contractIds []string, keyHashes []string, thresholdLedgers uint32, entries []utils.WatchedEntry) *ExpiryWatchlist {
+	watchlist := &ExpiryWatchlist{
+		contractIds:      map[string]bool{},
+		keyHashes:        map[string]bool{},
+		thresholdLedgers: thresholdLedgers,
+		entries:          map[string]utils.WatchedEntry{},
+		pending:          map[string]*utils.WatchedEntry{},
+		sending:          map[string]utils.ExpiryNotification{},
+	}
+	for _, contractId := range contractIds {
+		watchlist.contractIds[contractId] = true
+	}
+	for _, keyHash := range keyHashes {
+		watchlist.keyHashes[keyHash] = true
+	}
+	for _, entry := range entries {
+		watchlist.entries[entry.KeyHash] = entry
+	}
+	return watchlist
+}
+
+// get returns the state of a watched entry as of the ledger being processed
+func (w *ExpiryWatchlist) get(keyHash string) (utils.WatchedEntry, bool) {
+	if entry, ok := w.pending[keyHash]; ok {
+		if entry == nil {
+			return utils.WatchedEntry{}, false
+		}
+		return *entry, true
+	}
+	entry, ok := w.entries[keyHash]
+	return entry, ok
+}
+
+// watch starts tracking a watched entry created in the ledger, or stops tracking it once removed
+func (w *ExpiryWatchlist) watch(keyHash string, contractId string, durability string, deleted bool) {
+	if deleted {
+		w.pending[keyHash] = nil
+		return
+	}
+	if _, ok := w.get(keyHash); !ok {
+		w.pending[keyHash] = &utils.WatchedEntry{KeyHash: keyHash, ContractId: contractId, Durability: durability}
+	}
+}
+
+// Update applies the contract data, contract code and ttl changes of a ledger to the watched entries and returns
+// the notifications due at that ledger. The changes are kept pending until Commit, calling Update again before
+// that processes the ledger again from the last committed state.
+func (w *ExpiryWatchlist) Update(ledgerChangeSet *utils.LedgerChangeSet, ttls []contract.TtlOutput) ([]utils.ExpiryNotification, error) {
+	w.pending = map[string]*utils.WatchedEntry{}
+	for _, change := range ledgerChangeSet.Changes(xdr.LedgerEntryTypeContractData) {
+		ledgerEntry, _, deleted, err := contract.ExtractEntryFromChange(change)
+		if err != nil {
+			return nil, err
+		}
+		contractData := ledgerEntry.Data.MustContractData()
+		var contractId string
+		if id, ok := contractData.Contract.GetContractId(); ok {
+			idBytes, _ := id.MarshalBinary()
+			contractId, _ = strkey.Encode(strkey.VersionByteContract, idBytes)
+		}
+		keyHash := contract.LedgerEntryToLedgerKeyHash(ledgerEntry)
+		if !w.contractIds[contractId] && !w.keyHashes[keyHash] {
+			continue
+		}
+		durability := "temporary"
+		if contractData.Durability == xdr.ContractDataDurabilityPersistent {
+			durability = "persistent"
+		}
+		w.watch(keyHash, contractId, durability, deleted)
+	}
+	for _, change := range ledgerChangeSet.Changes(xdr.LedgerEntryTypeContractCode) {
+		ledgerEntry, _, deleted, err := contract.ExtractEntryFromChange(change)
+		if err != nil {
+			return nil, err
+		}
+		keyHash := contract.LedgerEntryToLedgerKeyHash(ledgerEntry)
+		if w.keyHashes[keyHash] {
+			w.watch(keyHash, "", "persistent", deleted)
+		}
+	}
+	for _, ttl := range ttls {
+		entry, ok := w.get(ttl.KeyHash)
+		if !ok || ttl.Deleted || entry.LiveUntilLedgerSequence == ttl.LiveUntilLedgerSeq {
+			continue
+		}
+		entry.LiveUntilLedgerSequence = ttl.LiveUntilLedgerSeq
+		entry.NotifiedEvent = ""
+		w.pending[ttl.KeyHash] = &entry
+	}
+
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+	closedAt, err := contract.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, fmt.Errorf("could not read close time of ledger %d: %w", lhe.Header.LedgerSeq, err)
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+
+	var watched []utils.WatchedEntry
+	for keyHash, entry := range w.entries {
+		if _, changed := w.pending[keyHash]; !changed {
+			watched = append(watched, entry)
+		}
+	}
+	for _, entry := range w.pending {
+		if entry != nil {
+			watched = append(watched, *entry)
+		}
+	}
+
+	notifications := []utils.ExpiryNotification{}
+	for _, entry := range watched {
+		if entry.LiveUntilLedgerSequence == 0 {
+			continue
+		}
+		remaining := int64(entry.LiveUntilLedgerSequence) - int64(ledgerSequence)
+		var event string
+		switch {
+		case remaining < 0:
+			event = utils.ExpiryEventExpired
+		case remaining <= int64(w.thresholdLedgers):
+			event = utils.ExpiryEventExpiring
+		default:
+			continue
+		}
+		if entry.NotifiedEvent == event || w.isSending(entry, event) {
+			continue
+		}
+		notifications = append(notifications, utils.ExpiryNotification{
+			Event:                   event,
+			ContractId:              entry.ContractId,
+			KeyHash:                 entry.KeyHash,
+			Durability:              entry.Durability,
+			LiveUntilLedgerSequence: entry.LiveUntilLedgerSequence,
+			LedgerSequence:          ledgerSequence,
+			ClosedAt:                closedAt,
+			RemainingLedgers:        remaining,
+		})
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].KeyHash < notifications[j].KeyHash })
+	w.due = notifications
+	return notifications, nil
+}
+
+// isSending reports whether event is being sent for the current live until ledger of entry
+func (w *ExpiryWatchlist) isSending(entry utils.WatchedEntry, event string) bool {
+	notification, ok := w.sending[entry.KeyHash]
+	return ok && notification.Event == event && notification.LiveUntilLedgerSequence == entry.LiveUntilLedgerSequence
+}
+
+// Commit applies the changes of the last updated ledger once it is committed. Its notifications are being sent
+// from then on, until they are Delivered or Failed.
+func (w *ExpiryWatchlist) Commit() {
+	for keyHash, entry := range w.pending {
+		if entry == nil {
+			delete(w.entries, keyHash)
+		} else {
+			w.entries[keyHash] = *entry
+		}
+	}
+	w.pending = map[string]*utils.WatchedEntry{}
+	for _, notification := range w.due {
+		w.sending[notification.KeyHash] = notification
+	}
+	w.due = nil
+}
+
+// Delivered marks the events of the delivered notifications as notified, so they are not reported again for the
+// same live until ledger.
+func (w *ExpiryWatchlist) Delivered(notifications []utils.ExpiryNotification) {
+	for _, notification := range notifications {
+		w.settle(notification)
+		entry, ok := w.entries[notification.KeyHash]
+		if ok && entry.LiveUntilLedgerSequence == notification.LiveUntilLedgerSequence {
+			entry.NotifiedEvent = notification.Event
+			w.entries[notification.KeyHash] = entry
+		}
+	}
+}
+
+// Failed releases the notifications that could not be delivered, so they are reported again at the next ledger.
+func (w *ExpiryWatchlist) Failed(notifications []utils.ExpiryNotification) {
+	for _, notification := range notifications {
+		w.settle(notification)
+	}
+}
+
+// settle stops tracking notification as being sent, unless a later notification of the entry is
+func (w *ExpiryWatchlist) settle(notification utils.ExpiryNotification) {
+	sending, ok := w.sending[notification.KeyHash]
+	if ok && sending.Event == notification.Event && sending.LiveUntilLedgerSequence == notification.LiveUntilLedgerSequence {
+		delete(w.sending, notification.KeyHash)
+	}
+}
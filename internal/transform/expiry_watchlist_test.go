@@ -0,0 +1,125 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeWatchlistTestChangeSet(ledgerSequence uint32, changes ...utils.SourcedChange) *utils.LedgerChangeSet {
+	header := xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{ScpValue: xdr.StellarValue{CloseTime: 1000}, LedgerSeq: xdr.Uint32(ledgerSequence)},
+	}
+	changesByType := map[xdr.LedgerEntryType][]utils.SourcedChange{}
+	for _, change := range changes {
+		changesByType[change.Change.Type] = append(changesByType[change.Change.Type], change)
+	}
+	return &utils.LedgerChangeSet{
+		LedgerCloseMeta: xdr.LedgerCloseMeta{V: 0, V0: &xdr.LedgerCloseMetaV0{LedgerHeader: header}},
+		ChangesByType:   changesByType,
+	}
+}
+
+func TestExpiryWatchlist(t *testing.T) {
+	contractId := "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4"
+	keyHash := "abfc33272095a9df4c310cff189040192a8aee6f6a23b6b462889114d80728ca"
+	codeKeyHash := "0101010101010101010101010101010101010101010101010101010101010101"
+	closedAt := time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC)
+
+	otherCodeKeyHash := "0202020202020202020202020202020202020202020202020202020202020202"
+
+	// The second contract code was already notified as expiring before a restart
+	watchlist := NewExpiryWatchlist([]string{contractId}, []string{codeKeyHash, otherCodeKeyHash}, 10, []utils.WatchedEntry{
+		{KeyHash: codeKeyHash, Durability: "persistent", LiveUntilLedgerSequence: 500},
+		{KeyHash: otherCodeKeyHash, Durability: "persistent", LiveUntilLedgerSequence: 105, NotifiedEvent: utils.ExpiryEventExpiring},
+	})
+	update := func(changeSet *utils.LedgerChangeSet, ttls []contract.TtlOutput) ([]utils.ExpiryNotification, error) {
+		notifications, err := watchlist.Update(changeSet, ttls)
+		watchlist.Commit()
+		watchlist.Delivered(notifications)
+		return notifications, err
+	}
+
+	// The contract data entry is created with its ttl and is already within the threshold
+	created := utils.SourcedChange{Change: makeContractDataTestInput()[0]}
+	ttls := []contract.TtlOutput{
+		{KeyHash: keyHash, LiveUntilLedgerSeq: 105, LedgerSequence: 100},
+		{KeyHash: "02", LiveUntilLedgerSeq: 101, LedgerSequence: 100},
+	}
+	expected := []utils.ExpiryNotification{{
+		Event:                   utils.ExpiryEventExpiring,
+		ContractId:              contractId,
+		KeyHash:                 keyHash,
+		Durability:              "persistent",
+		LiveUntilLedgerSequence: 105,
+		LedgerSequence:          100,
+		ClosedAt:                closedAt,
+		RemainingLedgers:        5,
+	}}
+	notifications, err := watchlist.Update(makeWatchlistTestChangeSet(100, created), ttls)
+	require.NoError(t, err)
+	assert.Equal(t, expected, notifications)
+
+	// A ledger processed again before being committed, e.g. on a retried transaction, notifies the same entries
+	notifications, err = update(makeWatchlistTestChangeSet(100, created), ttls)
+	require.NoError(t, err)
+	assert.Equal(t, expected, notifications)
+
+	// Each event is only notified once
+	notifications, err = update(makeWatchlistTestChangeSet(101), nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	notifications, err = update(makeWatchlistTestChangeSet(106), nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, otherCodeKeyHash, notifications[0].KeyHash)
+	assert.Equal(t, keyHash, notifications[1].KeyHash)
+	assert.Equal(t, utils.ExpiryEventExpired, notifications[1].Event)
+	assert.Equal(t, int64(-1), notifications[1].RemainingLedgers)
+
+	// Restoring the entry notifies it again the next time it runs out
+	notifications, err = update(makeWatchlistTestChangeSet(107), []contract.TtlOutput{{KeyHash: keyHash, LiveUntilLedgerSeq: 200, LedgerSequence: 107}})
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	// Both the contract data entry and the watched contract code loaded at startup run out
+	notifications, err = update(makeWatchlistTestChangeSet(495), nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, codeKeyHash, notifications[0].KeyHash)
+	assert.Equal(t, utils.ExpiryEventExpiring, notifications[0].Event)
+	assert.Equal(t, keyHash, notifications[1].KeyHash)
+	assert.Equal(t, utils.ExpiryEventExpired, notifications[1].Event)
+
+	// A notification being sent is not reported again, a failed one is reported again at the next ledger
+	failingKeyHash := "0303030303030303030303030303030303030303030303030303030303030303"
+	watchlist = NewExpiryWatchlist(nil, []string{failingKeyHash}, 10, []utils.WatchedEntry{
+		{KeyHash: failingKeyHash, Durability: "persistent", LiveUntilLedgerSequence: 105},
+	})
+	notifications, err = watchlist.Update(makeWatchlistTestChangeSet(100), nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	watchlist.Commit()
+
+	sending, err := watchlist.Update(makeWatchlistTestChangeSet(101), nil)
+	require.NoError(t, err)
+	assert.Empty(t, sending)
+	watchlist.Commit()
+	watchlist.Failed(notifications)
+
+	notifications, err = update(makeWatchlistTestChangeSet(102), nil)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, failingKeyHash, notifications[0].KeyHash)
+	assert.Equal(t, uint32(102), notifications[0].LedgerSequence)
+
+	notifications, err = update(makeWatchlistTestChangeSet(103), nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
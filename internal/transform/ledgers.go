@@ -0,0 +1,39 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+type LedgerProcessor struct {
+	utils.BaseProcessor
+}
+
+func GetLedgerDetails(ledgerCloseMeta xdr.LedgerCloseMeta) (contract.LedgerOutput, error) {
+	ledgerOutput, err := contract.TransformLedger(ledgerCloseMeta)
+	if err != nil {
+		return contract.LedgerOutput{}, fmt.Errorf("could not transform ledger %w", err)
+	}
+	return ledgerOutput, nil
+}
+
+func (p *LedgerProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	ledger, err := GetLedgerDetails(ledgerChangeSet.LedgerCloseMeta)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("ledgers", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed ledger sequence %d", lhe.Header.LedgerSeq)
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), []interface{}{ledger})
+}
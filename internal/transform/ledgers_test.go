@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLedgerDetails(t *testing.T) {
+	header := xdr.LedgerHeaderHistoryEntry{
+		Hash: xdr.Hash{1},
+		Header: xdr.LedgerHeader{
+			LedgerVersion:      23,
+			PreviousLedgerHash: xdr.Hash{2},
+			ScpValue: xdr.StellarValue{
+				CloseTime: 1000,
+			},
+			LedgerSeq:    10,
+			BaseFee:      100,
+			BaseReserve:  5000000,
+			MaxTxSetSize: 1000,
+		},
+	}
+	txProcessing := []xdr.TransactionResultMeta{{}, {}}
+	sorobanFeeWrite1Kb := int64(3500)
+	totalByteSize := uint64(123456)
+
+	tests := []struct {
+		name  string
+		input xdr.LedgerCloseMeta
+		want  contract.LedgerOutput
+	}{
+		{
+			"ledger close meta v0 has no soroban state",
+			xdr.LedgerCloseMeta{V: 0, V0: &xdr.LedgerCloseMetaV0{LedgerHeader: header, TxProcessing: txProcessing}},
+			makeLedgerTestOutput(nil, nil),
+		},
+		{
+			"ledger close meta v1",
+			xdr.LedgerCloseMeta{V: 1, V1: &xdr.LedgerCloseMetaV1{
+				Ext: xdr.LedgerCloseMetaExt{
+					V:  1,
+					V1: &xdr.LedgerCloseMetaExtV1{SorobanFeeWrite1Kb: xdr.Int64(sorobanFeeWrite1Kb)},
+				},
+				LedgerHeader:                    header,
+				TxProcessing:                    txProcessing,
+				TotalByteSizeOfLiveSorobanState: xdr.Uint64(totalByteSize),
+			}},
+			makeLedgerTestOutput(&sorobanFeeWrite1Kb, &totalByteSize),
+		},
+		{
+			"ledger close meta v2 without fee write ext",
+			xdr.LedgerCloseMeta{V: 2, V2: &xdr.LedgerCloseMetaV2{
+				LedgerHeader:                    header,
+				TxProcessing:                    []xdr.TransactionResultMetaV1{{}, {}},
+				TotalByteSizeOfLiveSorobanState: xdr.Uint64(totalByteSize),
+			}},
+			makeLedgerTestOutput(nil, &totalByteSize),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetLedgerDetails(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func makeLedgerTestOutput(sorobanFeeWrite1Kb *int64, totalByteSize *uint64) contract.LedgerOutput {
+	return contract.LedgerOutput{
+		Sequence:                        10,
+		LedgerHash:                      "0100000000000000000000000000000000000000000000000000000000000000",
+		PreviousLedgerHash:              "0200000000000000000000000000000000000000000000000000000000000000",
+		ClosedAt:                        time.Unix(1000, 0).UTC(),
+		ProtocolVersion:                 23,
+		BaseFee:                         100,
+		BaseReserve:                     5000000,
+		MaxTxSetSize:                    1000,
+		TransactionCount:                2,
+		SorobanFeeWrite1Kb:              sorobanFeeWrite1Kb,
+		TotalByteSizeOfLiveSorobanState: totalByteSize,
+	}
+}
@@ -0,0 +1,90 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// SACBalanceProcessor sends the Stellar Asset Contract balance entries changed in a ledger
+type SACBalanceProcessor struct {
+	utils.BaseProcessor
+}
+
+// SACAssetProcessor sends the Stellar Asset Contract instances changed in a ledger, which map the contract to its classic asset
+type SACAssetProcessor struct {
+	utils.BaseProcessor
+}
+
+// GetSACBalanceDetails returns the contract data details holding a Stellar Asset Contract balance of a contract address.
+// Balances of accounts are stored in trustlines and are not part of contract data.
+// Evictions only carry the key of the entry, so every evicted Balance key is kept, and matches a row only when it is
+// the balance of a Stellar Asset Contract.
+func GetSACBalanceDetails(contractDataOutputs []contract.ContractDataOutput) []contract.ContractDataOutput {
+	balanceOutputs := []contract.ContractDataOutput{}
+	for _, contractDataOutput := range contractDataOutputs {
+		evictedBalance := contractDataOutput.Evicted && contractDataOutput.KeySymbol == "Balance"
+		if contractDataOutput.ContractDataBalanceHolder == "" && !evictedBalance {
+			continue
+		}
+		balanceOutputs = append(balanceOutputs, contractDataOutput)
+	}
+	return balanceOutputs
+}
+
+// GetSACAssetDetails returns the contract data details of the contract instances of verified Stellar Asset Contracts.
+func GetSACAssetDetails(contractDataOutputs []contract.ContractDataOutput) []contract.ContractDataOutput {
+	assetOutputs := []contract.ContractDataOutput{}
+	for _, contractDataOutput := range contractDataOutputs {
+		if contractDataOutput.ContractDataAssetType == "" {
+			continue
+		}
+		assetOutputs = append(assetOutputs, contractDataOutput)
+	}
+	return assetOutputs
+}
+
+func (p *SACBalanceProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractDataOutputs, err := ledgerContractData(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+	balances := GetSACBalanceDetails(contractDataOutputs)
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("sac_balances", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d SAC balances in ledger sequence %d", len(balances), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, balance := range balances {
+		data = append(data, balance)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
+
+func (p *SACAssetProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	contractDataOutputs, err := ledgerContractData(ledgerChangeSet, p.Passphrase)
+	if err != nil {
+		return err
+	}
+	assets := GetSACAssetDetails(contractDataOutputs)
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("sac_assets", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d SAC assets in ledger sequence %d", len(assets), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, asset := range assets {
+		data = append(data, asset)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
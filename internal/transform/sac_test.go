@@ -0,0 +1,166 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sacTestPassphrase = "unit test"
+
+func TestGetSACDetails(t *testing.T) {
+	header := xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			ScpValue: xdr.StellarValue{
+				CloseTime: 1000,
+			},
+			LedgerSeq: 10,
+		},
+	}
+	var issuer [32]byte
+	asset, err := xdr.NewCreditAsset("USDC", strkey.MustEncode(strkey.VersionByteAccountID, issuer[:]))
+	require.NoError(t, err)
+	assetContractID, err := asset.ContractID(sacTestPassphrase)
+	require.NoError(t, err)
+	holder := xdr.ContractId{2}
+
+	changes := []utils.SourcedChange{
+		{Change: makeSACInstanceChange(assetContractID, issuer)},
+		{Change: makeSACBalanceChange(assetContractID, holder, 100)},
+		// Any other contract data is skipped
+		{Change: makeContractDataTestInput()[0]},
+	}
+	contractDataOutputs, err := GetContractDataDetails(changes, header, sacTestPassphrase)
+	require.NoError(t, err)
+
+	balances := GetSACBalanceDetails(contractDataOutputs)
+	require.Len(t, balances, 1)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, assetContractID[:]), balances[0].ContractId)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, holder[:]), balances[0].ContractDataBalanceHolder)
+	assert.Equal(t, "100", balances[0].ContractDataBalance)
+
+	assets := GetSACAssetDetails(contractDataOutputs)
+	require.Len(t, assets, 1)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, assetContractID[:]), assets[0].ContractId)
+	assert.Equal(t, "AssetTypeAssetTypeCreditAlphanum4", assets[0].ContractDataAssetType)
+	assert.Equal(t, "USDC", assets[0].ContractDataAssetCode)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteAccountID, issuer[:]), assets[0].ContractDataAssetIssuer)
+
+	// The balance of an evicted entry cannot be read, the eviction is kept to flag the balance
+	evictedBalance := makeSACBalanceChange(assetContractID, holder, 0)
+	evictedBalance.Post.Data.ContractData.Val = xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+	evictedBalance.ChangeType = xdr.LedgerEntryChangeTypeLedgerEntryRemoved
+	evictedBalance.Pre, evictedBalance.Post = evictedBalance.Post, nil
+	contractDataOutputs, err = GetContractDataDetails([]utils.SourcedChange{
+		{Change: evictedBalance, Source: utils.ChangeSource{Evicted: true}},
+	}, header, sacTestPassphrase)
+	require.NoError(t, err)
+	balances = GetSACBalanceDetails(contractDataOutputs)
+	require.Len(t, balances, 1)
+	assert.True(t, balances[0].Evicted)
+	assert.Empty(t, balances[0].ContractDataBalanceHolder)
+
+	// An instance claiming to be an asset it was not deployed for is not a Stellar Asset Contract
+	contractDataOutputs, err = GetContractDataDetails([]utils.SourcedChange{{Change: makeSACInstanceChange(holder, issuer)}}, header, sacTestPassphrase)
+	require.NoError(t, err)
+	assert.Empty(t, GetSACAssetDetails(contractDataOutputs))
+}
+
+func makeSACInstanceChange(contractID xdr.ContractId, issuer [32]byte) ingest.Change {
+	assetInfoSym := xdr.ScSymbol("AssetInfo")
+	alphaNum4Sym := xdr.ScSymbol("AlphaNum4")
+	assetCodeSym := xdr.ScSymbol("asset_code")
+	issuerSym := xdr.ScSymbol("issuer")
+	assetCode := xdr.ScString("USDC")
+	issuerBytes := xdr.ScBytes(issuer[:])
+
+	assetMap := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &assetCodeSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &assetCode}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &issuerSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &issuerBytes}},
+	}
+	assetInfo := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &alphaNum4Sym},
+		{Type: xdr.ScValTypeScvMap, Map: &assetMap},
+	}
+	assetInfoKey := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &assetInfoSym},
+	}
+
+	entry := xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 10,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract: xdr.ScAddress{
+					Type:       xdr.ScAddressTypeScAddressTypeContract,
+					ContractId: &contractID,
+				},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+				Durability: xdr.ContractDataDurabilityPersistent,
+				Val: xdr.ScVal{
+					Type: xdr.ScValTypeScvContractInstance,
+					Instance: &xdr.ScContractInstance{
+						Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableStellarAsset},
+						Storage: &xdr.ScMap{
+							{
+								Key: xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &assetInfoKey},
+								Val: xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &assetInfo},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return ingest.Change{
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Type:       xdr.LedgerEntryTypeContractData,
+		Post:       &entry,
+	}
+}
+
+func makeSACBalanceChange(contractID xdr.ContractId, holder xdr.ContractId, amount uint64) ingest.Change {
+	balanceSym := xdr.ScSymbol("Balance")
+	amountSym := xdr.ScSymbol("amount")
+	authorizedSym := xdr.ScSymbol("authorized")
+	clawbackSym := xdr.ScSymbol("clawback")
+	authorized := true
+	clawback := false
+	amountParts := xdr.Int128Parts{Hi: 0, Lo: xdr.Uint64(amount)}
+
+	key := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &balanceSym},
+		{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &holder}},
+	}
+	val := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &amountSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &amountParts}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &authorizedSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: &authorized}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &clawbackSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: &clawback}},
+	}
+
+	entry := xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 10,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract: xdr.ScAddress{
+					Type:       xdr.ScAddressTypeScAddressTypeContract,
+					ContractId: &contractID,
+				},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &key},
+				Durability: xdr.ContractDataDurabilityPersistent,
+				Val:        xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &val},
+			},
+		},
+	}
+	return ingest.Change{
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Type:       xdr.LedgerEntryTypeContractData,
+		Post:       &entry,
+	}
+}
@@ -0,0 +1,50 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
+)
+
+// TokenTransferProcessor sends the SEP-41 token events emitted in a ledger
+type TokenTransferProcessor struct {
+	utils.BaseProcessor
+}
+
+// GetTokenTransferDetails returns the contract events of transactions that are SEP-41 token events.
+func GetTokenTransferDetails(transactions []ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passPhrase string) ([]contract.TokenTransferOutput, error) {
+	tokenTransferOutputs := []contract.TokenTransferOutput{}
+	for _, transaction := range transactions {
+		tokenTransfers, err := contract.TransformTokenTransfer(transaction, lhe, passPhrase)
+		if err != nil {
+			return tokenTransferOutputs, fmt.Errorf("could not transform token transfers %w", err)
+		}
+		tokenTransferOutputs = append(tokenTransferOutputs, tokenTransfers...)
+	}
+	return tokenTransferOutputs, nil
+}
+
+func (p *TokenTransferProcessor) Process(ctx context.Context, msg utils.Message) error {
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
+	if err != nil {
+		return err
+	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+
+	tokenTransfers, err := GetTokenTransferDetails(ledgerChangeSet.Transactions, lhe, p.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	p.MetricRecorder.RecordProcessingLedgerSequence("token_transfers", uint32(lhe.Header.LedgerSeq))
+	p.Logger.Infof("Processed %d token transfers in ledger sequence %d", len(tokenTransfers), lhe.Header.LedgerSeq)
+	var data []interface{}
+	for _, transfer := range tokenTransfers {
+		data = append(data, transfer)
+	}
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
+}
@@ -0,0 +1,103 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTokenTransferDetails(t *testing.T) {
+	const passphrase = "unit test"
+	nativeAssetContractID, err := xdr.MustNewNativeAsset().ContractID(passphrase)
+	require.NoError(t, err)
+	nativeContractID := xdr.ContractId(nativeAssetContractID)
+	var tokenContractID xdr.ContractId
+	var fromContractID, toContractID xdr.ContractId
+	fromContractID[0], toContractID[0] = 1, 2
+	from := xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &fromContractID}}
+	to := xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &toContractID}}
+	fromAddress, err := from.MustAddress().String()
+	require.NoError(t, err)
+	toAddress, err := to.MustAddress().String()
+	require.NoError(t, err)
+
+	amount := xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: 5}}
+	muxedId := xdr.Uint64(7)
+	amountKey, muxedIdKey := xdr.ScSymbol("amount"), xdr.ScSymbol("to_muxed_id")
+	muxedData := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &amountKey}, Val: amount},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &muxedIdKey}, Val: xdr.ScVal{Type: xdr.ScValTypeScvU64, U64: &muxedId}},
+	}
+	expirationLedger := xdr.Uint32(1000)
+	allowance := &xdr.ScVec{amount, {Type: xdr.ScValTypeScvU32, U32: &expirationLedger}}
+
+	events := []xdr.ContractEvent{
+		// CAP-67 transfer of the native Stellar Asset Contract to a muxed account
+		makeTokenEvent(nativeContractID, []xdr.ScVal{symbolScVal("transfer"), from, to, stringScVal("native")}, xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &muxedData}),
+		// Older SAC mint with an admin topic, the asset does not match the contract and is dropped
+		makeTokenEvent(tokenContractID, []xdr.ScVal{symbolScVal("mint"), from, to, stringScVal("native")}, amount),
+		makeTokenEvent(tokenContractID, []xdr.ScVal{symbolScVal("approve"), from, to}, xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &allowance}),
+		// Events that do not have the shape of a token event are skipped
+		makeTokenEvent(tokenContractID, []xdr.ScVal{symbolScVal("transfer"), from}, amount),
+		makeTokenEvent(tokenContractID, []xdr.ScVal{symbolScVal("burn"), from}, xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &expirationLedger}),
+	}
+	diagnosticEvents := []xdr.DiagnosticEvent{
+		// Events of calls that were rolled back are skipped
+		{InSuccessfulContractCall: false, Event: makeTokenEvent(tokenContractID, []xdr.ScVal{symbolScVal("burn"), from}, amount)},
+	}
+	transaction := makeContractEventTestTransaction(xdr.TransactionMeta{
+		V:  3,
+		V3: &xdr.TransactionMetaV3{SorobanMeta: &xdr.SorobanTransactionMeta{Events: events, DiagnosticEvents: diagnosticEvents}},
+	})
+
+	closedAt := time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC)
+	transactionID := toid.New(10, 1, 0).ToInt64()
+	transactionHash := "0000000000000000000000000000000000000000000000000000000000000000"
+	expiration := uint32(1000)
+	nativeContractAddress := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &nativeContractID}
+	nativeContractId, err := nativeContractAddress.String()
+	require.NoError(t, err)
+	want := []contract.TokenTransferOutput{
+		{
+			TransactionHash: transactionHash, TransactionID: transactionID, EventIndex: 0, LedgerSequence: 10, ClosedAt: closedAt,
+			ContractId: nativeContractId, EventType: "transfer", From: fromAddress, To: toAddress, Amount: "5", ToMuxedId: "7", Asset: "native",
+		},
+		{
+			TransactionHash: transactionHash, TransactionID: transactionID, EventIndex: 1, LedgerSequence: 10, ClosedAt: closedAt,
+			ContractId: "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4", EventType: "mint", To: toAddress, Admin: fromAddress, Amount: "5",
+		},
+		{
+			TransactionHash: transactionHash, TransactionID: transactionID, EventIndex: 2, LedgerSequence: 10, ClosedAt: closedAt,
+			ContractId: "CAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABSC4", EventType: "approve", From: fromAddress, To: toAddress, Amount: "5",
+			ExpirationLedger: &expiration,
+		},
+	}
+
+	actualOutput, err := GetTokenTransferDetails([]ingest.LedgerTransaction{transaction}, makeContractEventTestHeader(), passphrase)
+	require.NoError(t, err)
+	assert.Equal(t, want, actualOutput)
+}
+
+func makeTokenEvent(contractID xdr.ContractId, topics []xdr.ScVal, data xdr.ScVal) xdr.ContractEvent {
+	return xdr.ContractEvent{
+		ContractId: &contractID,
+		Type:       xdr.ContractEventTypeContract,
+		Body:       xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: topics, Data: data}},
+	}
+}
+
+func symbolScVal(value string) xdr.ScVal {
+	symbol := xdr.ScSymbol(value)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &symbol}
+}
+
+func stringScVal(value string) xdr.ScVal {
+	str := xdr.ScString(value)
+	return xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &str}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/stellar/go-stellar-sdk/ingest"
 	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
@@ -12,20 +11,29 @@ import (
 
 type TTLDataProcessor struct {
 	utils.BaseProcessor
+	// Watchlist is optional, when set the watched entries whose TTL runs out are sent by NotificationSender once
+	// their ledger is committed
+	Watchlist          *ExpiryWatchlist
+	NotificationSender *utils.ExpiryNotificationSender
+	// notifications are the ones due at the ledger being processed, waiting for it to be committed
+	notifications []utils.ExpiryNotification
 }
 
-func GetTTLDataDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.TtlOutput, error) {
+func GetTTLDataDetails(changes []utils.SourcedChange, lhe xdr.LedgerHeaderHistoryEntry) ([]contract.TtlOutput, error) {
 	ttlDataOutputs := []contract.TtlOutput{}
 	for _, change := range changes {
 		if change.Type != xdr.LedgerEntryTypeTtl {
 			continue
 		}
 
-		TransformTTLData, err := contract.TransformTtl(change, lhe)
+		TransformTTLData, err := contract.TransformTtl(change.Change, lhe)
 
 		if err != nil {
 			return ttlDataOutputs, fmt.Errorf("could not transform ttl data %w", err)
 		}
+		TransformTTLData.TransactionHash = change.Source.TransactionHash
+		TransformTTLData.ApplicationOrder = change.Source.ApplicationOrder
+		TransformTTLData.OperationIndex = change.Source.OperationIndex
 
 		ttlDataOutputs = append(ttlDataOutputs, TransformTTLData)
 
@@ -38,26 +46,54 @@ func GetTTLDataDetails(changes []ingest.Change, lhe xdr.LedgerHeaderHistoryEntry
 }
 
 func (p *TTLDataProcessor) Process(ctx context.Context, msg utils.Message) error {
-	ledgerCloseMeta, err := p.ExtractLedgerCloseMeta(msg)
-	if err != nil {
-		return err
-	}
-	lhe := ledgerCloseMeta.LedgerHeaderHistoryEntry()
-	changes, err := p.ReadIngestChanges(ctx, msg)
+	ledgerChangeSet, err := p.ExtractLedgerChangeSet(msg)
 	if err != nil {
 		return err
 	}
+	lhe := ledgerChangeSet.LedgerCloseMeta.LedgerHeaderHistoryEntry()
+	changes := ledgerChangeSet.SourcedChanges(xdr.LedgerEntryTypeTtl)
 	ttls, err := GetTTLDataDetails(changes, lhe)
 	if err != nil {
 		return err
 	}
 
+	if p.Watchlist != nil {
+		p.notifications, err = p.Watchlist.Update(ledgerChangeSet, ttls)
+		if err != nil {
+			return fmt.Errorf("could not update expiry watchlist: %w", err)
+		}
+	}
+
 	p.MetricRecorder.RecordProcessingLedgerSequence("ttl", uint32(lhe.Header.LedgerSeq))
 	p.Logger.Infof("Processed %d ttls in ledger sequence %d", len(ttls), lhe.Header.LedgerSeq)
 	var data []interface{}
 	for _, tx := range ttls {
 		data = append(data, tx)
 	}
-	return p.SendInfo(ctx, data)
+	return p.SendInfo(ctx, uint32(lhe.Header.LedgerSeq), data)
 
 }
+
+// LedgerCommitted queues the notifications due at the committed ledger for delivery, outside of its transaction and
+// of the ingestion path. Notifications that could not be delivered are reported again at the next ledger, failing
+// to deliver them does not stop ingestion as the indexed data does not depend on them.
+func (p *TTLDataProcessor) LedgerCommitted(ctx context.Context, ledgerSequence uint32) {
+	if p.Watchlist == nil {
+		return
+	}
+	p.Watchlist.Commit()
+	delivered, failed := p.NotificationSender.Results()
+	p.Watchlist.Delivered(delivered)
+	p.Watchlist.Failed(failed)
+	if len(failed) > 0 {
+		p.Logger.Warnf("Retrying %d undelivered expiry notifications after ledger sequence %d", len(failed), ledgerSequence)
+	}
+
+	notifications := p.notifications
+	p.notifications = nil
+	if len(notifications) == 0 {
+		return
+	}
+	p.Logger.Infof("Sending %d expiry notifications for ledger sequence %d", len(notifications), ledgerSequence)
+	p.NotificationSender.Send(notifications)
+}
@@ -7,22 +7,32 @@ import (
 	"github.com/stellar/go-stellar-sdk/ingest"
 	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stellar/stellar-ledger-data-indexer/internal/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetTTLDetails(t *testing.T) {
 	type transformTest struct {
-		input      []ingest.Change
+		input      []utils.SourcedChange
 		wantOutput []contract.TtlOutput
 		wantErr    error
 	}
 
-	hardCodedInput := makeTtlTestInput()
+	operationIndex := uint32(0)
+	source := utils.ChangeSource{
+		TransactionHash:  "0101010101010101010101010101010101010101010101010101010101010101",
+		ApplicationOrder: 3,
+		OperationIndex:   &operationIndex,
+	}
+	hardCodedInput := []utils.SourcedChange{{Change: makeTtlTestInput()[0], Source: source}}
 	hardCodedOutput := makeTtlTestOutput()
+	hardCodedOutput[0].TransactionHash = source.TransactionHash
+	hardCodedOutput[0].ApplicationOrder = source.ApplicationOrder
+	hardCodedOutput[0].OperationIndex = source.OperationIndex
 	tests := []transformTest{
 		{
-			[]ingest.Change{
-				{
+			[]utils.SourcedChange{
+				{Change: ingest.Change{
 					ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
 					Type:       xdr.LedgerEntryTypeOffer,
 					Pre:        nil,
@@ -31,7 +41,7 @@ func TestGetTTLDetails(t *testing.T) {
 							Type: xdr.LedgerEntryTypeOffer,
 						},
 					},
-				},
+				}},
 			},
 			// Any non contract data (eg: LedgerEntryTypeOffer) is skipped
 			[]contract.TtlOutput{}, nil,
@@ -190,25 +200,25 @@ func TestGetTTLDetailsWithDuplicates(t *testing.T) {
 	}
 
 	// Create multiple changes to the same TTL entry within a single ledger
-	changes := []ingest.Change{
-		{
+	changes := []utils.SourcedChange{
+		{Change: ingest.Change{
 			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
 			Type:       xdr.LedgerEntryTypeTtl,
 			Pre:        &preTtlLedgerEntry1,
 			Post:       &postTtlLedgerEntry1,
-		},
-		{
+		}},
+		{Change: ingest.Change{
 			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
 			Type:       xdr.LedgerEntryTypeTtl,
 			Pre:        &preTtlLedgerEntry2,
 			Post:       &postTtlLedgerEntry2,
-		},
-		{
+		}},
+		{Change: ingest.Change{
 			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
 			Type:       xdr.LedgerEntryTypeTtl,
 			Pre:        &preTtlLedgerEntry3,
 			Post:       &postTtlLedgerEntry3,
-		},
+		}},
 	}
 
 	header := xdr.LedgerHeaderHistoryEntry{
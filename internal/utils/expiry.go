@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/support/log"
+)
+
+// Event types of ExpiryNotification
+const (
+	// ExpiryEventExpiring is sent once a watched entry is within the threshold of its live until ledger
+	ExpiryEventExpiring = "expiring"
+	// ExpiryEventExpired is sent once the live until ledger of a watched entry has passed
+	ExpiryEventExpired = "expired"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// expiryQueueSize is the number of ledgers whose notifications can wait for delivery before Send blocks
+const expiryQueueSize = 64
+
+// WatchedEntry is the live until ledger of an entry on the expiry watchlist
+type WatchedEntry struct {
+	KeyHash string `db:"key_hash"`
+	// ContractId is empty for contract code
+	ContractId              string `db:"contract_id"`
+	Durability              string `db:"durability"`
+	LiveUntilLedgerSequence uint32 `db:"live_until_ledger_sequence"`
+	// NotifiedEvent is the last event sent for LiveUntilLedgerSequence, empty if none was
+	NotifiedEvent string `db:"notified_event"`
+}
+
+// ExpiryNotification reports a watched entry whose TTL is running out
+type ExpiryNotification struct {
+	Event string `json:"event"`
+	// ContractId is empty for contract code
+	ContractId              string    `json:"contract_id"`
+	KeyHash                 string    `json:"key_hash"`
+	Durability              string    `json:"durability"`
+	LiveUntilLedgerSequence uint32    `json:"live_until_ledger_sequence"`
+	LedgerSequence          uint32    `json:"ledger_sequence"`
+	ClosedAt                time.Time `json:"closed_at"`
+	// RemainingLedgers is the number of ledgers the entry stays live for after LedgerSequence, negative once expired
+	RemainingLedgers int64 `json:"remaining_ledgers"`
+}
+
+// ExpiryNotifier delivers expiry notifications
+type ExpiryNotifier interface {
+	Notify(ctx context.Context, notifications []ExpiryNotification) error
+	Close()
+}
+
+// ExpiryNotificationStore records the notifications sent, so that they are not sent again after a restart
+type ExpiryNotificationStore interface {
+	RecordExpiryNotifications(ctx context.Context, notifications []ExpiryNotification) error
+}
+
+// ExpiryNotificationSender delivers expiry notifications to every notifier in the background, so that slow or
+// unreachable notifiers do not hold up ingestion, and records the delivered ones in the store. A notification is
+// delivered once every notifier accepted it, a failed one may be sent again to the notifiers that accepted it.
+type ExpiryNotificationSender struct {
+	notifiers []ExpiryNotifier
+	store     ExpiryNotificationStore
+	logger    *log.Entry
+	queue     chan []ExpiryNotification
+	done      chan struct{}
+	mu        sync.Mutex
+	delivered []ExpiryNotification
+	failed    []ExpiryNotification
+}
+
+// NewExpiryNotificationSender starts delivering the notifications passed to Send until Close. store is optional,
+// it must not share the transaction of the committed ledgers as it is written concurrently.
+func NewExpiryNotificationSender(ctx context.Context, notifiers []ExpiryNotifier, store ExpiryNotificationStore, logger *log.Entry) *ExpiryNotificationSender {
+	sender := &ExpiryNotificationSender{
+		notifiers: notifiers,
+		store:     store,
+		logger:    logger,
+		queue:     make(chan []ExpiryNotification, expiryQueueSize),
+		done:      make(chan struct{}),
+	}
+	go sender.run(ctx)
+	return sender
+}
+
+// Send queues the notifications of a committed ledger for delivery
+func (s *ExpiryNotificationSender) Send(notifications []ExpiryNotification) {
+	s.queue <- notifications
+}
+
+// Results returns the notifications delivered and the ones that failed since the last call
+func (s *ExpiryNotificationSender) Results() (delivered []ExpiryNotification, failed []ExpiryNotification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivered, failed = s.delivered, s.failed
+	s.delivered, s.failed = nil, nil
+	return delivered, failed
+}
+
+// Close delivers the queued notifications and closes the notifiers
+func (s *ExpiryNotificationSender) Close() {
+	close(s.queue)
+	<-s.done
+	for _, notifier := range s.notifiers {
+		notifier.Close()
+	}
+}
+
+func (s *ExpiryNotificationSender) run(ctx context.Context) {
+	defer close(s.done)
+	for notifications := range s.queue {
+		var delivered, failed []ExpiryNotification
+		for _, notification := range notifications {
+			if s.deliver(ctx, notification) {
+				delivered = append(delivered, notification)
+			} else {
+				failed = append(failed, notification)
+			}
+		}
+		// A delivered notification that could not be recorded is only sent again after a restart
+		if s.store != nil && len(delivered) > 0 {
+			if err := s.store.RecordExpiryNotifications(ctx, delivered); err != nil {
+				s.logger.Errorf("Failed to record %d delivered expiry notifications: %v", len(delivered), err)
+			}
+		}
+		s.mu.Lock()
+		s.delivered = append(s.delivered, delivered...)
+		s.failed = append(s.failed, failed...)
+		s.mu.Unlock()
+	}
+}
+
+func (s *ExpiryNotificationSender) deliver(ctx context.Context, notification ExpiryNotification) bool {
+	delivered := true
+	for _, notifier := range s.notifiers {
+		if err := notifier.Notify(ctx, []ExpiryNotification{notification}); err != nil {
+			s.logger.Errorf("Failed to send expiry notification of %s for ledger sequence %d: %v", notification.KeyHash, notification.LedgerSequence, err)
+			delivered = false
+		}
+	}
+	return delivered
+}
+
+// WebhookExpiryNotifier POSTs each notification as a JSON body to a URL
+type WebhookExpiryNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookExpiryNotifier(url string) *WebhookExpiryNotifier {
+	return &WebhookExpiryNotifier{URL: url, Client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (n *WebhookExpiryNotifier) Notify(ctx context.Context, notifications []ExpiryNotification) error {
+	for _, notification := range notifications {
+		body, err := json.Marshal(notification)
+		if err != nil {
+			return fmt.Errorf("could not encode expiry notification of %s: %w", notification.KeyHash, err)
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("could not create expiry webhook request: %w", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response, err := n.Client.Do(request)
+		if err != nil {
+			return fmt.Errorf("expiry webhook request failed: %w", err)
+		}
+		response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return fmt.Errorf("expiry webhook returned status %d for %s", response.StatusCode, notification.KeyHash)
+		}
+	}
+	return nil
+}
+
+func (n *WebhookExpiryNotifier) Close() {}
+
+// NDJSONExpiryNotifier appends each notification to a local file as a line of JSON
+type NDJSONExpiryNotifier struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewNDJSONExpiryNotifier(path string) (*NDJSONExpiryNotifier, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open expiry notification file %s: %w", path, err)
+	}
+	return &NDJSONExpiryNotifier{file: file}, nil
+}
+
+func (n *NDJSONExpiryNotifier) Notify(ctx context.Context, notifications []ExpiryNotification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	encoder := json.NewEncoder(n.file)
+	for _, notification := range notifications {
+		if err := encoder.Encode(notification); err != nil {
+			return fmt.Errorf("could not write expiry notification of %s: %w", notification.KeyHash, err)
+		}
+	}
+	return nil
+}
+
+func (n *NDJSONExpiryNotifier) Close() {
+	n.file.Close()
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/support/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// testExpiryNotifier fails the notifications of failing key hashes and keeps the other ones
+type testExpiryNotifier struct {
+	failing  map[string]bool
+	notified []ExpiryNotification
+}
+
+func (n *testExpiryNotifier) Notify(ctx context.Context, notifications []ExpiryNotification) error {
+	for _, notification := range notifications {
+		if n.failing[notification.KeyHash] {
+			return errors.New("unreachable")
+		}
+		n.notified = append(n.notified, notification)
+	}
+	return nil
+}
+
+func (n *testExpiryNotifier) Close() {}
+
+type testExpiryNotificationStore struct {
+	recorded []ExpiryNotification
+}
+
+func (s *testExpiryNotificationStore) RecordExpiryNotifications(ctx context.Context, notifications []ExpiryNotification) error {
+	s.recorded = append(s.recorded, notifications...)
+	return nil
+}
+
+func TestExpiryNotificationSender(t *testing.T) {
+	working := &testExpiryNotifier{}
+	failing := &testExpiryNotifier{failing: map[string]bool{"02": true}}
+	store := &testExpiryNotificationStore{}
+	sender := NewExpiryNotificationSender(context.Background(), []ExpiryNotifier{working, failing}, store, log.New())
+
+	delivered := ExpiryNotification{Event: ExpiryEventExpiring, KeyHash: "01", LedgerSequence: 100}
+	undelivered := ExpiryNotification{Event: ExpiryEventExpiring, KeyHash: "02", LedgerSequence: 100}
+	sender.Send([]ExpiryNotification{delivered, undelivered})
+	// Close waits for the queued notifications to be delivered
+	sender.Close()
+
+	// Only the notifications accepted by every notifier are delivered and recorded
+	deliveredNotifications, failedNotifications := sender.Results()
+	assert.Equal(t, []ExpiryNotification{delivered}, deliveredNotifications)
+	assert.Equal(t, []ExpiryNotification{undelivered}, failedNotifications)
+	assert.Equal(t, []ExpiryNotification{delivered}, store.recorded)
+	assert.Equal(t, []ExpiryNotification{delivered, undelivered}, working.notified)
+
+	deliveredNotifications, failedNotifications = sender.Results()
+	assert.Empty(t, deliveredNotifications)
+	assert.Empty(t, failedNotifications)
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ChangeSource identifies the transaction, and the operation within it, that caused a change.
+// Changes made by the ledger itself, e.g. protocol upgrades and evictions, have an empty TransactionHash.
+type ChangeSource struct {
+	TransactionHash string
+	// ApplicationOrder is the 1-based position of the transaction in the order it was applied
+	ApplicationOrder uint32
+	// OperationIndex is the 0-based index of the operation, nil for fee and transaction level changes
+	OperationIndex *uint32
+	// Evicted is set for the removal of entries evicted by the ledger, which only carry the key of the entry
+	Evicted bool
+}
+
+// SourcedChange is a ledger entry change along with what caused it.
+type SourcedChange struct {
+	ingest.Change
+	Source ChangeSource
+}
+
+// readLedgerChanges returns every change of a ledger in the order it was applied: fee charges, the meta
+// of every transaction, fee refunds, evictions and finally protocol upgrades. Unlike ingest.LedgerChangeReader,
+// changes are read per transaction so that they can be attributed to the transaction that caused them.
+func readLedgerChanges(ledgerCloseMeta xdr.LedgerCloseMeta, transactions []ingest.LedgerTransaction) ([]SourcedChange, error) {
+	changes := []SourcedChange{}
+	for _, transaction := range transactions {
+		changes = appendChanges(changes, ingest.GetChangesFromLedgerEntryChanges(transaction.FeeChanges), transactionSource(transaction, nil))
+	}
+	for _, transaction := range transactions {
+		transactionChanges, err := readTransactionChanges(transaction)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, transactionChanges...)
+	}
+	for _, transaction := range transactions {
+		changes = appendChanges(changes, ingest.GetChangesFromLedgerEntryChanges(transaction.PostTxApplyFeeChanges), transactionSource(transaction, nil))
+	}
+
+	evictedKeys, err := ledgerCloseMeta.EvictedLedgerKeys()
+	if err != nil {
+		return nil, fmt.Errorf("could not read evicted ledger keys %w", err)
+	}
+	evictionChanges, err := ingest.GetChangesFromLedgerEntryEvictions(evictedKeys)
+	if err != nil {
+		return nil, fmt.Errorf("could not read evictions %w", err)
+	}
+	changes = appendChanges(changes, evictionChanges, ChangeSource{Evicted: true})
+
+	for _, upgrade := range ledgerCloseMeta.UpgradesProcessing() {
+		changes = appendChanges(changes, ingest.GetChangesFromLedgerEntryChanges(upgrade.Changes), ChangeSource{})
+	}
+	return changes, nil
+}
+
+// readTransactionChanges returns the changes of the transaction meta: the changes made before the operations,
+// the changes of every operation and the changes made after them.
+func readTransactionChanges(transaction ingest.LedgerTransaction) ([]SourcedChange, error) {
+	var changesBefore, changesAfter xdr.LedgerEntryChanges
+	var operationChanges []xdr.LedgerEntryChanges
+
+	meta := transaction.UnsafeMeta
+	switch meta.V {
+	case 0:
+		for _, operation := range meta.MustOperations() {
+			operationChanges = append(operationChanges, operation.Changes)
+		}
+	case 1:
+		v1 := meta.MustV1()
+		changesBefore = v1.TxChanges
+		for _, operation := range v1.Operations {
+			operationChanges = append(operationChanges, operation.Changes)
+		}
+	case 2:
+		v2 := meta.MustV2()
+		changesBefore, changesAfter = v2.TxChangesBefore, v2.TxChangesAfter
+		for _, operation := range v2.Operations {
+			operationChanges = append(operationChanges, operation.Changes)
+		}
+	case 3:
+		v3 := meta.MustV3()
+		changesBefore, changesAfter = v3.TxChangesBefore, v3.TxChangesAfter
+		for _, operation := range v3.Operations {
+			operationChanges = append(operationChanges, operation.Changes)
+		}
+	case 4:
+		v4 := meta.MustV4()
+		changesBefore, changesAfter = v4.TxChangesBefore, v4.TxChangesAfter
+		for _, operation := range v4.Operations {
+			operationChanges = append(operationChanges, operation.Changes)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported transaction meta version %d for transaction %s", meta.V, transaction.Hash.HexString())
+	}
+
+	// Operation meta of transactions that failed with txINTERNAL_ERROR before protocol 13 is not reliable,
+	// see https://github.com/stellar/go/issues/2111
+	if transaction.Result.Result.Result.Code == xdr.TransactionResultCodeTxInternalError && transaction.LedgerVersion <= 12 {
+		operationChanges = nil
+	}
+
+	changes := appendChanges([]SourcedChange{}, ingest.GetChangesFromLedgerEntryChanges(changesBefore), transactionSource(transaction, nil))
+	for index, operation := range operationChanges {
+		operationIndex := uint32(index)
+		changes = appendChanges(changes, ingest.GetChangesFromLedgerEntryChanges(operation), transactionSource(transaction, &operationIndex))
+	}
+	return appendChanges(changes, ingest.GetChangesFromLedgerEntryChanges(changesAfter), transactionSource(transaction, nil)), nil
+}
+
+func transactionSource(transaction ingest.LedgerTransaction, operationIndex *uint32) ChangeSource {
+	return ChangeSource{
+		TransactionHash:  transaction.Hash.HexString(),
+		ApplicationOrder: transaction.Index,
+		OperationIndex:   operationIndex,
+	}
+}
+
+func appendChanges(sourcedChanges []SourcedChange, changes []ingest.Change, source ChangeSource) []SourcedChange {
+	for _, change := range changes {
+		sourcedChanges = append(sourcedChanges, SourcedChange{Change: change, Source: source})
+	}
+	return sourcedChanges
+}
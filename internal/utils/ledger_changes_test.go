@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTransactionChanges(t *testing.T) {
+	transaction := ingest.LedgerTransaction{
+		Index: 2,
+		Hash:  xdr.Hash{1},
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 3,
+			V3: &xdr.TransactionMetaV3{
+				TxChangesBefore: makeTTLCreatedChanges(1),
+				Operations: []xdr.OperationMeta{
+					{Changes: makeTTLCreatedChanges(2)},
+					{Changes: makeTTLCreatedChanges(3)},
+				},
+				TxChangesAfter: makeTTLCreatedChanges(4),
+			},
+		},
+	}
+
+	changes, err := readTransactionChanges(transaction)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	transactionHash := "0100000000000000000000000000000000000000000000000000000000000000"
+	firstOperation, secondOperation := uint32(0), uint32(1)
+	wantSources := []ChangeSource{
+		{TransactionHash: transactionHash, ApplicationOrder: 2},
+		{TransactionHash: transactionHash, ApplicationOrder: 2, OperationIndex: &firstOperation},
+		{TransactionHash: transactionHash, ApplicationOrder: 2, OperationIndex: &secondOperation},
+		{TransactionHash: transactionHash, ApplicationOrder: 2},
+	}
+	for i, change := range changes {
+		assert.Equal(t, wantSources[i], change.Source)
+		assert.Equal(t, xdr.Uint32(i+1), change.Post.Data.MustTtl().LiveUntilLedgerSeq)
+	}
+}
+
+func makeTTLCreatedChanges(liveUntilLedgerSeq uint32) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{
+			Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+			Created: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeTtl,
+					Ttl: &xdr.TtlEntry{
+						KeyHash:            xdr.Hash{byte(liveUntilLedgerSeq)},
+						LiveUntilLedgerSeq: xdr.Uint32(liveUntilLedgerSeq),
+					},
+				},
+			},
+		},
+	}
+}
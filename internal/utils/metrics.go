@@ -118,10 +118,10 @@ func (metricRecorder *metricRecorder) RegisterMaxLedgerSequenceIndexedMetric(ctx
 	maxLedgerSequenceIndexedMetric := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Namespace: nameSpace,
 		Name:      "max_ledger_sequence_indexed",
-		Help:      "The latest ledger sequence indexed in the database",
+		Help:      "The latest ledger sequence fully committed to the database, read from the ingestion cursor",
 	},
 		func() float64 {
-			latestIndexedLedger, err := dbOperator.GetMaxLedgerSequence(ctx)
+			latestIndexedLedger, err := dbOperator.GetIngestionCursor(ctx)
 			if err != nil {
 				metricRecorder.Logger.Errorf("Error fetching max ledger sequence indexed: %v", err)
 				return 0
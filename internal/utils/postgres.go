@@ -23,6 +23,8 @@ func chunkRecords[T any](records []T, chunkSize int) [][]T {
 	return chunks
 }
 
+// Write upserts the records of a message within the transaction opened by PostgresLedgerCommitter.
+// It does not commit, so a failure leaves nothing of the ledger behind once the transaction is rolled back.
 func (p *PostgresAdapter) Write(ctx context.Context, msg Message) error {
 
 	var records []interface{}
@@ -37,43 +39,9 @@ func (p *PostgresAdapter) Write(ctx context.Context, msg Message) error {
 
 	const batchSize = 1000
 	for _, batch := range chunkRecords(records, batchSize) {
-		var lastErr error
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			tx := p.DBOperator.Session()
-			tx.Begin(ctx)
-			err := p.DBOperator.Upsert(ctx, batch)
-			if err == nil {
-				err = p.Flush(ctx)
-			}
-			if err == nil {
-				// Success
-				lastErr = nil
-				break
-			}
-			// rollback transaction on error
-			tx.Rollback()
-
-			lastErr = err
-			backoff := time.Duration(attempt+1) * baseBackoff
-			p.Logger.Warn(
-				"retryable db error, retrying",
-				"table", p.DBOperator.TableName(),
-				"attempt", attempt+1,
-				"backoff", backoff,
-				"err", err,
-			)
-			time.Sleep(backoff)
-			continue
-
-			// Non-retryable error
-			return fmt.Errorf("error adding batch to %s: %w",
-				p.DBOperator.TableName(), err)
-		}
-		if lastErr != nil {
-			return fmt.Errorf(
-				"exceeded retries for table %s: %w",
-				p.DBOperator.TableName(), lastErr,
-			)
+		if err := p.DBOperator.Upsert(ctx, batch); err != nil {
+			return fmt.Errorf("error adding batch to %s for ledger %d: %w",
+				p.DBOperator.TableName(), msg.LedgerSequence, err)
 		}
 	}
 
@@ -82,17 +50,57 @@ func (p *PostgresAdapter) Write(ctx context.Context, msg Message) error {
 	return nil
 }
 
-func (p *PostgresAdapter) Flush(ctx context.Context) error {
-	err := p.DBOperator.Session().Commit()
+// CommitLedger runs apply, which writes every processor's rows for the ledger, and moves the
+// ingestion cursor of every dataset to ledgerSequence outside of backfills, all within a single transaction.
+// On failure the transaction is rolled back and the whole ledger is retried, so a ledger is
+// either fully applied across all datasets or not at all.
+func (c *PostgresLedgerCommitter) CommitLedger(ctx context.Context, ledgerSequence uint32, apply func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		err := c.commitLedger(ctx, ledgerSequence, apply)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		backoff := time.Duration(attempt+1) * baseBackoff
+		c.Logger.Warn(
+			"retryable db error, retrying",
+			"ledger", ledgerSequence,
+			"attempt", attempt+1,
+			"backoff", backoff,
+			"err", err,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return fmt.Errorf("exceeded retries for ledger %d: %w", ledgerSequence, lastErr)
+}
+
+func (c *PostgresLedgerCommitter) commitLedger(ctx context.Context, ledgerSequence uint32, apply func() error) error {
+	if err := c.Session.Begin(ctx); err != nil {
+		return fmt.Errorf("error starting transaction for ledger %d: %w", ledgerSequence, err)
+	}
+
+	err := apply()
+	for _, dbOperator := range c.DBOperators {
+		if err != nil || c.Backfill {
+			break
+		}
+		err = dbOperator.UpdateIngestionCursor(ctx, ledgerSequence)
+	}
 	if err != nil {
-		rollbackErr := p.DBOperator.Session().Rollback()
-		if rollbackErr != nil {
-			return fmt.Errorf(
-				"commit failed for %s: %v; rollback also failed: %v",
-				p.DBOperator.TableName(), err, rollbackErr,
-			)
+		if rollbackErr := c.Session.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w; rollback also failed: %v", err, rollbackErr)
 		}
-		return fmt.Errorf("error committing transaction for %s. rolled back successfully: %w", p.DBOperator.TableName(), err)
+		return err
+	}
+
+	if err := c.Session.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction for ledger %d: %w", ledgerSequence, err)
 	}
 	return nil
 }
@@ -103,6 +111,6 @@ func (p *PostgresAdapter) Close() {
 	}
 }
 
-func (p *PostgresAdapter) GetMaxLedgerSequence(ctx context.Context) (uint32, error) {
-	return p.DBOperator.GetMaxLedgerSequence(ctx)
+func (p *PostgresAdapter) GetIngestionCursor(ctx context.Context) (uint32, error) {
+	return p.DBOperator.GetIngestionCursor(ctx)
 }
@@ -19,6 +19,12 @@ type Processor interface {
 	Process(context.Context, Message) error
 }
 
+// LedgerCommitListener is implemented by processors holding state that must only change once the ledger they
+// processed is committed, as Process runs again for every attempt at committing a ledger.
+type LedgerCommitListener interface {
+	LedgerCommitted(ctx context.Context, ledgerSequence uint32)
+}
+
 type BaseProcessor struct {
 	OutboundAdapters []OutboundAdapter
 	Logger           *log.Entry
@@ -26,53 +32,110 @@ type BaseProcessor struct {
 	MetricRecorder   MetricRecorder
 }
 
-func (p *BaseProcessor) CreateLCMDataReader(ledgerCloseMeta xdr.LedgerCloseMeta) (*ingest.LedgerChangeReader, error) {
-	p.Logger.Infof("Creating LedgerChangeReader with phrase %s", p.Passphrase)
-	return ingest.NewLedgerChangeReaderFromLedgerCloseMeta(p.Passphrase, ledgerCloseMeta)
+// LedgerChangeSet holds the ledger entry changes of a single ledger grouped by LedgerEntryType,
+// along with its transactions. It is decoded once per ledger by LedgerMetadataReader and shared
+// by every processor, so the cost of reading a ledger does not grow with the number of datasets.
+type LedgerChangeSet struct {
+	LedgerCloseMeta xdr.LedgerCloseMeta
+	ChangesByType   map[xdr.LedgerEntryType][]SourcedChange
+	Transactions    []ingest.LedgerTransaction
+	// details holds the outputs processors derive from the changes, see Details
+	details map[string]interface{}
 }
 
-func (p *BaseProcessor) ExtractLedgerCloseMeta(msg Message) (xdr.LedgerCloseMeta, error) {
-	ledgerCloseMeta, ok := msg.Payload.(xdr.LedgerCloseMeta)
-	if !ok {
-		return xdr.LedgerCloseMeta{}, fmt.Errorf("invalid payload type")
+// NewLedgerChangeSet reads every change and transaction of ledgerCloseMeta and groups the changes
+// by ledger entry type, preserving the order in which they were applied.
+func NewLedgerChangeSet(passphrase string, ledgerCloseMeta xdr.LedgerCloseMeta) (*LedgerChangeSet, error) {
+	transactions, err := readLedgerTransactions(passphrase, ledgerCloseMeta)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := readLedgerChanges(ledgerCloseMeta, transactions)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ledger data %w", err)
+	}
+
+	changesByType := map[xdr.LedgerEntryType][]SourcedChange{}
+	for _, change := range changes {
+		changesByType[change.Type] = append(changesByType[change.Type], change)
 	}
-	return ledgerCloseMeta, nil
+	return &LedgerChangeSet{LedgerCloseMeta: ledgerCloseMeta, ChangesByType: changesByType, Transactions: transactions}, nil
 }
 
-func (p *BaseProcessor) SendInfo(ctx context.Context, data interface{}) error {
-	for _, adapter := range p.OutboundAdapters {
-		err := adapter.Write(ctx, Message{Payload: data})
+func readLedgerTransactions(passphrase string, ledgerCloseMeta xdr.LedgerCloseMeta) ([]ingest.LedgerTransaction, error) {
+	txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(passphrase, ledgerCloseMeta)
+	if err != nil {
+		return nil, fmt.Errorf("could not create ledger transaction reader: %w", err)
+	}
+	defer txReader.Close()
+
+	transactions := []ingest.LedgerTransaction{}
+	for {
+		transaction, err := txReader.Read()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return fmt.Errorf("error sending data to outbound adapter: %w", err)
+			return nil, fmt.Errorf("could not read ledger transaction %w", err)
 		}
+		transactions = append(transactions, transaction)
 	}
-	return nil
+	return transactions, nil
 }
 
-func (p *BaseProcessor) ReadIngestChanges(ctx context.Context, msg Message) ([]ingest.Change, error) {
-	changes := []ingest.Change{}
+// Changes returns the changes of the given ledger entry type.
+func (l *LedgerChangeSet) Changes(entryType xdr.LedgerEntryType) []ingest.Change {
+	sourcedChanges := l.ChangesByType[entryType]
+	changes := make([]ingest.Change, 0, len(sourcedChanges))
+	for _, sourcedChange := range sourcedChanges {
+		changes = append(changes, sourcedChange.Change)
+	}
+	return changes
+}
+
+// SourcedChanges returns the changes of the given ledger entry type along with the transaction that caused them.
+func (l *LedgerChangeSet) SourcedChanges(entryType xdr.LedgerEntryType) []SourcedChange {
+	return l.ChangesByType[entryType]
+}
 
-	ledgerCloseMeta, err := p.ExtractLedgerCloseMeta(msg)
+// Details returns the output of compute stored under name, computing it once per ledger so that the processors
+// deriving the same output from the changes share it. Shared outputs must not be modified by processors.
+func (l *LedgerChangeSet) Details(name string, compute func() (interface{}, error)) (interface{}, error) {
+	if details, ok := l.details[name]; ok {
+		return details, nil
+	}
+	details, err := compute()
 	if err != nil {
-		return []ingest.Change{}, err
+		return nil, err
+	}
+	if l.details == nil {
+		l.details = map[string]interface{}{}
 	}
+	l.details[name] = details
+	return details, nil
+}
 
-	dataReader, err := p.CreateLCMDataReader(ledgerCloseMeta)
-	if err != nil {
-		return []ingest.Change{}, err
+// LedgerSequence returns the sequence of the ledger the changes belong to.
+func (l *LedgerChangeSet) LedgerSequence() uint32 {
+	return l.LedgerCloseMeta.LedgerSequence()
+}
+
+func (p *BaseProcessor) ExtractLedgerChangeSet(msg Message) (*LedgerChangeSet, error) {
+	ledgerChangeSet, ok := msg.Payload.(*LedgerChangeSet)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type")
 	}
+	return ledgerChangeSet, nil
+}
 
-	for {
-		change, err := dataReader.Read()
-		if err == io.EOF {
-			break
-		}
+func (p *BaseProcessor) SendInfo(ctx context.Context, ledgerSequence uint32, data interface{}) error {
+	for _, adapter := range p.OutboundAdapters {
+		err := adapter.Write(ctx, Message{Payload: data, LedgerSequence: ledgerSequence})
 		if err != nil {
-			return []ingest.Change{}, fmt.Errorf("could not read ledger data %w", err)
+			return fmt.Errorf("error sending data to outbound adapter: %w", err)
 		}
-		changes = append(changes, change)
 	}
-	return changes, nil
+	return nil
 }
 
 // RemoveDuplicatesByFields removes duplicate entries from a slice based on given primary key fields.
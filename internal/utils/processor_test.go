@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/stellar/stellar-ledger-data-indexer/internal/contract"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRemoveDuplicatesByFields(t *testing.T) {
@@ -91,3 +93,26 @@ func TestRemoveDuplicatesByFields(t *testing.T) {
 		t.Fatal("expected to find C2 row in output")
 	}
 }
+
+func TestLedgerChangeSetDetails(t *testing.T) {
+	ledgerChangeSet := &LedgerChangeSet{}
+	computed := 0
+	compute := func() (interface{}, error) {
+		computed++
+		return []string{"details"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		details, err := ledgerChangeSet.Details("test", compute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"details"}, details)
+	}
+	assert.Equal(t, 1, computed)
+
+	// Errors are not kept, the next processor computes the details again
+	_, err := ledgerChangeSet.Details("failing", func() (interface{}, error) { return nil, errors.New("failed") })
+	assert.Error(t, err)
+	details, err := ledgerChangeSet.Details("failing", compute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"details"}, details)
+}
@@ -11,20 +11,37 @@ type DBOperator interface {
 	Upsert(ctx context.Context, data any) error
 	TableName() string
 	Session() db.SessionInterface
-	GetMaxLedgerSequence(ctx context.Context) (uint32, error)
+	GetIngestionCursor(ctx context.Context) (uint32, error)
+	UpdateIngestionCursor(ctx context.Context, ledgerSequence uint32) error
 }
 
 type Message struct {
-	Payload interface{}
+	Payload        interface{}
+	LedgerSequence uint32
 }
 
 type OutboundAdapter interface {
 	Write(ctx context.Context, message Message) error
 	Close()
-	GetMaxLedgerSequence(ctx context.Context) (uint32, error)
+	GetIngestionCursor(ctx context.Context) (uint32, error)
 }
 
 type PostgresAdapter struct {
 	DBOperator DBOperator
 	Logger     *log.Entry
 }
+
+// LedgerCommitter makes the writes of all processors for a single ledger atomic.
+type LedgerCommitter interface {
+	CommitLedger(ctx context.Context, ledgerSequence uint32, apply func() error) error
+}
+
+// PostgresLedgerCommitter commits a ledger in a single transaction on the session shared by DBOperators.
+type PostgresLedgerCommitter struct {
+	Session     db.SessionInterface
+	DBOperators []DBOperator
+	Logger      *log.Entry
+	// Backfill leaves the ingestion cursors as is, they hold the resume point of the live indexer
+	// and a backfilled range says nothing about the ledgers before it.
+	Backfill bool
+}